@@ -6,8 +6,15 @@ import (
 	"log/slog"
 	"time"
 
+	"prompt-maker/internal/attachment"
+	"prompt-maker/internal/cache"
 	"prompt-maker/internal/config"
+	"prompt-maker/internal/gemini"
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/library"
 	"prompt-maker/internal/observability"
+	"prompt-maker/internal/placeholder"
+	"prompt-maker/internal/projectctx"
 	"prompt-maker/internal/tui"
 	"prompt-maker/internal/web"
 
@@ -17,16 +24,32 @@ import (
 
 var version = "dev"
 
-const tracerShutdownTimeout = 5 * time.Second
+const (
+	tracerShutdownTimeout = 5 * time.Second
+	thinkingBudgetFlag    = "thinking-budget"
+)
+
+type startTUIFn func(cfg *config.Config, opts tui.Options) error
 
-type startTUIFn func(cfg *config.Config, version, modelName, history string, temperature float32) error
+type newChatCreatorFn func(ctx context.Context, cfg *config.Config) (gemini.ChatCreator, error)
 
 type app struct {
-	startTUI    startTUIFn
-	version     string
-	model       string
-	history     string
-	temperature float32
+	startTUI       startTUIFn
+	newChatCreator newChatCreatorFn
+	version        string
+	model          string
+	executeModel   string
+	history        string
+	temperature    float32
+	fallbacks      []string
+	// thinkingBudget is nil unless --thinking-budget was given.
+	thinkingBudget *int32
+	showThoughts   bool
+	attachPaths    []string
+	contextGlobs   []string
+	contextBudget  int
+	inputLimit     int
+	noCache        bool
 }
 
 // NewRootCmd creates the root Cobra command for the prompt-maker CLI.
@@ -36,13 +59,21 @@ func NewRootCmd() *cobra.Command {
 		startTUI: tui.Start,
 		version:  version,
 	}
+	a.newChatCreator = a.geminiChatCreator
 
-	var webMode bool
+	var (
+		webMode        bool
+		thinkingBudget int32
+	)
 
 	cmd := &cobra.Command{
 		Use:   "prompt-maker",
 		Short: "Crafts optimized prompts for AI models.",
-		RunE: func(_ *cobra.Command, _ []string) error {
+		RunE: func(c *cobra.Command, _ []string) error {
+			if c.Flags().Changed(thinkingBudgetFlag) {
+				a.thinkingBudget = &thinkingBudget
+			}
+
 			if webMode {
 				return a.runWeb()
 			}
@@ -53,8 +84,25 @@ func NewRootCmd() *cobra.Command {
 
 	cmd.Flags().BoolVar(&webMode, "web", false, "Run in web server mode on port 8080")
 	cmd.Flags().StringVar(&a.model, "model", "", "Specify the model to use")
-	cmd.Flags().Float32Var(&a.temperature, "temperature", 0.0, "Specify the model temperature")
+	cmd.Flags().StringVar(&a.executeModel, "execute-model", "",
+		"Model that executes crafted prompts, when it should differ from --model (TUI mode)")
+	cmd.Flags().Float32Var(&a.temperature, "temperature", config.DefaultModelTemperature, "Specify the model temperature")
 	cmd.Flags().StringVar(&a.history, "history", "", "Path to a file containing chat history")
+	cmd.PersistentFlags().StringSliceVar(&a.fallbacks, "fallback", nil,
+		"Ordered fallback models to try when the chosen one is unavailable (overrides GEMINI_FALLBACK_MODELS)")
+	cmd.Flags().Int32Var(&thinkingBudget, thinkingBudgetFlag, 0,
+		"Thinking token budget: -1 lets the model decide, 0 disables thinking (model default when unset)")
+	cmd.Flags().BoolVar(&a.showThoughts, "show-thoughts", false, "Request thought summaries and make them viewable")
+	cmd.Flags().StringArrayVar(&a.attachPaths, "attach", nil,
+		"Attach an image or text file as context (repeatable, TUI mode)")
+	cmd.Flags().StringArrayVar(&a.contextGlobs, "context", nil,
+		`Include project files matching a glob such as "./internal/**/*.go" as context (repeatable, TUI mode)`)
+	cmd.PersistentFlags().BoolVar(&a.noCache, "no-cache", false, "Bypass the on-disk response cache")
+	cmd.Flags().IntVar(&a.contextBudget, "context-budget", projectctx.DefaultBudget,
+		"Approximate token budget for --context files")
+	cmd.Flags().IntVar(&a.inputLimit, "input-limit", tui.DefaultInputCharLimit, "Maximum number of characters in the TUI prompt editor")
+
+	cmd.AddCommand(a.newHistoryCmd(), a.newLibraryCmd(), newExportCmd(), newDatasetCmd(), newStatsCmd())
 
 	return cmd
 }
@@ -63,9 +111,9 @@ func NewRootCmd() *cobra.Command {
 // In Echo v5, Start blocks until an OS signal is received and
 // performs graceful shutdown automatically.
 func (a *app) runWeb() error {
-	cfg, err := config.Load()
+	cfg, err := a.loadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
 
 	ctx := context.Background()
@@ -84,16 +132,19 @@ func (a *app) runWeb() error {
 		}
 	}()
 
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: cfg.APIKey})
+	creator, err := a.cachedChatCreator(ctx, cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create genai client: %w", err)
+		return err
 	}
 
-	promptGenerator := web.NewGeminiPromptGenerator(client)
+	promptGenerator := web.NewGeminiPromptGenerator(creator, cfg.FallbackModels, a.generationOptions())
 
 	webCfg := web.Config{
-		Generator: promptGenerator,
-		Version:   a.version,
+		Generator:    promptGenerator,
+		Version:      a.version,
+		Recorder:     a.newRecorder(ctx, history.SourceWeb),
+		Library:      optionalLibrary(ctx),
+		Placeholders: optionalPlaceholders(ctx),
 	}
 
 	server, err := web.NewServer(webCfg)
@@ -107,12 +158,222 @@ func (a *app) runWeb() error {
 	return server.Start(":8080")
 }
 
-// runTUI is simplified. It no longer selects a model.
+// runTUI loads the configuration and hands the CLI options to the TUI.
 func (a *app) runTUI() error {
+	cfg, err := a.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	attachments, err := loadAttachments(a.attachPaths)
+	if err != nil {
+		return err
+	}
+
+	var contextFiles []projectctx.File
+	if len(a.contextGlobs) > 0 {
+		if contextFiles, err = projectctx.Collect(".", a.contextGlobs); err != nil {
+			return err
+		}
+	}
+
+	file, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	return a.startTUI(cfg, tui.Options{
+		Version:        a.version,
+		Model:          a.model,
+		ExecuteModel:   a.executeModel,
+		History:        a.history,
+		Temperature:    a.temperature,
+		Fallbacks:      cfg.FallbackModels,
+		ThinkingBudget: a.thinkingBudget,
+		ShowThoughts:   a.showThoughts,
+		Attachments:    attachments,
+		Context:        contextFiles,
+		ContextBudget:  a.contextBudget,
+		InputCharLimit: a.inputLimit,
+		Keys:           file.Keys,
+		Cache:          a.openCache(context.Background()),
+		Recorder:       a.newRecorder(context.Background(), history.SourceTUI),
+		Library:        optionalLibrary(context.Background()),
+		Placeholders:   optionalPlaceholders(context.Background()),
+	})
+}
+
+// loadConfigFile reads the optional config file and checks the keys it
+// rebinds, so that a typo fails at start rather than leaving a key unbound.
+func loadConfigFile() (config.File, error) {
+	path, err := config.FilePath()
+	if err != nil {
+		return config.File{}, fmt.Errorf("failed to locate config file: %w", err)
+	}
+
+	file, err := config.LoadFile(path)
+	if err != nil {
+		return config.File{}, err
+	}
+
+	if err := tui.ValidateKeys(file.Keys); err != nil {
+		return config.File{}, fmt.Errorf("invalid keys in %s: %w", path, err)
+	}
+
+	return file, nil
+}
+
+// newRecorder returns a history recorder for source. History is skipped
+// with a warning when no location can be determined.
+func (a *app) newRecorder(ctx context.Context, source string) *history.Recorder {
+	store, err := openHistory()
+	if err != nil {
+		slog.WarnContext(ctx, "history disabled", "error", err)
+
+		return nil
+	}
+
+	return history.NewRecorder(store, source, a.generationOptions())
+}
+
+// openHistory returns the history store at the configured location, or at
+// the default one.
+func openHistory() (*history.FileStore, error) {
+	path := config.HistoryFile()
+	if path == "" {
+		var err error
+		if path, err = history.DefaultPath(); err != nil {
+			return nil, fmt.Errorf("failed to locate history: %w", err)
+		}
+	}
+
+	return history.NewFileStore(path), nil
+}
+
+// optionalLibrary returns the prompt library, or nil with a warning when no
+// location can be determined.
+func optionalLibrary(ctx context.Context) *library.Library {
+	lib, err := openLibrary()
+	if err != nil {
+		slog.WarnContext(ctx, "library disabled", "error", err)
+
+		return nil
+	}
+
+	return lib
+}
+
+// openLibrary returns the prompt library at the configured location, or at
+// the default one.
+func openLibrary() (*library.Library, error) {
+	dir := config.LibraryDir()
+	if dir == "" {
+		var err error
+		if dir, err = library.DefaultDir(); err != nil {
+			return nil, fmt.Errorf("failed to locate library: %w", err)
+		}
+	}
+
+	return library.New(dir), nil
+}
+
+// optionalPlaceholders returns the memory of placeholder values, or nil with
+// a warning when no location can be determined.
+func optionalPlaceholders(ctx context.Context) *placeholder.Memory {
+	path, err := placeholder.DefaultPath()
+	if err != nil {
+		slog.WarnContext(ctx, "placeholder memory disabled", "error", err)
+
+		return nil
+	}
+
+	return placeholder.NewMemory(path)
+}
+
+// geminiChatCreator creates a Gemini API client.
+func (*app) geminiChatCreator(ctx context.Context, cfg *config.Config) (gemini.ChatCreator, error) {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: cfg.APIKey, Backend: genai.BackendGeminiAPI})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create genai client: %w", err)
+	}
+
+	return gemini.NewChatCreator(client), nil
+}
+
+// cachedChatCreator creates the chat creator and wraps it with the response
+// cache unless it is disabled.
+func (a *app) cachedChatCreator(ctx context.Context, cfg *config.Config) (gemini.ChatCreator, error) {
+	creator, err := a.newChatCreator(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if store := a.openCache(ctx); store != nil {
+		creator = gemini.NewCachingChatCreator(creator, store)
+	}
+
+	return creator, nil
+}
+
+// openCache opens the response cache unless --no-cache was given. A cache
+// that cannot be opened is skipped with a warning rather than failing the run.
+func (a *app) openCache(ctx context.Context) *cache.Store {
+	if a.noCache {
+		return nil
+	}
+
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		slog.WarnContext(ctx, "response cache disabled", "error", err)
+
+		return nil
+	}
+
+	store, err := cache.Open(dir, cache.DefaultTTL, cache.DefaultMaxBytes)
+	if err != nil {
+		slog.WarnContext(ctx, "response cache disabled", "error", err)
+
+		return nil
+	}
+
+	return store
+}
+
+// loadAttachments reads and validates every --attach path.
+func loadAttachments(paths []string) ([]attachment.Attachment, error) {
+	attachments := make([]attachment.Attachment, 0, len(paths))
+
+	for _, path := range paths {
+		a, err := attachment.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to attach %s: %w", path, err)
+		}
+
+		attachments = append(attachments, a)
+	}
+
+	return attachments, nil
+}
+
+// generationOptions collects the generation flags shared by both modes.
+func (a *app) generationOptions() gemini.GenerationOptions {
+	return gemini.GenerationOptions{
+		Temperature:     a.temperature,
+		ThinkingBudget:  a.thinkingBudget,
+		IncludeThoughts: a.showThoughts,
+	}
+}
+
+// loadConfig reads the environment configuration and applies CLI overrides.
+func (a *app) loadConfig() (*config.Config, error) {
 	cfg, err := config.Load()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if len(a.fallbacks) > 0 {
+		cfg.FallbackModels = a.fallbacks
 	}
 
-	return a.startTUI(cfg, a.version, a.model, a.history, a.temperature)
+	return cfg, nil
 }
@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"prompt-maker/internal/config"
+	"prompt-maker/internal/tui"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -30,14 +33,16 @@ func TestApp_RunTUI(t *testing.T) {
 
 	t.Run("TUIError", func(t *testing.T) {
 		t.Setenv("GEMINI_API_KEY", "test-key")
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
 
 		a := &app{
-			startTUI: func(cfg *config.Config, version, modelName, history string, temperature float32) error {
+			startTUI: func(cfg *config.Config, opts tui.Options) error {
 				assert.NotNil(t, cfg)
-				assert.Equal(t, "dev", version)
-				assert.Empty(t, modelName)
-				assert.Empty(t, history)
-				assert.Zero(t, temperature)
+				assert.Equal(t, "dev", opts.Version)
+				assert.Empty(t, opts.Model)
+				assert.Empty(t, opts.History)
+				assert.Zero(t, opts.Temperature)
+				assert.NotNil(t, opts.Cache)
 
 				return errTUI
 			},
@@ -48,3 +53,55 @@ func TestApp_RunTUI(t *testing.T) {
 		assert.ErrorIs(t, err, errTUI)
 	})
 }
+
+func TestApp_RunTUI_NoCache(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "test-key")
+
+	a := &app{
+		noCache:    true,
+		inputLimit: 500,
+		startTUI: func(_ *config.Config, opts tui.Options) error {
+			assert.Nil(t, opts.Cache)
+			assert.Equal(t, 500, opts.InputCharLimit)
+
+			return nil
+		},
+	}
+	require.NoError(t, a.runTUI())
+}
+
+func TestApp_RunTUI_KeysFromConfigFile(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "test-key")
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("PROMPT_MAKER_CONFIG", path)
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys": {"quit": ["ctrl+q"]}}`), 0o600))
+
+	a := &app{
+		noCache: true,
+		startTUI: func(_ *config.Config, opts tui.Options) error {
+			assert.Equal(t, map[string][]string{"quit": {"ctrl+q"}}, opts.Keys)
+
+			return nil
+		},
+	}
+	require.NoError(t, a.runTUI())
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys": {"quti": ["ctrl+q"]}}`), 0o600))
+	require.ErrorContains(t, a.runTUI(), "quti")
+}
+
+func TestApp_LoadConfig_FallbackFlagOverridesEnv(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	t.Setenv("GEMINI_FALLBACK_MODELS", "env-a,env-b")
+
+	a := &app{fallbacks: []string{"flag-a"}}
+	cfg, err := a.loadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"flag-a"}, cfg.FallbackModels)
+
+	a = &app{}
+	cfg, err = a.loadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"env-a", "env-b"}, cfg.FallbackModels)
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"prompt-maker/internal/dataset"
+
+	"github.com/spf13/cobra"
+)
+
+// errNoExamples is returned when no matching entry has both a rough and a crafted prompt.
+var errNoExamples = errors.New("no matching history entries have both a rough and a crafted prompt")
+
+// datasetFlags holds the flags of the dataset command.
+type datasetFlags struct {
+	history historyFlags
+	query   string
+	format  string
+	output  string
+}
+
+// newDatasetCmd creates the dataset command.
+func newDatasetCmd() *cobra.Command {
+	var flags datasetFlags
+
+	cmd := &cobra.Command{
+		Use:   "dataset",
+		Short: "Export rough and crafted prompt pairs from history as a JSON-lines dataset.",
+		Long: `Export history as a JSON-lines dataset for tuning or evaluating a prompt
+optimizer, oldest first. Each rough prompt is an input and the crafted prompt
+that was executed, as edited, is its target. Entries without both are skipped.
+
+Formats:
+  gemini   {"contents": [{"role": "user", ...}, {"role": "model", ...}]}
+  generic  {"input": ..., "output": ...}`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, _ []string) error {
+			return runDataset(c, flags)
+		},
+	}
+
+	addFilterFlags(cmd, &flags.history)
+	cmd.Flags().StringVar(&flags.query, "search", "", "Only entries whose input, prompts or answer contain this text")
+	cmd.Flags().StringVarP(&flags.format, "format", "f", string(dataset.Gemini), "Line format: gemini or generic")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Write to this file instead of stdout")
+
+	return cmd
+}
+
+func runDataset(c *cobra.Command, flags datasetFlags) error {
+	format, err := dataset.ParseFormat(flags.format)
+	if err != nil {
+		return err
+	}
+
+	entries, err := filteredHistory(c.Context(), flags.history, flags.query)
+	if err != nil {
+		return err
+	}
+
+	slices.Reverse(entries)
+
+	var buf bytes.Buffer
+
+	n, err := dataset.Write(&buf, entries, format)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return errNoExamples
+	}
+
+	if flags.output == "" {
+		_, err = c.OutOrStdout().Write(buf.Bytes())
+
+		return err
+	}
+
+	if err = os.WriteFile(flags.output, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write dataset: %w", err)
+	}
+
+	fmt.Fprintf(c.ErrOrStderr(), "Wrote %d examples to %s\n", n, flags.output)
+
+	return nil
+}
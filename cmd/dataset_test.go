@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runDatasetCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"dataset"}, args...))
+
+	err := cmd.ExecuteContext(t.Context())
+
+	return out.String(), err
+}
+
+func TestDataset_FiltersByRatingAndTag(t *testing.T) {
+	seedHistory(t, sampleEntries()...)
+
+	_, err := runDatasetCmd(t, "--rating", "up")
+	require.ErrorIs(t, err, errNoExamples)
+
+	_, err = runHistory(t, "rate", "aaa111", "up", "--note", "clear and specific")
+	require.NoError(t, err)
+	_, err = runHistory(t, "tag", "aaa111", "#Best")
+	require.NoError(t, err)
+
+	out, err := runDatasetCmd(t, "--rating", "up")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"contents": [
+		{"role": "user", "parts": [{"text": "email asking for a raise"}]},
+		{"role": "model", "parts": [{"text": "You are an HR expert..."}]}
+	]}`, out)
+
+	out, err = runDatasetCmd(t, "--tag", "best", "--format", "generic")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"input": "email asking for a raise", "output": "You are an HR expert..."}`, out)
+
+	_, err = runDatasetCmd(t, "--format", "csv")
+	require.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"prompt-maker/internal/export"
+	"prompt-maker/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+// errNoExportEntries is returned when the filters select no history entries.
+var errNoExportEntries = errors.New("no history entries match")
+
+// exportFlags holds the flags of the export command.
+type exportFlags struct {
+	history historyFlags
+	query   string
+	format  string
+	theme   string
+	title   string
+	output  string
+}
+
+// newExportCmd creates the export command.
+func newExportCmd() *cobra.Command {
+	var flags exportFlags
+
+	cmd := &cobra.Command{
+		Use:   "export [ID...]",
+		Short: "Export history entries as a Markdown, HTML or JSON transcript.",
+		Long: `Export history entries as a transcript, oldest first.
+
+Without IDs, every entry selected by the filter flags is exported. The format
+defaults to the extension of --output, or Markdown when writing to stdout.`,
+		RunE: func(c *cobra.Command, args []string) error {
+			return runExport(c, flags, args)
+		},
+	}
+
+	addFilterFlags(cmd, &flags.history)
+	cmd.Flags().StringVar(&flags.query, "search", "", "Only entries whose input, prompts or answer contain this text")
+	cmd.Flags().StringVarP(&flags.format, "format", "f", "", "Transcript format: md, html or json")
+	cmd.Flags().StringVar(&flags.theme, "theme", export.DefaultTheme, "Theme of HTML transcripts")
+	cmd.Flags().StringVar(&flags.title, "title", "", "Title of the transcript")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Write to this file instead of stdout")
+
+	return cmd
+}
+
+func runExport(c *cobra.Command, flags exportFlags, ids []string) error {
+	format, err := flags.exportFormat()
+	if err != nil {
+		return err
+	}
+
+	entries, err := exportEntries(c.Context(), flags, ids)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	opts := export.Options{Format: format, Title: flags.title, Theme: flags.theme}
+	if err = export.Write(&buf, entries, opts); err != nil {
+		return err
+	}
+
+	if flags.output == "" {
+		_, err = c.OutOrStdout().Write(buf.Bytes())
+
+		return err
+	}
+
+	if err = os.WriteFile(flags.output, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+
+	fmt.Fprintf(c.ErrOrStderr(), "Exported %d entries to %s\n", len(entries), flags.output)
+
+	return nil
+}
+
+// exportFormat returns the chosen format, the one of the output file, or
+// Markdown.
+func (f exportFlags) exportFormat() (export.Format, error) {
+	switch {
+	case f.format != "":
+		return export.ParseFormat(f.format)
+	case f.output != "":
+		return export.FormatForPath(f.output)
+	default:
+		return export.Markdown, nil
+	}
+}
+
+// exportEntries reads the entries named by ids, in that order, or else the
+// entries selected by the filter flags, oldest first.
+func exportEntries(ctx context.Context, flags exportFlags, ids []string) ([]history.Entry, error) {
+	if len(ids) == 0 {
+		entries, err := filteredHistory(ctx, flags.history, flags.query)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(entries) == 0 {
+			return nil, errNoExportEntries
+		}
+
+		slices.Reverse(entries)
+
+		return entries, nil
+	}
+
+	store, err := openHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]history.Entry, 0, len(ids))
+
+	for _, id := range ids {
+		e, getErr := store.Get(ctx, id)
+		if getErr != nil {
+			return nil, fmt.Errorf("failed to read history: %w", getErr)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runExportCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"export"}, args...))
+
+	err := cmd.ExecuteContext(t.Context())
+
+	return out.String(), err
+}
+
+func TestExport_MarkdownToStdout(t *testing.T) {
+	seedHistory(t, sampleEntries()...)
+
+	out, err := runExportCmd(t)
+
+	require.NoError(t, err)
+	assert.Contains(t, out, "# Prompt Maker session")
+	assert.Less(t, strings.Index(out, "email asking for a raise"), strings.Index(out, "Summarize the release notes"), "Oldest first")
+}
+
+func TestExport_FiltersAndIDs(t *testing.T) {
+	seedHistory(t, sampleEntries()...)
+
+	out, err := runExportCmd(t, "--model", "gemini-2.5-flash", "--format", "json")
+	require.NoError(t, err)
+	assert.Contains(t, out, "bbb222")
+	assert.NotContains(t, out, "aaa111")
+
+	out, err = runExportCmd(t, "aaa111", "--title", "Raise")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out, "# Raise\n"))
+
+	_, err = runExportCmd(t, "--search", "nothing like this")
+	require.ErrorIs(t, err, errNoExportEntries)
+}
+
+func TestExport_HTMLFileFromExtension(t *testing.T) {
+	seedHistory(t, sampleEntries()...)
+
+	path := filepath.Join(t.TempDir(), "session.html")
+
+	out, err := runExportCmd(t, "-o", path, "--theme", "flexoki")
+	require.NoError(t, err)
+	assert.Equal(t, "Exported 2 entries to "+path+"\n", out)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `data-theme="flexoki"`)
+
+	_, err = runExportCmd(t, "-o", filepath.Join(t.TempDir(), "session.txt"))
+	require.Error(t, err)
+}
@@ -0,0 +1,578 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"prompt-maker/internal/config"
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/prompt"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// dateLayout is accepted by --since and --until and used in listings.
+	dateLayout = "2006-01-02"
+	// listTimeLayout formats entry times in listings.
+	listTimeLayout = "2006-01-02 15:04"
+	// maxTitleWidth truncates entry titles in listings.
+	maxTitleWidth = 60
+	// tabPadding separates listing columns.
+	tabPadding = 2
+	// rateArgs is an entry ID and a rating.
+	rateArgs = 2
+	// hoursPerDay converts the "d" suffix of relative times.
+	hoursPerDay = 24
+)
+
+// errNothingToRerun is returned for entries with neither an input nor an executed prompt.
+var errNothingToRerun = errors.New("history entry has no input or prompt to replay")
+
+// errInvalidTime is returned for --since and --until values that cannot be parsed.
+var errInvalidTime = errors.New(`invalid time: use YYYY-MM-DD, RFC 3339 or a relative duration such as "36h" or "7d"`)
+
+// historyFlags holds the filter and output flags shared by list and search.
+type historyFlags struct {
+	since   string
+	until   string
+	model   string
+	persona string
+	tag     string
+	rating  string
+	limit   int
+	json    bool
+}
+
+// newHistoryCmd creates the history command and its subcommands.
+func (a *app) newHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Browse, search, rerun and delete past crafts and executions.",
+	}
+
+	cmd.AddCommand(
+		newHistoryListCmd(),
+		newHistorySearchCmd(),
+		newHistoryShowCmd(),
+		a.newHistoryRerunCmd(),
+		newHistoryTagCmd(),
+		newHistoryRateCmd(),
+		newHistoryRmCmd(),
+	)
+
+	return cmd
+}
+
+func newHistoryListCmd() *cobra.Command {
+	var flags historyFlags
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List history entries, newest first.",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, _ []string) error {
+			return listHistory(c, flags, "")
+		},
+	}
+
+	addHistoryFlags(cmd, &flags)
+
+	return cmd
+}
+
+func newHistorySearchCmd() *cobra.Command {
+	var flags historyFlags
+
+	cmd := &cobra.Command{
+		Use:   "search QUERY",
+		Short: "Search the inputs, prompts and answers of history entries.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			return listHistory(c, flags, args[0])
+		},
+	}
+
+	addHistoryFlags(cmd, &flags)
+
+	return cmd
+}
+
+func addHistoryFlags(cmd *cobra.Command, flags *historyFlags) {
+	addFilterFlags(cmd, flags)
+	cmd.Flags().BoolVar(&flags.json, "json", false, "Print entries as JSON")
+}
+
+// addFilterFlags adds the flags that select history entries.
+func addFilterFlags(cmd *cobra.Command, flags *historyFlags) {
+	cmd.Flags().StringVar(&flags.since, "since", "", "Only entries created on or after this date (YYYY-MM-DD) or duration ago (e.g. 7d)")
+	cmd.Flags().StringVar(&flags.until, "until", "", "Only entries created before the end of this date or duration ago")
+	cmd.Flags().StringVar(&flags.model, "model", "", "Only entries that used this model")
+	cmd.Flags().StringVar(&flags.persona, "persona", "", "Only entries crafted with this persona")
+	cmd.Flags().StringVar(&flags.tag, "tag", "", "Only entries with this tag")
+	cmd.Flags().StringVar(&flags.rating, "rating", "", `Only entries whose crafted prompt was rated "up" or "down"`)
+	cmd.Flags().IntVar(&flags.limit, "limit", 0, "Use at most this many entries, newest first (0 uses all)")
+}
+
+func listHistory(c *cobra.Command, flags historyFlags, query string) error {
+	entries, err := filteredHistory(c.Context(), flags, query)
+	if err != nil {
+		return err
+	}
+
+	if flags.json {
+		return writeJSON(c.OutOrStdout(), entries)
+	}
+
+	return writeEntryTable(c.OutOrStdout(), entries)
+}
+
+// filteredHistory reads the entries selected by flags and query, newest first.
+func filteredHistory(ctx context.Context, flags historyFlags, query string) ([]history.Entry, error) {
+	filter, err := flags.filter(time.Now(), query)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := openHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	entries = filter.Apply(entries)
+	if flags.limit > 0 && len(entries) > flags.limit {
+		entries = entries[:flags.limit]
+	}
+
+	return entries, nil
+}
+
+func newHistoryShowCmd() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "show ID",
+		Short: "Show every detail of a history entry.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			store, err := openHistory()
+			if err != nil {
+				return err
+			}
+
+			entry, err := store.Get(c.Context(), args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read history: %w", err)
+			}
+
+			if asJSON {
+				return writeJSON(c.OutOrStdout(), entry)
+			}
+
+			return writeEntry(c.OutOrStdout(), entry)
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the entry as JSON")
+
+	return cmd
+}
+
+func (a *app) newHistoryRerunCmd() *cobra.Command {
+	var (
+		model   string
+		execute bool
+		asJSON  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rerun ID",
+		Short: "Replay a history entry with its original or an overridden model.",
+		Long: "Replay a history entry with the generation parameters it was recorded with.\n" +
+			"A crafted entry is crafted again from its rough input, and with --execute the new prompt\n" +
+			"is executed too; an execution-only entry is executed again. The result is saved as a new entry.\n" +
+			"Attachments are not stored in history, so they are not replayed. The model is always called\n" +
+			"again: cached responses are not used, even at temperature 0.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			entry, err := a.rerun(c.Context(), args[0], model, execute)
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				return writeJSON(c.OutOrStdout(), entry)
+			}
+
+			return writeEntry(c.OutOrStdout(), entry)
+		},
+	}
+
+	cmd.Flags().StringVar(&model, "model", "", "Model to use instead of the one recorded")
+	cmd.Flags().BoolVar(&execute, "execute", false, "Also execute the newly crafted prompt")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the new entry as JSON")
+
+	return cmd
+}
+
+// rerun replays the entry with id through the same prompt.Runner path as the
+// TUI and web server, and records the outcome as a new entry.
+func (a *app) rerun(ctx context.Context, id, model string, execute bool) (history.Entry, error) {
+	cfg, err := a.loadConfig()
+	if err != nil {
+		return history.Entry{}, err
+	}
+
+	store, err := openHistory()
+	if err != nil {
+		return history.Entry{}, err
+	}
+
+	entry, err := store.Get(ctx, id)
+	if err != nil {
+		return history.Entry{}, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	if entry.Input == "" && entry.ExecutedPrompt == "" {
+		return history.Entry{}, errNothingToRerun
+	}
+
+	model = cmp.Or(model, entry.Model(), config.DefaultModel)
+	genOpts := entry.Params().Options()
+
+	// A rerun asks the model again, so it bypasses the response cache.
+	creator, err := a.newChatCreator(ctx, cfg)
+	if err != nil {
+		return history.Entry{}, err
+	}
+
+	runner := prompt.NewRunner(creator, cfg.FallbackModels, genOpts)
+	recorder := history.NewRecorder(store, history.SourceCLI, genOpts)
+
+	var (
+		result prompt.Result
+		rerun  history.Entry
+	)
+
+	if entry.Input != "" {
+		if result, err = runner.Generate(ctx, model, entry.Input); err != nil {
+			return history.Entry{}, fmt.Errorf("failed to craft prompt: %w", err)
+		}
+
+		if rerun, err = recorder.Craft(ctx, entry.Input, nil, result); err != nil || !execute {
+			return rerun, recordError(err)
+		}
+
+		entry.ExecutedPrompt = result.Text
+	}
+
+	if result, err = runner.Execute(ctx, model, entry.ExecutedPrompt); err != nil {
+		return history.Entry{}, fmt.Errorf("failed to execute prompt: %w", err)
+	}
+
+	rerun, err = recorder.Execute(ctx, rerun.ID, entry.ExecutedPrompt, nil, result)
+
+	return rerun, recordError(err)
+}
+
+func newHistoryTagCmd() *cobra.Command {
+	var remove bool
+
+	cmd := &cobra.Command{
+		Use:   "tag ID TAG...",
+		Short: "Add tags to a history entry, or remove them with --remove.",
+		Args:  cobra.MinimumNArgs(minTagArgs),
+		RunE: func(c *cobra.Command, args []string) error {
+			entry, err := updateEntry(c.Context(), args[0], func(e *history.Entry) {
+				if remove {
+					e.Retag(nil, args[1:])
+				} else {
+					e.Retag(args[1:], nil)
+				}
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(c.OutOrStdout(), "%s: %s\n", entry.ID, cmp.Or(strings.Join(entry.Tags, ", "), "no tags"))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&remove, "remove", false, "Remove the tags instead of adding them")
+
+	return cmd
+}
+
+func newHistoryRateCmd() *cobra.Command {
+	var (
+		answer bool
+		note   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rate ID up|down",
+		Short: "Rate the crafted prompt of a history entry, or its answer with --answer.",
+		Args:  cobra.ExactArgs(rateArgs),
+		RunE: func(c *cobra.Command, args []string) error {
+			rating, err := history.ParseRating(args[1])
+			if err != nil {
+				return err
+			}
+
+			target := history.TargetPrompt
+			if answer {
+				target = history.TargetAnswer
+			}
+
+			store, err := openHistory()
+			if err != nil {
+				return err
+			}
+
+			entry, err := history.Rate(c.Context(), store, args[0], target, history.Feedback{Rating: rating, Note: note, At: time.Now()})
+			if err != nil {
+				return fmt.Errorf("failed to rate %s: %w", args[0], err)
+			}
+
+			fmt.Fprintf(c.OutOrStdout(), "Rated %s %s\n", entry.ID, rating)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&answer, "answer", false, "Rate the final answer instead of the crafted prompt")
+	cmd.Flags().StringVar(&note, "note", "", "Note explaining the rating")
+
+	return cmd
+}
+
+// updateEntry applies update to the entry with id and saves the result.
+func updateEntry(ctx context.Context, id string, update func(*history.Entry)) (history.Entry, error) {
+	store, err := openHistory()
+	if err != nil {
+		return history.Entry{}, err
+	}
+
+	entry, err := store.Get(ctx, id)
+	if err != nil {
+		return history.Entry{}, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	update(&entry)
+
+	if err = store.Save(ctx, entry); err != nil {
+		return history.Entry{}, fmt.Errorf("failed to save history entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+func newHistoryRmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "rm ID...",
+		Aliases: []string{"delete"},
+		Short:   "Delete history entries.",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			store, err := openHistory()
+			if err != nil {
+				return err
+			}
+
+			for _, id := range args {
+				if err := store.Delete(c.Context(), id); err != nil {
+					return fmt.Errorf("failed to delete %s: %w", id, err)
+				}
+
+				fmt.Fprintf(c.OutOrStdout(), "Deleted %s\n", id)
+			}
+
+			return nil
+		},
+	}
+}
+
+// filter converts the flags to a history.Filter relative to now.
+func (f historyFlags) filter(now time.Time, query string) (history.Filter, error) {
+	filter := history.Filter{Model: f.model, Persona: f.persona, Query: query, Tag: f.tag}
+
+	var err error
+
+	if f.rating != "" {
+		if filter.CraftRating, err = history.ParseRating(f.rating); err != nil {
+			return history.Filter{}, err
+		}
+	}
+
+	if f.since != "" {
+		if filter.Since, err = parseTime(f.since, now, false); err != nil {
+			return history.Filter{}, err
+		}
+	}
+
+	if f.until != "" {
+		if filter.Until, err = parseTime(f.until, now, true); err != nil {
+			return history.Filter{}, err
+		}
+	}
+
+	return filter, nil
+}
+
+// parseTime accepts a date, an RFC 3339 timestamp or a duration before now,
+// where "d" counts days. A date used as an upper bound covers the whole day.
+func parseTime(s string, now time.Time, endOfDay bool) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		s = days + "h"
+		if d, err := time.ParseDuration(s); err == nil {
+			return now.Add(-d * hoursPerDay), nil
+		}
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	if t, err := time.ParseInLocation(dateLayout, s, now.Location()); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %q", errInvalidTime, s)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+
+	return nil
+}
+
+func writeEntryTable(w io.Writer, entries []history.Entry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, tabPadding, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCREATED\tMODEL\tPERSONA\tTITLE")
+
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			e.ID, e.CreatedAt.Local().Format(listTimeLayout), e.Model(), cmp.Or(e.Persona, "-"), truncate(e.Title(), maxTitleWidth))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+
+	return nil
+}
+
+func writeEntry(w io.Writer, e history.Entry) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "ID:       %s\nCreated:  %s\nSource:   %s\n", e.ID, e.CreatedAt.Local().Format(time.RFC3339), e.Source)
+
+	if e.Persona != "" {
+		fmt.Fprintf(&b, "Persona:  %s\n", e.Persona)
+	}
+
+	if len(e.Attachments) > 0 {
+		fmt.Fprintf(&b, "Attached: %s\n", strings.Join(e.Attachments, ", "))
+	}
+
+	if len(e.Tags) > 0 {
+		fmt.Fprintf(&b, "Tags:     %s\n", strings.Join(e.Tags, ", "))
+	}
+
+	writeCall(&b, "Craft", e.Craft)
+	writeCall(&b, "Execute", e.Execute)
+	writeFeedback(&b, "Prompt", e.CraftFeedback)
+	writeFeedback(&b, "Answer", e.AnswerFeedback)
+	writeSection(&b, "Input", e.Input)
+	writeSection(&b, "Crafted prompt", e.CraftedPrompt)
+
+	if e.ExecutedPrompt != e.CraftedPrompt {
+		writeSection(&b, "Executed prompt", e.ExecutedPrompt)
+	}
+
+	writeSection(&b, "Answer", e.Answer)
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+
+	return nil
+}
+
+func writeCall(b *strings.Builder, label string, call *history.Call) {
+	if call == nil {
+		return
+	}
+
+	fmt.Fprintf(b, "%-9s %s, temperature %g, %s, tokens in %d · out %d · thinking %d\n",
+		label+":", call.Model, call.Params.Temperature, call.Latency.Round(time.Millisecond),
+		call.Usage.PromptTokens, call.Usage.OutputTokens, call.Usage.ThinkingTokens)
+}
+
+func writeFeedback(b *strings.Builder, label string, f *history.Feedback) {
+	if f == nil {
+		return
+	}
+
+	fmt.Fprintf(b, "%-9s rated %s", label+":", f.Rating)
+
+	if f.Note != "" {
+		fmt.Fprintf(b, ": %s", f.Note)
+	}
+
+	b.WriteString("\n")
+}
+
+func writeSection(b *strings.Builder, title, text string) {
+	if text == "" {
+		return
+	}
+
+	fmt.Fprintf(b, "\n--- %s ---\n%s\n", title, strings.TrimRight(text, "\n"))
+}
+
+// recordError wraps a failure to save a replayed result.
+func recordError(err error) error {
+	if err != nil {
+		return fmt.Errorf("failed to record history: %w", err)
+	}
+
+	return nil
+}
+
+// truncate shortens s to at most width runes, marking the cut with an ellipsis.
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+
+	return string(runes[:width-1]) + "…"
+}
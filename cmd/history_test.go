@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"prompt-maker/internal/config"
+	"prompt-maker/internal/gemini"
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+// seedHistory points the history at a temporary file holding entries.
+func seedHistory(t *testing.T, entries ...history.Entry) *history.FileStore {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	t.Setenv("PROMPT_MAKER_HISTORY_FILE", path)
+
+	store := history.NewFileStore(path)
+	for _, e := range entries {
+		require.NoError(t, store.Save(t.Context(), e))
+	}
+
+	return store
+}
+
+func runHistory(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"history"}, args...))
+
+	err := cmd.ExecuteContext(t.Context())
+
+	return out.String(), err
+}
+
+func sampleEntries() []history.Entry {
+	return []history.Entry{
+		{
+			ID: "aaa111", CreatedAt: time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC), Persona: "lyra",
+			Input: "email asking for a raise", CraftedPrompt: "You are an HR expert...",
+			Craft: &history.Call{Model: "gemini-2.5-pro"},
+		},
+		{
+			ID: "bbb222", CreatedAt: time.Date(2025, 5, 3, 9, 0, 0, 0, time.UTC),
+			ExecutedPrompt: "Summarize the release notes", Answer: "Three bug fixes.",
+			Execute: &history.Call{Model: "gemini-2.5-flash"},
+		},
+	}
+}
+
+func TestHistoryList(t *testing.T) {
+	seedHistory(t, sampleEntries()...)
+
+	out, err := runHistory(t, "list")
+
+	require.NoError(t, err)
+	assert.Contains(t, out, "ID")
+	assert.Less(t, bytes.Index([]byte(out), []byte("bbb222")), bytes.Index([]byte(out), []byte("aaa111")), "Newest first")
+	assert.Contains(t, out, "email asking for a raise")
+}
+
+func TestHistoryList_FiltersAndJSON(t *testing.T) {
+	seedHistory(t, sampleEntries()...)
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"model", []string{"--model", "gemini-2.5-flash"}, []string{"bbb222"}},
+		{"persona", []string{"--persona", "LYRA"}, []string{"aaa111"}},
+		{"since", []string{"--since", "2025-05-02"}, []string{"bbb222"}},
+		{"until", []string{"--until", "2025-05-01"}, []string{"aaa111"}},
+		{"limit", []string{"--limit", "1"}, []string{"bbb222"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runHistory(t, append([]string{"list", "--json"}, tt.args...)...)
+			require.NoError(t, err)
+
+			var entries []history.Entry
+			require.NoError(t, json.Unmarshal([]byte(out), &entries))
+
+			ids := make([]string, len(entries))
+			for i, e := range entries {
+				ids[i] = e.ID
+			}
+
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestHistorySearch(t *testing.T) {
+	seedHistory(t, sampleEntries()...)
+
+	out, err := runHistory(t, "search", "bug fixes")
+
+	require.NoError(t, err)
+	assert.Contains(t, out, "bbb222")
+	assert.NotContains(t, out, "aaa111")
+}
+
+func TestHistoryShowAndRm(t *testing.T) {
+	store := seedHistory(t, sampleEntries()...)
+
+	out, err := runHistory(t, "show", "aaa111")
+	require.NoError(t, err)
+	assert.Contains(t, out, "--- Crafted prompt ---\nYou are an HR expert...")
+	assert.Contains(t, out, "gemini-2.5-pro")
+
+	out, err = runHistory(t, "rm", "aaa111")
+	require.NoError(t, err)
+	assert.Equal(t, "Deleted aaa111\n", out)
+
+	_, err = store.Get(t.Context(), "aaa111")
+	require.ErrorIs(t, err, history.ErrNotFound)
+
+	_, err = runHistory(t, "show", "aaa111")
+	require.ErrorIs(t, err, history.ErrNotFound)
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2025, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	got, err := parseTime("7d", now, false)
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, -7), got)
+
+	got, err = parseTime("2025-05-01", now, true)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 5, 1, 23, 59, 59, 999999999, time.UTC), got)
+
+	_, err = parseTime("yesterday", now, false)
+	require.ErrorIs(t, err, errInvalidTime)
+}
+
+func TestApp_Rerun(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "test-key")
+
+	store := seedHistory(t, sampleEntries()...)
+
+	var models []string
+
+	a := &app{
+		newChatCreator: func(context.Context, *config.Config) (gemini.ChatCreator, error) {
+			return chatCreatorFunc(func(model string) gemini.ChatSession {
+				models = append(models, model)
+
+				return &testutil.MockChatSession{
+					SendMessageFunc: func(context.Context, ...genai.Part) (*genai.GenerateContentResponse, error) {
+						return &genai.GenerateContentResponse{
+							Candidates: []*genai.Candidate{{Content: genai.NewContentFromText("reply from "+model, genai.RoleModel)}},
+						}, nil
+					},
+				}
+			}), nil
+		},
+	}
+
+	entry, err := a.rerun(t.Context(), "aaa111", "gemini-2.5-flash-lite", true)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gemini-2.5-flash-lite", "gemini-2.5-flash-lite"}, models, "Crafts then executes with the override")
+	assert.NotEqual(t, "aaa111", entry.ID)
+	assert.Equal(t, history.SourceCLI, entry.Source)
+	assert.Equal(t, "email asking for a raise", entry.Input)
+	assert.Equal(t, "reply from gemini-2.5-flash-lite", entry.Answer)
+
+	entries, err := store.List(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
+
+func TestApp_Rerun_BypassesResponseCache(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	seedHistory(t, sampleEntries()...)
+
+	calls := 0
+	a := &app{
+		newChatCreator: func(context.Context, *config.Config) (gemini.ChatCreator, error) {
+			return chatCreatorFunc(func(string) gemini.ChatSession {
+				return &testutil.MockChatSession{
+					SendMessageFunc: func(context.Context, ...genai.Part) (*genai.GenerateContentResponse, error) {
+						calls++
+
+						return &genai.GenerateContentResponse{
+							Candidates: []*genai.Candidate{{Content: genai.NewContentFromText("reply", genai.RoleModel)}},
+						}, nil
+					},
+				}
+			}), nil
+		},
+	}
+
+	// The entry was executed at temperature 0, which the cache would answer.
+	for range 2 {
+		_, err := a.rerun(t.Context(), "bbb222", "", false)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 2, calls, "Every rerun calls the model")
+
+	for range 2 {
+		_, err := a.executePrompt(t.Context(), "gemini-2.5-flash", "Summarize the release notes")
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 3, calls, "Other commands still use the cache")
+}
+
+func TestHistoryRerun_TakesRootFlags(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	seedHistory(t)
+
+	_, err := runHistory(t, "rerun", "--no-cache", "--fallback", "gemini-2.5-flash", "zzz999")
+
+	require.ErrorIs(t, err, history.ErrNotFound)
+}
+
+// chatCreatorFunc adapts a function to gemini.ChatCreator.
+type chatCreatorFunc func(model string) gemini.ChatSession
+
+func (f chatCreatorFunc) Create(
+	_ context.Context, model string, _ *genai.GenerateContentConfig, _ []*genai.Content,
+) (gemini.ChatSession, error) {
+	return f(model), nil
+}
+
+func TestHistoryTagAndRate(t *testing.T) {
+	seedHistory(t, sampleEntries()...)
+
+	out, err := runHistory(t, "tag", "bbb222", "docs", "#Eval")
+	require.NoError(t, err)
+	assert.Equal(t, "bbb222: docs, eval\n", out)
+
+	out, err = runHistory(t, "tag", "bbb222", "docs", "--remove")
+	require.NoError(t, err)
+	assert.Equal(t, "bbb222: eval\n", out)
+
+	out, err = runHistory(t, "rate", "bbb222", "-", "--answer", "--note", "missed a fix")
+	require.NoError(t, err)
+	assert.Equal(t, "Rated bbb222 down\n", out)
+
+	out, err = runHistory(t, "show", "bbb222")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Tags:     eval")
+	assert.Contains(t, out, "Answer:   rated down: missed a fix")
+
+	_, err = runHistory(t, "rate", "aaa111", "up", "--answer")
+	require.ErrorIs(t, err, history.ErrNoAnswer)
+
+	_, err = runHistory(t, "rate", "aaa111", "meh")
+	require.ErrorIs(t, err, history.ErrInvalidRating)
+
+	out, err = runHistory(t, "list", "--tag", "eval")
+	require.NoError(t, err)
+	assert.Contains(t, out, "bbb222")
+	assert.NotContains(t, out, "aaa111")
+}
@@ -0,0 +1,556 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"prompt-maker/internal/config"
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/library"
+	"prompt-maker/internal/prompt"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// minTagArgs is a prompt name and at least one tag.
+	minTagArgs = 2
+	// maxDiffArgs is a prompt name and up to two versions.
+	maxDiffArgs = 3
+	// minDiffRevisions is the number of revisions a default diff compares.
+	minDiffRevisions = 2
+)
+
+// errInvalidVar is returned for --var values that are not NAME=VALUE.
+var errInvalidVar = errors.New("invalid --var: use NAME=VALUE")
+
+// errInvalidVersion is returned for versions that are not positive numbers.
+var errInvalidVersion = errors.New("invalid version: use a number such as 2 or v2")
+
+// errTooFewRevisions is returned when diffing a prompt that has one revision.
+var errTooFewRevisions = errors.New("prompt has a single revision; give two versions to compare")
+
+// newLibraryCmd creates the library command and its subcommands.
+func (a *app) newLibraryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "library",
+		Short: "Save, tag, version and reuse named prompts.",
+		Long: "Save, tag, version and reuse named prompts. Every save of a changed text is kept as a\n" +
+			"revision. The library is a directory of plain files that can be kept in git;\n" +
+			"set PROMPT_MAKER_LIBRARY_DIR to use another directory than the default.",
+	}
+
+	cmd.AddCommand(
+		newLibraryListCmd(),
+		newLibraryShowCmd(),
+		newLibrarySaveCmd(),
+		newLibraryTagCmd(),
+		newLibraryLogCmd(),
+		newLibraryDiffCmd(),
+		newLibraryRenderCmd(),
+		a.newLibraryRunCmd(),
+		newLibraryRmCmd(),
+	)
+
+	return cmd
+}
+
+func newLibraryListCmd() *cobra.Command {
+	var (
+		tag    string
+		asJSON bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list [QUERY]",
+		Short: "List saved prompts, optionally searching their names, descriptions and text.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			lib, err := openLibrary()
+			if err != nil {
+				return err
+			}
+
+			prompts, err := lib.List(c.Context())
+			if err != nil {
+				return fmt.Errorf("failed to read library: %w", err)
+			}
+
+			filter := library.Filter{Tag: tag}
+			if len(args) > 0 {
+				filter.Query = args[0]
+			}
+
+			prompts = filter.Apply(prompts)
+
+			if asJSON {
+				return writeJSON(c.OutOrStdout(), prompts)
+			}
+
+			return writePromptTable(c.OutOrStdout(), prompts)
+		},
+	}
+
+	cmd.Flags().StringVar(&tag, "tag", "", "Only prompts with this tag")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print prompts as JSON")
+
+	return cmd
+}
+
+func newLibraryShowCmd() *cobra.Command {
+	var (
+		version string
+		raw     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "show NAME",
+		Short: "Show a saved prompt and its metadata.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			p, rev, err := loadRevision(c.Context(), args[0], version)
+			if err != nil {
+				return err
+			}
+
+			if raw {
+				_, err = io.WriteString(c.OutOrStdout(), rev.Text)
+
+				return err
+			}
+
+			return writePrompt(c.OutOrStdout(), p, rev)
+		},
+	}
+
+	cmd.Flags().StringVar(&version, "version", "", "Show this revision instead of the newest")
+	cmd.Flags().BoolVar(&raw, "raw", false, "Print only the prompt text")
+
+	return cmd
+}
+
+func newLibrarySaveCmd() *cobra.Command {
+	var (
+		file        string
+		fromHistory string
+		opts        library.SaveOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "save NAME",
+		Short: "Save a prompt, or a new revision of it, from a file, a history entry or stdin.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			if !c.Flags().Changed("tag") {
+				opts.Tags = nil
+			}
+
+			text, entryID, err := promptSource(c, file, fromHistory)
+			if err != nil {
+				return err
+			}
+
+			opts.EntryID = entryID
+
+			lib, err := openLibrary()
+			if err != nil {
+				return err
+			}
+
+			p, err := lib.Save(c.Context(), args[0], text, opts)
+			if err != nil {
+				return fmt.Errorf("failed to save %s: %w", args[0], err)
+			}
+
+			fmt.Fprintf(c.OutOrStdout(), "Saved %s v%d\n", p.Name, p.Latest().Version)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "Read the prompt from this file")
+	cmd.Flags().StringVar(&fromHistory, "from-history", "", "Save the crafted prompt of this history entry")
+	cmd.Flags().StringSliceVar(&opts.Tags, "tag", nil, "Replace the tags of the prompt (repeatable or comma-separated)")
+	cmd.Flags().StringVar(&opts.Description, "description", "", "Describe what the prompt is for")
+	cmd.Flags().StringVar(&opts.Note, "note", "", "Describe what changed in this revision")
+
+	return cmd
+}
+
+// promptSource reads the text to save from a file, a history entry or
+// stdin, and returns the history entry it came from, if any.
+func promptSource(c *cobra.Command, file, entryID string) (string, string, error) {
+	switch {
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		return string(data), "", nil
+	case entryID != "":
+		store, err := openHistory()
+		if err != nil {
+			return "", "", err
+		}
+
+		entry, err := store.Get(c.Context(), entryID)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read history: %w", err)
+		}
+
+		return cmp.Or(entry.CraftedPrompt, entry.ExecutedPrompt), entry.ID, nil
+	default:
+		data, err := io.ReadAll(c.InOrStdin())
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read stdin: %w", err)
+		}
+
+		return string(data), "", nil
+	}
+}
+
+func newLibraryTagCmd() *cobra.Command {
+	var remove bool
+
+	cmd := &cobra.Command{
+		Use:   "tag NAME TAG...",
+		Short: "Add tags to a saved prompt, or remove them with --remove.",
+		Args:  cobra.MinimumNArgs(minTagArgs),
+		RunE: func(c *cobra.Command, args []string) error {
+			lib, err := openLibrary()
+			if err != nil {
+				return err
+			}
+
+			add, del := args[1:], []string(nil)
+			if remove {
+				add, del = nil, args[1:]
+			}
+
+			p, err := lib.Tag(c.Context(), args[0], add, del)
+			if err != nil {
+				return fmt.Errorf("failed to tag %s: %w", args[0], err)
+			}
+
+			fmt.Fprintf(c.OutOrStdout(), "%s: %s\n", p.Name, cmp.Or(strings.Join(p.Tags, ", "), "no tags"))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&remove, "remove", false, "Remove the tags instead of adding them")
+
+	return cmd
+}
+
+func newLibraryLogCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "log NAME",
+		Short: "List the revisions of a saved prompt, newest first.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			p, _, err := loadRevision(c.Context(), args[0], "")
+			if err != nil {
+				return err
+			}
+
+			tw := tabwriter.NewWriter(c.OutOrStdout(), 0, 0, tabPadding, ' ', 0)
+			fmt.Fprintln(tw, "VERSION\tCREATED\tENTRY\tNOTE")
+
+			for i := len(p.Revisions) - 1; i >= 0; i-- {
+				rev := p.Revisions[i]
+				fmt.Fprintf(tw, "v%d\t%s\t%s\t%s\n",
+					rev.Version, rev.CreatedAt.Local().Format(listTimeLayout), cmp.Or(rev.EntryID, "-"), rev.Note)
+			}
+
+			if err := tw.Flush(); err != nil {
+				return fmt.Errorf("failed to write library: %w", err)
+			}
+
+			return nil
+		},
+	}
+}
+
+func newLibraryDiffCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff NAME [FROM [TO]]",
+		Short: "Show what changed between two revisions, by default the last two.",
+		Args:  cobra.RangeArgs(1, maxDiffArgs),
+		RunE: func(c *cobra.Command, args []string) error {
+			p, to, err := loadRevision(c.Context(), args[0], "")
+			if err != nil {
+				return err
+			}
+
+			from, to, err := diffRevisions(p, to, args[1:])
+			if err != nil {
+				return err
+			}
+
+			_, err = io.WriteString(c.OutOrStdout(), library.Diff(p.Name, from, to))
+
+			return err
+		},
+	}
+}
+
+// diffRevisions picks the revisions of p to compare from the given
+// versions: none compares the last two, one compares it with the newest.
+func diffRevisions(p library.Prompt, latest library.Revision, versions []string) (from, to library.Revision, err error) {
+	to = latest
+
+	if len(versions) == 0 {
+		if len(p.Revisions) < minDiffRevisions {
+			return from, to, errTooFewRevisions
+		}
+
+		return p.Revisions[len(p.Revisions)-2], to, nil
+	}
+
+	if from, err = revisionOf(p, versions[0]); err != nil {
+		return from, to, err
+	}
+
+	if len(versions) > 1 {
+		to, err = revisionOf(p, versions[1])
+	}
+
+	return from, to, err
+}
+
+func newLibraryRenderCmd() *cobra.Command {
+	var (
+		version string
+		vars    []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "render NAME",
+		Short: "Print a saved prompt with its {{variables}} filled in.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			text, err := renderPrompt(c.Context(), args[0], version, vars)
+			if err != nil {
+				return err
+			}
+
+			_, err = io.WriteString(c.OutOrStdout(), text)
+
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&version, "version", "", "Render this revision instead of the newest")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Value of a variable as NAME=VALUE (repeatable)")
+
+	return cmd
+}
+
+func (a *app) newLibraryRunCmd() *cobra.Command {
+	var (
+		version string
+		vars    []string
+		model   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "run NAME",
+		Short: "Execute a saved prompt with its {{variables}} filled in and print the answer.",
+		Long: "Execute a saved prompt with its {{variables}} filled in and print the answer.\n" +
+			"The execution is recorded in history.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			text, err := renderPrompt(c.Context(), args[0], version, vars)
+			if err != nil {
+				return err
+			}
+
+			answer, err := a.executePrompt(c.Context(), cmp.Or(model, config.DefaultModel), text)
+			if err != nil {
+				return err
+			}
+
+			_, err = fmt.Fprintln(c.OutOrStdout(), strings.TrimRight(answer, "\n"))
+
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&version, "version", "", "Run this revision instead of the newest")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Value of a variable as NAME=VALUE (repeatable)")
+	cmd.Flags().StringVar(&model, "model", "", "Model to execute the prompt with")
+
+	return cmd
+}
+
+// executePrompt sends text for a final answer through the same prompt.Runner
+// path as the TUI and web server, and records it in history.
+func (a *app) executePrompt(ctx context.Context, model, text string) (string, error) {
+	cfg, err := a.loadConfig()
+	if err != nil {
+		return "", err
+	}
+
+	creator, err := a.cachedChatCreator(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := prompt.NewRunner(creator, cfg.FallbackModels, a.generationOptions()).Execute(ctx, model, text)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute prompt: %w", err)
+	}
+
+	if recorder := a.newRecorder(ctx, history.SourceCLI); recorder != nil {
+		if _, err := recorder.Execute(ctx, "", text, nil, result); err != nil {
+			return "", recordError(err)
+		}
+	}
+
+	return result.Text, nil
+}
+
+func newLibraryRmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "rm NAME...",
+		Aliases: []string{"delete"},
+		Short:   "Delete saved prompts and all their revisions.",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			lib, err := openLibrary()
+			if err != nil {
+				return err
+			}
+
+			for _, name := range args {
+				if err := lib.Delete(c.Context(), name); err != nil {
+					return fmt.Errorf("failed to delete %s: %w", name, err)
+				}
+
+				fmt.Fprintf(c.OutOrStdout(), "Deleted %s\n", library.NormalizeName(name))
+			}
+
+			return nil
+		},
+	}
+}
+
+// loadRevision reads the prompt called name and its revision version, or
+// its newest revision when version is empty.
+func loadRevision(ctx context.Context, name, version string) (library.Prompt, library.Revision, error) {
+	lib, err := openLibrary()
+	if err != nil {
+		return library.Prompt{}, library.Revision{}, err
+	}
+
+	p, err := lib.Get(ctx, name)
+	if err != nil {
+		return library.Prompt{}, library.Revision{}, fmt.Errorf("failed to read library: %w", err)
+	}
+
+	if version == "" {
+		return p, p.Latest(), nil
+	}
+
+	rev, err := revisionOf(p, version)
+
+	return p, rev, err
+}
+
+// revisionOf returns the revision of p named by version, such as "2" or "v2".
+func revisionOf(p library.Prompt, version string) (library.Revision, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil || n < 1 {
+		return library.Revision{}, fmt.Errorf("%w: %q", errInvalidVersion, version)
+	}
+
+	rev, err := p.Revision(n)
+	if err != nil {
+		return library.Revision{}, fmt.Errorf("failed to read library: %w", err)
+	}
+
+	return rev, nil
+}
+
+// renderPrompt fills the variables of a saved prompt from NAME=VALUE pairs.
+func renderPrompt(ctx context.Context, name, version string, vars []string) (string, error) {
+	_, rev, err := loadRevision(ctx, name, version)
+	if err != nil {
+		return "", err
+	}
+
+	values := make(map[string]string, len(vars))
+
+	for _, v := range vars {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return "", fmt.Errorf("%w: %q", errInvalidVar, v)
+		}
+
+		values[key] = value
+	}
+
+	text, err := library.Render(rev.Text, values)
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+
+	return text, nil
+}
+
+func writePromptTable(w io.Writer, prompts []library.Prompt) error {
+	tw := tabwriter.NewWriter(w, 0, 0, tabPadding, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVERSION\tUPDATED\tTAGS\tDESCRIPTION")
+
+	for _, p := range prompts {
+		fmt.Fprintf(tw, "%s\tv%d\t%s\t%s\t%s\n",
+			p.Name, p.Latest().Version, p.UpdatedAt.Local().Format(listTimeLayout),
+			cmp.Or(strings.Join(p.Tags, ","), "-"), truncate(p.Description, maxTitleWidth))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to write library: %w", err)
+	}
+
+	return nil
+}
+
+func writePrompt(w io.Writer, p library.Prompt, rev library.Revision) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Name:     %s\nVersion:  v%d of %d\nSaved:    %s\n",
+		p.Name, rev.Version, len(p.Revisions), rev.CreatedAt.Local().Format(listTimeLayout))
+
+	if len(p.Tags) > 0 {
+		fmt.Fprintf(&b, "Tags:     %s\n", strings.Join(p.Tags, ", "))
+	}
+
+	if vars := library.Variables(rev.Text); len(vars) > 0 {
+		fmt.Fprintf(&b, "Vars:     %s\n", strings.Join(vars, ", "))
+	}
+
+	if p.Description != "" {
+		fmt.Fprintf(&b, "About:    %s\n", p.Description)
+	}
+
+	if rev.Note != "" {
+		fmt.Fprintf(&b, "Note:     %s\n", rev.Note)
+	}
+
+	writeSection(&b, "Prompt", rev.Text)
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write library: %w", err)
+	}
+
+	return nil
+}
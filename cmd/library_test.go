@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"prompt-maker/internal/config"
+	"prompt-maker/internal/gemini"
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+// useLibrary points the library at a temporary directory.
+func useLibrary(t *testing.T) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "library")
+	t.Setenv("PROMPT_MAKER_LIBRARY_DIR", dir)
+
+	return dir
+}
+
+func runLibrary(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	cmd := NewRootCmd()
+	cmd.SetIn(strings.NewReader(stdin))
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"library"}, args...))
+
+	err := cmd.ExecuteContext(t.Context())
+
+	return out.String(), err
+}
+
+func TestLibrary_SaveShowAndDiff(t *testing.T) {
+	useLibrary(t)
+	seedHistory(t, sampleEntries()...)
+
+	out, err := runLibrary(t, "", "save", "Raise Email", "--from-history", "aaa111", "--tag", "email,hr")
+	require.NoError(t, err)
+	assert.Equal(t, "Saved raise-email v1\n", out)
+
+	out, err = runLibrary(t, "You are an HR expert writing to {{manager}}...\n", "save", "raise-email", "--note", "address the manager")
+	require.NoError(t, err)
+	assert.Equal(t, "Saved raise-email v2\n", out)
+
+	out, err = runLibrary(t, "", "list", "--tag", "HR")
+	require.NoError(t, err)
+	assert.Contains(t, out, "raise-email")
+	assert.Contains(t, out, "v2")
+	assert.Contains(t, out, "email,hr", "Tags survive a save without --tag")
+
+	out, err = runLibrary(t, "", "show", "raise-email")
+	require.NoError(t, err)
+	assert.Contains(t, out, "v2 of 2")
+	assert.Contains(t, out, "Vars:     manager")
+
+	out, err = runLibrary(t, "", "show", "raise-email", "--version", "v1", "--raw")
+	require.NoError(t, err)
+	assert.Equal(t, "You are an HR expert...", out)
+
+	out, err = runLibrary(t, "", "log", "raise-email")
+	require.NoError(t, err)
+	assert.Less(t, strings.Index(out, "v2"), strings.Index(out, "v1"), "Newest first")
+	assert.Contains(t, out, "aaa111")
+	assert.Contains(t, out, "address the manager")
+
+	out, err = runLibrary(t, "", "diff", "raise-email")
+	require.NoError(t, err)
+	assert.Contains(t, out, "-You are an HR expert...\n+You are an HR expert writing to {{manager}}...\n")
+}
+
+func TestLibrary_TagRenderAndRm(t *testing.T) {
+	useLibrary(t)
+
+	_, err := runLibrary(t, "Notes for {{version}} in {{tone}} tone.", "save", "notes")
+	require.NoError(t, err)
+
+	out, err := runLibrary(t, "", "tag", "notes", "docs", "release")
+	require.NoError(t, err)
+	assert.Equal(t, "notes: docs, release\n", out)
+
+	out, err = runLibrary(t, "", "tag", "notes", "release", "--remove")
+	require.NoError(t, err)
+	assert.Equal(t, "notes: docs\n", out)
+
+	out, err = runLibrary(t, "", "render", "notes", "--var", "version=v2", "--var", "tone=a=b")
+	require.NoError(t, err)
+	assert.Equal(t, "Notes for v2 in a=b tone.", out)
+
+	_, err = runLibrary(t, "", "render", "notes", "--var", "version=v2")
+	require.ErrorContains(t, err, "tone")
+
+	_, err = runLibrary(t, "", "diff", "notes")
+	require.ErrorIs(t, err, errTooFewRevisions)
+
+	_, err = runLibrary(t, "", "show", "notes", "--version", "x")
+	require.ErrorIs(t, err, errInvalidVersion)
+
+	out, err = runLibrary(t, "", "rm", "notes")
+	require.NoError(t, err)
+	assert.Equal(t, "Deleted notes\n", out)
+
+	out, err = runLibrary(t, "", "list")
+	require.NoError(t, err)
+	assert.NotContains(t, out, "notes")
+}
+
+func TestApp_ExecutePrompt_RecordsHistory(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "test-key")
+
+	store := seedHistory(t)
+
+	a := &app{
+		newChatCreator: func(context.Context, *config.Config) (gemini.ChatCreator, error) {
+			return chatCreatorFunc(func(model string) gemini.ChatSession {
+				return &testutil.MockChatSession{
+					SendMessageFunc: func(context.Context, ...genai.Part) (*genai.GenerateContentResponse, error) {
+						return &genai.GenerateContentResponse{
+							Candidates: []*genai.Candidate{{Content: genai.NewContentFromText("answer from "+model, genai.RoleModel)}},
+						}, nil
+					},
+				}
+			}), nil
+		},
+	}
+
+	answer, err := a.executePrompt(t.Context(), "gemini-2.5-flash", "Notes for v2.")
+
+	require.NoError(t, err)
+	assert.Equal(t, "answer from gemini-2.5-flash", answer)
+
+	entries, err := store.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, history.SourceCLI, entries[0].Source)
+	assert.Equal(t, "Notes for v2.", entries[0].ExecutedPrompt)
+}
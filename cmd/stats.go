@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"prompt-maker/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+// percent converts a share to a percentage.
+const percent = 100
+
+// newStatsCmd creates the stats command.
+func newStatsCmd() *cobra.Command {
+	var flags historyFlags
+
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Report how crafted prompts and answers were rated, per model and persona.",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, _ []string) error {
+			entries, err := filteredHistory(c.Context(), flags, "")
+			if err != nil {
+				return err
+			}
+
+			stats := history.ComputeStats(entries)
+			if flags.json {
+				return writeJSON(c.OutOrStdout(), stats)
+			}
+
+			return writeStats(c.OutOrStdout(), stats)
+		},
+	}
+
+	addHistoryFlags(cmd, &flags)
+
+	return cmd
+}
+
+// writeStats prints a table of rating counts for each grouping.
+func writeStats(w io.Writer, stats history.Stats) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d history entries\n", stats.Entries)
+
+	for _, section := range []struct {
+		title  string
+		column string
+		groups []history.Group
+	}{
+		{"Crafted prompts by model", "MODEL", stats.PromptsByModel},
+		{"Crafted prompts by persona", "PERSONA", stats.PromptsByPersona},
+		{"Answers by model", "MODEL", stats.AnswersByModel},
+		{"Answers by persona", "PERSONA", stats.AnswersByPersona},
+	} {
+		if len(section.groups) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n%s\n", section.title)
+
+		tw := tabwriter.NewWriter(&b, 0, 0, tabPadding, ' ', 0)
+		fmt.Fprintf(tw, "%s\tUP\tDOWN\tUNRATED\tUP %%\n", section.column)
+
+		for _, g := range section.groups {
+			share := "-"
+			if g.Rated() > 0 {
+				share = fmt.Sprintf("%.0f%%", g.UpShare()*percent)
+			}
+
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n", g.Name, g.Up, g.Down, g.Unrated, share)
+		}
+
+		if err := tw.Flush(); err != nil {
+			return fmt.Errorf("failed to write stats: %w", err)
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write stats: %w", err)
+	}
+
+	return nil
+}
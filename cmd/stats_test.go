@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	seedHistory(t, sampleEntries()...)
+
+	_, err := runHistory(t, "rate", "aaa111", "up")
+	require.NoError(t, err)
+	_, err = runHistory(t, "rate", "bbb222", "down", "--answer")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+
+	cmd := NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"stats"})
+	require.NoError(t, cmd.ExecuteContext(t.Context()))
+
+	got := out.String()
+	assert.Contains(t, got, "2 history entries")
+	assert.Contains(t, got, "Crafted prompts by model\nMODEL           UP  DOWN  UNRATED  UP %\ngemini-2.5-pro  1   0     0        100%\n")
+	assert.Contains(t, got, "Answers by persona\nPERSONA  UP  DOWN  UNRATED  UP %\n(none)   0   1     0        0%\n")
+}
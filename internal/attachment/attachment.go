@@ -0,0 +1,154 @@
+// Package attachment loads local files so they can be sent to the model as
+// context: images as inline data and text files as labeled text parts.
+package attachment
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+)
+
+// MaxSize is the largest file, in bytes, that may be attached.
+const MaxSize = 8 << 20
+
+var (
+	// ErrTooLarge is returned when a file exceeds MaxSize.
+	ErrTooLarge = errors.New("attachment is too large")
+	// ErrUnsupportedType is returned for files that are neither a supported image nor text.
+	ErrUnsupportedType = errors.New("unsupported attachment type")
+	// ErrEmpty is returned for empty files.
+	ErrEmpty = errors.New("attachment is empty")
+)
+
+// Attachment is a file sent to the model alongside the prompt.
+type Attachment struct {
+	Name     string
+	MIMEType string
+	Data     []byte
+}
+
+// Load reads the file at path and validates it with New.
+func Load(path string) (Attachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("reading attachment: %w", err)
+	}
+
+	if info.Size() > MaxSize {
+		return Attachment{}, fmt.Errorf("%w: %s is %d bytes (max %d)", ErrTooLarge, path, info.Size(), MaxSize)
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // Attaching user-chosen files is the point of this function.
+	if err != nil {
+		return Attachment{}, fmt.Errorf("reading attachment: %w", err)
+	}
+
+	return New(filepath.Base(path), data)
+}
+
+// Read reads up to MaxSize bytes from r and validates them with New. It is
+// used for uploads, whose size is not known up front.
+func Read(name string, r io.Reader) (Attachment, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return Attachment{}, fmt.Errorf("reading attachment %s: %w", name, err)
+	}
+
+	if len(data) > MaxSize {
+		return Attachment{}, fmt.Errorf("%w: %s (max %d bytes)", ErrTooLarge, name, MaxSize)
+	}
+
+	return New(name, data)
+}
+
+// New validates data and detects its MIME type. Supported images are kept
+// as binary; anything else must be valid UTF-8 text.
+func New(name string, data []byte) (Attachment, error) {
+	if len(data) == 0 {
+		return Attachment{}, fmt.Errorf("%w: %s", ErrEmpty, name)
+	}
+
+	if len(data) > MaxSize {
+		return Attachment{}, fmt.Errorf("%w: %s (max %d bytes)", ErrTooLarge, name, MaxSize)
+	}
+
+	if sniffed := baseType(http.DetectContentType(data)); isImageType(sniffed) {
+		return Attachment{Name: name, MIMEType: sniffed, Data: data}, nil
+	}
+
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return Attachment{}, fmt.Errorf("%w: %s is neither a supported image nor text", ErrUnsupportedType, name)
+	}
+
+	return Attachment{Name: name, MIMEType: textType(name), Data: data}, nil
+}
+
+// IsImage reports whether the attachment is sent as inline image data.
+func (a Attachment) IsImage() bool {
+	return isImageType(a.MIMEType)
+}
+
+// Part converts the attachment to a genai.Part. Text files are wrapped in a
+// block labeled with the file name so the model can tell them apart.
+func (a Attachment) Part() genai.Part {
+	if a.IsImage() {
+		return *genai.NewPartFromBytes(a.Data, a.MIMEType)
+	}
+
+	return genai.Part{Text: fmt.Sprintf(
+		"--- Attached file: %s (%s) ---\n%s\n--- End of %s ---",
+		a.Name, a.MIMEType, strings.TrimRight(string(a.Data), "\n"), a.Name,
+	)}
+}
+
+// Names lists the attachment names, for display.
+func Names(attachments []Attachment) []string {
+	names := make([]string, len(attachments))
+	for i, a := range attachments {
+		names[i] = a.Name
+	}
+
+	return names
+}
+
+// isImageType reports whether t is an image MIME type the Gemini API
+// accepts as inline data.
+func isImageType(t string) bool {
+	switch t {
+	case "image/png", "image/jpeg", "image/webp", "image/heic", "image/heif":
+		return true
+	}
+
+	return false
+}
+
+// textType picks a MIME type for a text file from its extension, defaulting
+// to text/plain for source code and unknown extensions.
+func textType(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == ".md" || ext == ".markdown" {
+		return "text/markdown"
+	}
+
+	if t := baseType(mime.TypeByExtension(ext)); strings.HasPrefix(t, "text/") {
+		return t
+	}
+
+	return "text/plain"
+}
+
+// baseType strips parameters such as charset from a MIME type.
+func baseType(t string) string {
+	base, _, _ := strings.Cut(t, ";")
+
+	return strings.TrimSpace(base)
+}
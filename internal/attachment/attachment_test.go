@@ -0,0 +1,76 @@
+package attachment
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is enough of a PNG file for content sniffing.
+const pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func TestNew_Image(t *testing.T) {
+	a, err := New("shot.png", []byte(pngHeader))
+
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", a.MIMEType)
+	assert.True(t, a.IsImage())
+
+	part := a.Part()
+	require.NotNil(t, part.InlineData)
+	assert.Equal(t, "image/png", part.InlineData.MIMEType)
+}
+
+func TestNew_TextIsLabeled(t *testing.T) {
+	a, err := New("main.go", []byte("package main\n"))
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(a.MIMEType, "text/"), "Source code is sent as text")
+	assert.False(t, a.IsImage())
+
+	text := a.Part().Text
+	assert.True(t, strings.HasPrefix(text, "--- Attached file: main.go ("+a.MIMEType+") ---\n"))
+	assert.Contains(t, text, "package main")
+	assert.True(t, strings.HasSuffix(text, "--- End of main.go ---"))
+}
+
+func TestNew_Markdown(t *testing.T) {
+	a, err := New("spec.md", []byte("# Spec"))
+
+	require.NoError(t, err)
+	assert.Equal(t, "text/markdown", a.MIMEType)
+}
+
+func TestNew_Rejects(t *testing.T) {
+	_, err := New("blob.bin", []byte{0x00, 0xff, 0xfe})
+	require.ErrorIs(t, err, ErrUnsupportedType)
+
+	_, err = New("empty.txt", nil)
+	require.ErrorIs(t, err, ErrEmpty)
+
+	_, err = New("big.txt", make([]byte, MaxSize+1))
+	require.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestRead_TooLarge(t *testing.T) {
+	_, err := Read("big.txt", strings.NewReader(strings.Repeat("a", MaxSize+1)))
+	require.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("remember the milk"), 0o600))
+
+	a, err := Load(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "notes.txt", a.Name)
+	assert.Equal(t, "text/plain", a.MIMEType)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+}
@@ -0,0 +1,242 @@
+// Package cache implements a small content-addressed on-disk cache with a
+// time-to-live and a size cap enforced by least-recently-used eviction.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL is how long an entry stays valid.
+	DefaultTTL = 7 * 24 * time.Hour
+	// DefaultMaxBytes caps the total size of the cache directory.
+	DefaultMaxBytes = 64 << 20
+	// entryExt is the file extension of cache entries.
+	entryExt = ".json"
+	// dirPerm keeps cached responses private to the user.
+	dirPerm = 0o700
+)
+
+// ErrInvalidKey is returned for keys that are not produced by Key.
+var ErrInvalidKey = errors.New("invalid cache key")
+
+// Store is an on-disk cache rooted at a directory. Each entry is a file
+// named after its key; the file's modification time records the last use,
+// which drives eviction. It is safe for concurrent use.
+type Store struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+// entry is the on-disk representation of a cached value.
+type entry struct {
+	Created time.Time       `json:"created"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// DefaultDir returns the per-user cache directory for prompt-maker.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locating user cache directory: %w", err)
+	}
+
+	return filepath.Join(dir, "prompt-maker", "responses"), nil
+}
+
+// Open creates dir if needed and returns a Store using it. A non-positive
+// ttl or maxBytes selects the default.
+func Open(dir string, ttl time.Duration, maxBytes int64) (*Store, error) {
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+
+	return &Store{dir: dir, ttl: ttl, maxBytes: maxBytes, now: time.Now}, nil
+}
+
+// Key derives a cache key from the JSON encoding of parts.
+func Key(parts ...any) (string, error) {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+
+	for _, p := range parts {
+		if err := enc.Encode(p); err != nil {
+			return "", fmt.Errorf("encoding cache key: %w", err)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Get decodes the value stored under key into v. It reports false on a miss,
+// including when the entry has expired or cannot be read.
+func (s *Store) Get(key string, v any) bool {
+	p, err := s.path(key)
+	if err != nil {
+		return false
+	}
+
+	data, err := os.ReadFile(p) //nolint:gosec // The path is built from a validated hex key.
+	if err != nil {
+		return false
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || s.now().Sub(e.Created) > s.ttl {
+		_ = os.Remove(p)
+
+		return false
+	}
+
+	if err := json.Unmarshal(e.Value, v); err != nil {
+		return false
+	}
+
+	// Record the use for LRU eviction.
+	now := s.now()
+	_ = os.Chtimes(p, now, now)
+
+	return true
+}
+
+// Put stores v under key and evicts the least recently used entries if the
+// cache grows beyond its size cap.
+func (s *Store) Put(key string, v any) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding cache entry: %w", err)
+	}
+
+	data, err := json.Marshal(entry{Created: s.now(), Value: value})
+	if err != nil {
+		return fmt.Errorf("encoding cache entry: %w", err)
+	}
+
+	if err := s.write(p, data); err != nil {
+		return err
+	}
+
+	return s.evict()
+}
+
+// write replaces the file at p through a temporary file and a rename, so
+// readers never see a partial entry.
+func (s *Store) write(p string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(s.dir, "tmp-*")
+	if err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), p); err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+
+	now := s.now()
+	if err = os.Chtimes(p, now, now); err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// evict removes the least recently used entries until the cache fits in
+// maxBytes.
+func (s *Store) evict() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("reading cache directory: %w", err)
+	}
+
+	infos := make([]fs.FileInfo, 0, len(dirEntries))
+
+	var total int64
+
+	for _, d := range dirEntries {
+		if d.IsDir() || !strings.HasSuffix(d.Name(), entryExt) {
+			continue
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			continue // Removed concurrently.
+		}
+
+		infos = append(infos, info)
+		total += info.Size()
+	}
+
+	slices.SortFunc(infos, func(a, b fs.FileInfo) int {
+		return a.ModTime().Compare(b.ModTime())
+	})
+
+	for _, info := range infos {
+		if total <= s.maxBytes {
+			break
+		}
+
+		err := os.Remove(filepath.Join(s.dir, info.Name()))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("evicting cache entry: %w", err)
+		}
+
+		total -= info.Size()
+	}
+
+	return nil
+}
+
+// path maps key to its entry file, rejecting anything that is not a hex
+// digest so keys cannot escape the cache directory.
+func (s *Store) path(key string) (string, error) {
+	if _, err := hex.DecodeString(key); err != nil || len(key) != sha256.Size*2 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	return filepath.Join(s.dir, key+entryExt), nil
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T, ttl time.Duration, maxBytes int64) (*Store, *time.Time) {
+	t.Helper()
+
+	s, err := Open(t.TempDir(), ttl, maxBytes)
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	return s, &now
+}
+
+func mustKey(t *testing.T, parts ...any) string {
+	t.Helper()
+
+	key, err := Key(parts...)
+	require.NoError(t, err)
+
+	return key
+}
+
+func TestKey_DependsOnEveryPart(t *testing.T) {
+	assert.Equal(t, mustKey(t, "gemini", "model", 0), mustKey(t, "gemini", "model", 0))
+	assert.NotEqual(t, mustKey(t, "gemini", "model", 0), mustKey(t, "gemini", "model", 1))
+	assert.NotEqual(t, mustKey(t, "gemini", "a"), mustKey(t, "gemini", "b"))
+}
+
+func TestStore_PutGet(t *testing.T) {
+	s, _ := newTestStore(t, 0, 0)
+	key := mustKey(t, "hello")
+
+	var got string
+	assert.False(t, s.Get(key, &got), "Empty cache misses")
+
+	require.NoError(t, s.Put(key, "world"))
+	require.True(t, s.Get(key, &got))
+	assert.Equal(t, "world", got)
+}
+
+func TestStore_ExpiresAfterTTL(t *testing.T) {
+	s, now := newTestStore(t, time.Hour, 0)
+	key := mustKey(t, "hello")
+	require.NoError(t, s.Put(key, "world"))
+
+	*now = now.Add(2 * time.Hour)
+
+	var got string
+	assert.False(t, s.Get(key, &got))
+	assert.NoFileExists(t, filepath.Join(s.dir, key+entryExt), "Expired entries are removed")
+}
+
+func TestStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	s, now := newTestStore(t, 0, 0)
+	keys := []string{mustKey(t, 1), mustKey(t, 2), mustKey(t, 3)}
+
+	for _, key := range keys {
+		require.NoError(t, s.Put(key, "value"))
+
+		*now = now.Add(time.Minute)
+	}
+
+	// Touch the oldest entry so the second one becomes least recently used.
+	var got string
+	require.True(t, s.Get(keys[0], &got))
+
+	info, err := os.Stat(filepath.Join(s.dir, keys[0]+entryExt))
+	require.NoError(t, err)
+
+	s.maxBytes = 2 * info.Size()
+	require.NoError(t, s.evict())
+
+	assert.True(t, s.Get(keys[0], &got))
+	assert.False(t, s.Get(keys[1], &got))
+	assert.True(t, s.Get(keys[2], &got))
+}
+
+func TestStore_RejectsInvalidKey(t *testing.T) {
+	s, _ := newTestStore(t, 0, 0)
+
+	require.ErrorIs(t, s.Put("../escape", "value"), ErrInvalidKey)
+}
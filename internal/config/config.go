@@ -4,17 +4,31 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 )
 
 //nolint:gosec // This is a false positive. We are defining the name of an env var, not a credential.
 const apiKeyEnvVar = "GEMINI_API_KEY"
 
+// fallbackModelsEnvVar names the comma-separated list of fallback models.
+const fallbackModelsEnvVar = "GEMINI_FALLBACK_MODELS"
+
+// historyFileEnvVar overrides where the history of crafts and executions is kept.
+const historyFileEnvVar = "PROMPT_MAKER_HISTORY_FILE"
+
+// libraryDirEnvVar overrides where the saved prompt library is kept, for
+// example in a directory tracked by a team's git repository.
+const libraryDirEnvVar = "PROMPT_MAKER_LIBRARY_DIR"
+
 // ErrAPIKeyNotFound is returned when the API key environment variable is not set.
 var ErrAPIKeyNotFound = errors.New("API key not found in environment variable")
 
 // Config holds the application configuration loaded from the environment.
 type Config struct {
 	APIKey string
+	// FallbackModels is tried, in order, when the chosen model is out of
+	// quota, overloaded or not found.
+	FallbackModels []string
 }
 
 // Load reads configuration from environment variables and returns a Config.
@@ -24,5 +38,35 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("%w (checked environment variable: %s)", ErrAPIKeyNotFound, apiKeyEnvVar)
 	}
 
-	return &Config{APIKey: apiKey}, nil
+	return &Config{
+		APIKey:         apiKey,
+		FallbackModels: ParseList(os.Getenv(fallbackModelsEnvVar)),
+	}, nil
+}
+
+// HistoryFile returns the history file location set in the environment, or
+// an empty string to use the default. Unlike Load, it needs no API key, so
+// history can be browsed offline.
+func HistoryFile() string {
+	return os.Getenv(historyFileEnvVar)
+}
+
+// LibraryDir returns the prompt library directory set in the environment,
+// or an empty string to use the default. Like HistoryFile, it needs no API key.
+func LibraryDir() string {
+	return os.Getenv(libraryDirEnvVar)
+}
+
+// ParseList splits a comma-separated list, trimming spaces and dropping
+// empty entries. It returns nil when the list is empty.
+func ParseList(s string) []string {
+	var items []string
+
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
 }
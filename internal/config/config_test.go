@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -71,3 +72,48 @@ func TestLoad(t *testing.T) {
 		})
 	}
 }
+
+func TestLoad_FallbackModels(t *testing.T) {
+	t.Setenv(apiKeyEnvVar, "test_api_key")
+	t.Setenv(fallbackModelsEnvVar, "gemini-2.5-flash, gemini-2.5-flash-lite,")
+
+	conf, err := Load()
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gemini-2.5-flash", "gemini-2.5-flash-lite"}, conf.FallbackModels)
+}
+
+func TestParseList(t *testing.T) {
+	assert.Nil(t, ParseList(""))
+	assert.Nil(t, ParseList(" , "))
+	assert.Equal(t, []string{"a", "b"}, ParseList("a, b"))
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	f, err := LoadFile(filepath.Join(dir, "missing.json"))
+	require.NoError(t, err)
+	assert.Equal(t, File{}, f)
+
+	path := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys": {"quit": ["ctrl+q"]}}`), 0o600))
+
+	f, err = LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"quit": {"ctrl+q"}}, f.Keys)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys": `), 0o600))
+
+	_, err = LoadFile(path)
+	require.Error(t, err)
+}
+
+func TestFilePath_FromEnvironment(t *testing.T) {
+	t.Setenv(configFileEnvVar, "/tmp/prompt-maker.json")
+
+	path, err := FilePath()
+
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/prompt-maker.json", path)
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// configFileEnvVar overrides where the config file is read from.
+const configFileEnvVar = "PROMPT_MAKER_CONFIG"
+
+// File holds the settings of the optional JSON config file.
+type File struct {
+	// Keys rebinds TUI actions by name, for example
+	// {"quit": ["ctrl+q"], "copy": ["y"]}.
+	Keys map[string][]string `json:"keys,omitempty"`
+}
+
+// FilePath returns the config file location set in the environment, or
+// config.json in the per-user config directory.
+func FilePath() (string, error) {
+	if path := os.Getenv(configFileEnvVar); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating user config directory: %w", err)
+	}
+
+	return filepath.Join(dir, "prompt-maker", "config.json"), nil
+}
+
+// LoadFile reads the config file at path. A missing file is not an error
+// and yields the zero File.
+func LoadFile(path string) (File, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // The path is the user's own config file.
+	if errors.Is(err, fs.ErrNotExist) {
+		return File{}, nil
+	}
+
+	if err != nil {
+		return File{}, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return File{}, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,104 @@
+// Package dataset turns history entries into JSON-lines datasets for tuning
+// or evaluating a prompt optimizer: the rough prompt is the input and the
+// crafted prompt the user went with is the target.
+package dataset
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"prompt-maker/internal/history"
+)
+
+// Format is the layout of each dataset line.
+type Format string
+
+// Formats of dataset lines.
+const (
+	// Gemini is the Gemini tuning format: {"contents": [user turn, model turn]}.
+	Gemini Format = "gemini"
+	// Generic is {"input": ..., "output": ...}.
+	Generic Format = "generic"
+)
+
+// ErrUnknownFormat is returned for format names other than gemini and generic.
+var ErrUnknownFormat = errors.New("unknown dataset format: use gemini or generic")
+
+// ParseFormat accepts "gemini" and "generic".
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case Gemini, Generic:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+	}
+}
+
+// Example is one input and its target.
+type Example struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+// geminiExample is an example in the Gemini tuning format.
+type geminiExample struct {
+	Contents []geminiContent `json:"contents"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+// ExampleFrom returns the example an entry teaches, reporting false for
+// entries without both a rough prompt and a crafted prompt. The target is
+// the prompt that was executed, which is the crafted prompt as edited, or
+// else the crafted prompt.
+func ExampleFrom(e history.Entry) (Example, bool) {
+	input := strings.TrimSpace(e.Input)
+	output := strings.TrimSpace(cmp.Or(e.ExecutedPrompt, e.CraftedPrompt))
+
+	if input == "" || e.CraftedPrompt == "" || output == "" {
+		return Example{}, false
+	}
+
+	return Example{Input: input, Output: output}, true
+}
+
+// Write writes one line per usable entry in format and returns the number of
+// lines written.
+func Write(w io.Writer, entries []history.Entry, format Format) (int, error) {
+	enc := json.NewEncoder(w)
+	n := 0
+
+	for _, e := range entries {
+		ex, ok := ExampleFrom(e)
+		if !ok {
+			continue
+		}
+
+		var line any = ex
+		if format == Gemini {
+			line = geminiExample{Contents: []geminiContent{
+				{Role: "user", Parts: []geminiPart{{Text: ex.Input}}},
+				{Role: "model", Parts: []geminiPart{{Text: ex.Output}}},
+			}}
+		}
+
+		if err := enc.Encode(line); err != nil {
+			return n, fmt.Errorf("writing dataset: %w", err)
+		}
+
+		n++
+	}
+
+	return n, nil
+}
@@ -0,0 +1,55 @@
+package dataset
+
+import (
+	"bytes"
+	"testing"
+
+	"prompt-maker/internal/history"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleEntries() []history.Entry {
+	return []history.Entry{
+		{ID: "a", Input: "email for a raise", CraftedPrompt: "You are an HR expert."},
+		{ID: "b", Input: "release notes", CraftedPrompt: "You are a writer.", ExecutedPrompt: "You are a technical writer."},
+		{ID: "c", ExecutedPrompt: "No rough prompt", Answer: "Skipped"},
+		{ID: "d", Input: "not crafted yet"},
+	}
+}
+
+func TestWrite_Generic(t *testing.T) {
+	var buf bytes.Buffer
+
+	n, err := Write(&buf, sampleEntries(), Generic)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t,
+		`{"input":"email for a raise","output":"You are an HR expert."}`+"\n"+
+			`{"input":"release notes","output":"You are a technical writer."}`+"\n",
+		buf.String(), "The edited prompt is the target")
+}
+
+func TestWrite_Gemini(t *testing.T) {
+	var buf bytes.Buffer
+
+	n, err := Write(&buf, sampleEntries()[:1], Gemini)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.JSONEq(t, `{"contents": [
+		{"role": "user", "parts": [{"text": "email for a raise"}]},
+		{"role": "model", "parts": [{"text": "You are an HR expert."}]}
+	]}`, buf.String())
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("Gemini")
+	require.NoError(t, err)
+	assert.Equal(t, Gemini, f)
+
+	_, err = ParseFormat("csv")
+	require.ErrorIs(t, err, ErrUnknownFormat)
+}
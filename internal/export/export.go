@@ -0,0 +1,260 @@
+// Package export writes history entries as transcripts to attach to design
+// docs and tickets: Markdown, a standalone themed HTML page, or JSON.
+package export
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/markdown"
+)
+
+// Format is the file format of a transcript.
+type Format string
+
+// Formats of transcripts.
+const (
+	Markdown Format = "markdown"
+	HTML     Format = "html"
+	JSON     Format = "json"
+)
+
+const (
+	// DefaultTheme is the theme of HTML transcripts when none is chosen.
+	DefaultTheme = "prompt-maker"
+	// sessionTitle names transcripts of several entries.
+	sessionTitle = "Prompt Maker session"
+	// timeLayout formats times in transcripts.
+	timeLayout = "2006-01-02 15:04"
+)
+
+var (
+	// ErrUnknownFormat is returned for format names other than those of Markdown, HTML and JSON.
+	ErrUnknownFormat = errors.New("unknown export format: use md, html or json")
+	// ErrUnknownTheme is returned for themes that have no palette.
+	ErrUnknownTheme = errors.New("unknown theme")
+	// ErrNoEntries is returned when there is nothing to export.
+	ErrNoEntries = errors.New("nothing to export")
+)
+
+//go:embed themes.css
+var themesCSS string
+
+//go:embed transcript.css
+var transcriptCSS string
+
+//go:embed page.html
+var pageHTML string
+
+// pageTemplate is the standalone HTML page around a rendered transcript.
+var pageTemplate = template.Must(template.New("page").Parse(pageHTML))
+
+// ParseFormat accepts a format name or a file extension such as "md".
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, ".")) {
+	case "md", "markdown":
+		return Markdown, nil
+	case "html", "htm":
+		return HTML, nil
+	case "json":
+		return JSON, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+	}
+}
+
+// FormatForPath returns the format matching the extension of path.
+func FormatForPath(path string) (Format, error) {
+	return ParseFormat(filepath.Ext(path))
+}
+
+// Ext returns the file extension of f, without the dot.
+func (f Format) Ext() string {
+	if f == Markdown {
+		return "md"
+	}
+
+	return string(f)
+}
+
+// ContentType returns the MIME type of f.
+func (f Format) ContentType() string {
+	switch f {
+	case HTML:
+		return "text/html; charset=utf-8"
+	case JSON:
+		return "application/json"
+	default:
+		return "text/markdown; charset=utf-8"
+	}
+}
+
+// Options describe a transcript.
+type Options struct {
+	Format Format
+	// Title defaults to the title of a single entry, or a session title.
+	Title string
+	// Theme is the palette of HTML transcripts.
+	Theme string
+	// ExportedAt is stated in the transcript; it defaults to now.
+	ExportedAt time.Time
+}
+
+// Transcript is the JSON form of an export.
+type Transcript struct {
+	Title      string          `json:"title"`
+	ExportedAt time.Time       `json:"exportedAt"`
+	Entries    []history.Entry `json:"entries"`
+}
+
+// KnownTheme reports whether theme has a palette for HTML transcripts.
+func KnownTheme(theme string) bool {
+	return strings.Contains(themesCSS, `[data-theme="`+theme+`"]`)
+}
+
+// Write writes entries, oldest first, as a transcript in the format of opts.
+func Write(w io.Writer, entries []history.Entry, opts Options) error {
+	if len(entries) == 0 {
+		return ErrNoEntries
+	}
+
+	if opts.ExportedAt.IsZero() {
+		opts.ExportedAt = time.Now()
+	}
+
+	if opts.Title == "" {
+		opts.Title = sessionTitle
+		if len(entries) == 1 {
+			opts.Title = cmp.Or(entries[0].Title(), sessionTitle)
+		}
+	}
+
+	switch opts.Format {
+	case JSON:
+		return writeJSON(w, Transcript{Title: opts.Title, ExportedAt: opts.ExportedAt, Entries: entries})
+	case HTML:
+		return writeHTML(w, entries, opts)
+	case Markdown, "":
+		_, err := io.WriteString(w, transcriptMarkdown(entries, opts))
+
+		return wrapWriteError(err)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, opts.Format)
+	}
+}
+
+func writeJSON(w io.Writer, t Transcript) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return wrapWriteError(enc.Encode(t))
+}
+
+// writeHTML renders the Markdown transcript into a standalone page.
+func writeHTML(w io.Writer, entries []history.Entry, opts Options) error {
+	theme := cmp.Or(opts.Theme, DefaultTheme)
+	if !KnownTheme(theme) {
+		return fmt.Errorf("%w: %q", ErrUnknownTheme, theme)
+	}
+
+	//nolint:gosec // The CSS is embedded, and the body is the model output the web interface renders the same way.
+	err := pageTemplate.Execute(w, struct {
+		Title string
+		Theme string
+		CSS   template.CSS
+		Body  template.HTML
+	}{
+		Title: opts.Title,
+		Theme: theme,
+		CSS:   template.CSS(transcriptCSS + "\n" + themesCSS),
+		Body:  template.HTML(markdown.New().ToHTML(transcriptMarkdown(entries, opts))),
+	})
+
+	return wrapWriteError(err)
+}
+
+// transcriptMarkdown lays out entries as a Markdown document.
+func transcriptMarkdown(entries []history.Entry, opts Options) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n_Exported %s", opts.Title, opts.ExportedAt.Local().Format(timeLayout))
+
+	if len(entries) > 1 {
+		fmt.Fprintf(&b, " · %d interactions", len(entries))
+	}
+
+	b.WriteString("_\n")
+
+	for i, e := range entries {
+		if len(entries) > 1 {
+			fmt.Fprintf(&b, "\n## %d. %s\n", i+1, cmp.Or(e.Title(), "Untitled"))
+		}
+
+		writeEntry(&b, e)
+	}
+
+	return b.String()
+}
+
+func writeEntry(b *strings.Builder, e history.Entry) {
+	meta := []string{e.CreatedAt.Local().Format(timeLayout)}
+	if e.Persona != "" {
+		meta = append(meta, "persona "+e.Persona)
+	}
+
+	fmt.Fprintf(b, "\n_%s_\n", strings.Join(meta, " · "))
+
+	if len(e.Attachments) > 0 {
+		fmt.Fprintf(b, "\nAttached: %s\n", strings.Join(e.Attachments, ", "))
+	}
+
+	if e.Input != "" {
+		fmt.Fprintf(b, "\n### Rough prompt\n\n%s\n", quote(e.Input))
+	}
+
+	writeSection(b, "Crafted prompt", e.CraftedPrompt, e.Craft)
+
+	if e.ExecutedPrompt != e.CraftedPrompt {
+		writeSection(b, "Executed prompt", e.ExecutedPrompt, nil)
+	}
+
+	writeSection(b, "Answer", e.Answer, e.Execute)
+}
+
+// writeSection writes text under a heading, followed by the call that
+// produced it.
+func writeSection(b *strings.Builder, title, text string, call *history.Call) {
+	if text == "" {
+		return
+	}
+
+	fmt.Fprintf(b, "\n### %s\n\n%s\n", title, strings.TrimSpace(text))
+
+	if call != nil {
+		fmt.Fprintf(b, "\n_%s · %s · tokens in %d · out %d · thinking %d_\n",
+			call.Model, call.Latency.Round(time.Millisecond),
+			call.Usage.PromptTokens, call.Usage.OutputTokens, call.Usage.ThinkingTokens)
+	}
+}
+
+// quote turns text into a Markdown block quote.
+func quote(text string) string {
+	return "> " + strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n> ")
+}
+
+func wrapWriteError(err error) error {
+	if err != nil {
+		return fmt.Errorf("writing transcript: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/prompt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleEntries() []history.Entry {
+	return []history.Entry{
+		{
+			ID:            "a",
+			CreatedAt:     time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC),
+			Persona:       "lyra",
+			Input:         "email asking\nfor a raise",
+			CraftedPrompt: "You are an **HR expert**.",
+			Answer:        "Dear manager,",
+			Attachments:   []string{"cv.pdf"},
+			Craft:         &history.Call{Model: "gemini-2.5-flash", Latency: 1500 * time.Millisecond},
+			Execute:       &history.Call{Model: "gemini-2.5-pro", Usage: prompt.Usage{OutputTokens: 42}},
+		},
+		{
+			ID:             "b",
+			CreatedAt:      time.Date(2025, 5, 2, 9, 0, 0, 0, time.UTC),
+			ExecutedPrompt: "Summarize the release notes",
+			Answer:         "Three bug fixes.",
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Format
+	}{
+		{"md", Markdown},
+		{".MD", Markdown},
+		{"markdown", Markdown},
+		{"htm", HTML},
+		{"json", JSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseFormat(tt.in)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := FormatForPath("notes.txt")
+	require.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestWrite_Markdown(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := Write(&buf, sampleEntries(), Options{ExportedAt: time.Date(2025, 5, 3, 9, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+
+	got := buf.String()
+	assert.Contains(t, got, "# Prompt Maker session\n")
+	assert.Contains(t, got, "2 interactions")
+	assert.Contains(t, got, "## 1. email asking\n")
+	assert.Contains(t, got, "### Rough prompt\n\n> email asking\n> for a raise\n")
+	assert.Contains(t, got, "_gemini-2.5-flash · 1.5s · tokens in 0 · out 0 · thinking 0_")
+	assert.Contains(t, got, "Attached: cv.pdf")
+	assert.Contains(t, got, "## 2. Summarize the release notes\n")
+	assert.Contains(t, got, "### Executed prompt\n\nSummarize the release notes\n")
+}
+
+func TestWrite_HTML(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, Write(&buf, sampleEntries()[:1], Options{Format: HTML, Theme: "gruvbox"}))
+
+	got := buf.String()
+	assert.Contains(t, got, `<html lang="en" data-theme="gruvbox">`)
+	assert.Contains(t, got, "<title>email asking</title>", "A single entry names the transcript")
+	assert.Contains(t, got, "<strong>HR expert</strong>")
+	assert.Contains(t, got, `[data-theme="gruvbox"]`)
+
+	err := Write(&buf, sampleEntries(), Options{Format: HTML, Theme: "neon"})
+	require.ErrorIs(t, err, ErrUnknownTheme)
+}
+
+func TestWrite_JSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, Write(&buf, sampleEntries(), Options{Format: JSON, Title: "Raise"}))
+
+	var got Transcript
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "Raise", got.Title)
+	require.Len(t, got.Entries, 2)
+	assert.Equal(t, "Dear manager,", got.Entries[0].Answer)
+
+	require.ErrorIs(t, Write(&buf, nil, Options{}), ErrNoEntries)
+}
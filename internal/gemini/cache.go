@@ -0,0 +1,124 @@
+package gemini
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"prompt-maker/internal/cache"
+
+	"google.golang.org/genai"
+)
+
+// cacheProvider identifies this provider in cache keys.
+const cacheProvider = "gemini"
+
+// cachingChatCreator serves repeated deterministic requests from a cache.
+type cachingChatCreator struct {
+	inner ChatCreator
+	store *cache.Store
+}
+
+// NewCachingChatCreator wraps inner so that requests made at temperature 0
+// are answered from store when an identical request was made before. The
+// key covers the provider, model, generation config, history and message,
+// which includes the system prompt, so any change to them is a miss.
+func NewCachingChatCreator(inner ChatCreator, store *cache.Store) ChatCreator {
+	return &cachingChatCreator{inner: inner, store: store}
+}
+
+// Create returns a session that consults the cache before each message.
+// The underlying session is created lazily, only on a cache miss.
+func (c *cachingChatCreator) Create(
+	ctx context.Context,
+	model string,
+	genConfig *genai.GenerateContentConfig,
+	history []*genai.Content,
+) (ChatSession, error) {
+	if !isDeterministic(genConfig) {
+		return c.inner.Create(ctx, model, genConfig, history)
+	}
+
+	return &cachingChatSession{
+		creator:   c.inner,
+		store:     c.store,
+		model:     model,
+		genConfig: genConfig,
+		history:   slices.Clone(history),
+	}, nil
+}
+
+// cachingChatSession tracks its own history so that a conversation can
+// continue after a cache hit.
+type cachingChatSession struct {
+	creator   ChatCreator
+	store     *cache.Store
+	model     string
+	genConfig *genai.GenerateContentConfig
+	history   []*genai.Content
+	// chat is the live session, or nil until a message misses the cache
+	// or after a hit left it behind the tracked history.
+	chat ChatSession
+}
+
+// SendMessage answers from the cache when possible and otherwise sends the
+// message and caches a successful response. Cache failures are logged and
+// never fail the request.
+func (s *cachingChatSession) SendMessage(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+	key, err := cache.Key(cacheProvider, s.model, s.genConfig, s.history, parts)
+	if err != nil {
+		slog.WarnContext(ctx, "skipping response cache", "error", err)
+	}
+
+	var resp *genai.GenerateContentResponse
+	if key != "" && s.store.Get(key, &resp) && resp != nil {
+		slog.DebugContext(ctx, "response cache hit", "model", s.model)
+		// A cached answer costs nothing, so do not report the original usage.
+		resp.UsageMetadata = nil
+		s.chat = nil
+		s.record(parts, resp)
+
+		return resp, nil
+	}
+
+	if s.chat == nil {
+		if s.chat, err = s.creator.Create(ctx, s.model, s.genConfig, s.history); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err = s.chat.SendMessage(ctx, parts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.record(parts, resp)
+
+	if key != "" && len(resp.Candidates) > 0 {
+		if err := s.store.Put(key, resp); err != nil {
+			slog.WarnContext(ctx, "failed to cache response", "error", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// record appends a turn to the tracked history.
+func (s *cachingChatSession) record(parts []genai.Part, resp *genai.GenerateContentResponse) {
+	user := &genai.Content{Role: genai.RoleUser}
+	for i := range parts {
+		user.Parts = append(user.Parts, &parts[i])
+	}
+
+	s.history = append(s.history, user)
+
+	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
+		s.history = append(s.history, resp.Candidates[0].Content)
+	}
+}
+
+// isDeterministic reports whether genConfig asks for temperature 0, the
+// only setting at which replaying a stored answer matches a fresh one.
+func isDeterministic(genConfig *genai.GenerateContentConfig) bool {
+	return genConfig != nil && genConfig.Temperature != nil && *genConfig.Temperature == 0
+}
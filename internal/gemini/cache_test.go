@@ -0,0 +1,66 @@
+package gemini
+
+import (
+	"context"
+	"testing"
+
+	"prompt-maker/internal/cache"
+	"prompt-maker/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+// countingChatCreator counts the messages that reach the model.
+type countingChatCreator struct {
+	sends int
+}
+
+func (c *countingChatCreator) Create(context.Context, string, *genai.GenerateContentConfig, []*genai.Content) (ChatSession, error) {
+	return &testutil.MockChatSession{
+		SendMessageFunc: func(context.Context, ...genai.Part) (*genai.GenerateContentResponse, error) {
+			c.sends++
+
+			return &genai.GenerateContentResponse{
+				Candidates:    []*genai.Candidate{{Content: genai.NewContentFromText("answer", genai.RoleModel)}},
+				UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 10},
+			}, nil
+		},
+	}, nil
+}
+
+func send(t *testing.T, creator ChatCreator, temperature float32, text string) *genai.GenerateContentResponse {
+	t.Helper()
+
+	cs, err := creator.Create(t.Context(), "gemini-2.5-flash", GenerationOptions{Temperature: temperature}.ContentConfig(), nil)
+	require.NoError(t, err)
+
+	resp, err := cs.SendMessage(t.Context(), genai.Part{Text: text})
+	require.NoError(t, err)
+
+	return resp
+}
+
+func TestCachingChatCreator(t *testing.T) {
+	store, err := cache.Open(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+
+	inner := &countingChatCreator{}
+	creator := NewCachingChatCreator(inner, store)
+
+	first := send(t, creator, 0, "hello")
+	assert.NotNil(t, first.UsageMetadata)
+
+	cached := send(t, creator, 0, "hello")
+	assert.Equal(t, 1, inner.sends, "An identical request is served from the cache")
+	assert.Equal(t, "answer", cached.Text())
+	assert.Nil(t, cached.UsageMetadata, "Cached answers report no usage")
+
+	send(t, creator, 0, "goodbye")
+	assert.Equal(t, 2, inner.sends, "A different message misses")
+
+	send(t, creator, 1, "hello")
+	send(t, creator, 1, "hello")
+	assert.Equal(t, 4, inner.sends, "Non-zero temperatures are never cached")
+}
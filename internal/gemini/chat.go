@@ -10,3 +10,33 @@ import (
 type ChatSession interface {
 	SendMessage(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error)
 }
+
+// ChatCreator defines an interface for creating chat sessions.
+type ChatCreator interface {
+	Create(
+		ctx context.Context,
+		model string,
+		genConfig *genai.GenerateContentConfig,
+		history []*genai.Content,
+	) (ChatSession, error)
+}
+
+// genaiChatCreator holds the genai.Client to satisfy the ChatCreator interface.
+type genaiChatCreator struct {
+	client *genai.Client
+}
+
+// NewChatCreator returns a ChatCreator backed by the given genai.Client.
+func NewChatCreator(client *genai.Client) ChatCreator {
+	return &genaiChatCreator{client: client}
+}
+
+// Create satisfies the ChatCreator interface for the real implementation.
+func (c *genaiChatCreator) Create(
+	ctx context.Context,
+	model string,
+	genConfig *genai.GenerateContentConfig,
+	history []*genai.Content,
+) (ChatSession, error) {
+	return c.client.Chats.Create(ctx, model, genConfig, history)
+}
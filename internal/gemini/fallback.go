@@ -0,0 +1,49 @@
+package gemini
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// ModelChain returns the ordered list of models to try: the primary model
+// followed by the fallbacks, with blanks and duplicates removed.
+func ModelChain(primary string, fallbacks []string) []string {
+	chain := make([]string, 0, len(fallbacks)+1)
+
+	for _, name := range append([]string{primary}, fallbacks...) {
+		name = strings.TrimSpace(name)
+		if name == "" || slices.Contains(chain, name) {
+			continue
+		}
+
+		chain = append(chain, name)
+	}
+
+	return chain
+}
+
+// IsFallbackError reports whether err means the model could not serve the
+// request (quota exhausted, overloaded or unknown model), so that the next
+// model in the chain should be tried.
+func IsFallbackError(err error) bool {
+	apiErr, ok := errors.AsType[genai.APIError](err)
+	if !ok {
+		return false
+	}
+
+	switch apiErr.Code {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusNotFound:
+		return true
+	}
+
+	switch apiErr.Status {
+	case "RESOURCE_EXHAUSTED", "UNAVAILABLE", "NOT_FOUND":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,44 @@
+package gemini
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genai"
+)
+
+var errPlain = errors.New("boom")
+
+func TestModelChain(t *testing.T) {
+	chain := ModelChain("gemini-2.5-pro", []string{"gemini-2.5-flash", " ", "gemini-2.5-pro", "gemini-2.5-flash-lite"})
+	assert.Equal(t, []string{"gemini-2.5-pro", "gemini-2.5-flash", "gemini-2.5-flash-lite"}, chain)
+}
+
+func TestModelChain_NoFallbacks(t *testing.T) {
+	assert.Equal(t, []string{"gemini-2.5-flash"}, ModelChain("gemini-2.5-flash", nil))
+}
+
+func TestIsFallbackError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "quota", err: genai.APIError{Code: http.StatusTooManyRequests}, want: true},
+		{name: "overloaded", err: genai.APIError{Code: http.StatusServiceUnavailable}, want: true},
+		{name: "not found", err: genai.APIError{Code: http.StatusNotFound}, want: true},
+		{name: "status only", err: genai.APIError{Status: "RESOURCE_EXHAUSTED"}, want: true},
+		{name: "wrapped", err: fmt.Errorf("sending: %w", genai.APIError{Code: http.StatusServiceUnavailable}), want: true},
+		{name: "bad request", err: genai.APIError{Code: http.StatusBadRequest}, want: false},
+		{name: "plain error", err: errPlain, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsFallbackError(tt.err))
+		})
+	}
+}
@@ -31,3 +31,15 @@ func TestGetModelOptions(t *testing.T) {
 		assert.Equal(t, opt.ModelName, opt.FilterValue(), "FilterValue should return ModelName")
 	}
 }
+
+func TestGenerationOptions_ContentConfig(t *testing.T) {
+	plain := GenerationOptions{Temperature: 0.5}.ContentConfig()
+	assert.InDelta(t, 0.5, *plain.Temperature, 0.0001)
+	assert.Nil(t, plain.ThinkingConfig, "Thinking should be left to the model default")
+
+	budget := int32(1024)
+	thinking := GenerationOptions{ThinkingBudget: &budget, IncludeThoughts: true}.ContentConfig()
+	assert.NotNil(t, thinking.ThinkingConfig)
+	assert.True(t, thinking.ThinkingConfig.IncludeThoughts)
+	assert.Equal(t, int32(1024), *thinking.ThinkingConfig.ThinkingBudget)
+}
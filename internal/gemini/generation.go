@@ -0,0 +1,28 @@
+package gemini
+
+import "google.golang.org/genai"
+
+// GenerationOptions holds the tunable generation parameters shared by the
+// TUI and web front ends.
+type GenerationOptions struct {
+	Temperature float32
+	// ThinkingBudget caps the tokens a model may spend thinking. Nil leaves
+	// the model default, -1 lets the model decide and 0 disables thinking.
+	ThinkingBudget *int32
+	// IncludeThoughts asks the model to return thought summaries.
+	IncludeThoughts bool
+}
+
+// ContentConfig builds the genai request configuration for these options.
+func (o GenerationOptions) ContentConfig() *genai.GenerateContentConfig {
+	genConfig := &genai.GenerateContentConfig{Temperature: genai.Ptr(o.Temperature)}
+
+	if o.ThinkingBudget != nil || o.IncludeThoughts {
+		genConfig.ThinkingConfig = &genai.ThinkingConfig{
+			IncludeThoughts: o.IncludeThoughts,
+			ThinkingBudget:  o.ThinkingBudget,
+		}
+	}
+
+	return genConfig
+}
@@ -0,0 +1,166 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Rating is a thumbs up or down given to a response.
+type Rating int
+
+// Ratings of a response. The zero Rating means unrated.
+const (
+	RatingDown Rating = -1
+	RatingUp   Rating = 1
+)
+
+// Target names the response of an entry that feedback rates: the crafted
+// prompt or the answer.
+type Target string
+
+// Targets are the responses of an entry that feedback rates.
+const (
+	TargetPrompt Target = "prompt"
+	TargetAnswer Target = "answer"
+)
+
+var (
+	// ErrInvalidRating is returned for ratings other than up and down.
+	ErrInvalidRating = errors.New(`invalid rating: use "up" or "down"`)
+	// ErrInvalidTarget is returned for targets other than prompt and answer.
+	ErrInvalidTarget = errors.New(`invalid rating target: use "prompt" or "answer"`)
+	// ErrNoAnswer is returned when rating the answer of an entry that has none.
+	ErrNoAnswer = errors.New("history entry has no answer to rate")
+)
+
+// ParseRating accepts "up", "+", "down" and "-".
+func ParseRating(s string) (Rating, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "up", "+", "good":
+		return RatingUp, nil
+	case "down", "-", "bad":
+		return RatingDown, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRating, s)
+	}
+}
+
+// String returns "up", "down" or "unrated".
+func (r Rating) String() string {
+	switch r {
+	case RatingUp:
+		return "up"
+	case RatingDown:
+		return "down"
+	default:
+		return "unrated"
+	}
+}
+
+// Feedback is the rating of one response, with an optional note.
+type Feedback struct {
+	Rating Rating    `json:"rating"`
+	Note   string    `json:"note,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// ParseTarget accepts "prompt" and "answer".
+func ParseTarget(s string) (Target, error) {
+	switch t := Target(strings.ToLower(strings.TrimSpace(s))); t {
+	case TargetPrompt, TargetAnswer:
+		return t, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidTarget, s)
+	}
+}
+
+// Rate stores f as the feedback on target of the entry with id.
+func Rate(ctx context.Context, store Store, id string, target Target, f Feedback) (Entry, error) {
+	e, err := store.Get(ctx, id)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	switch target {
+	case TargetPrompt:
+		e.CraftFeedback = &f
+	case TargetAnswer:
+		if e.Answer == "" {
+			return Entry{}, ErrNoAnswer
+		}
+
+		e.AnswerFeedback = &f
+	default:
+		return Entry{}, fmt.Errorf("%w: %q", ErrInvalidTarget, target)
+	}
+
+	return e, store.Save(ctx, e)
+}
+
+// Feedback returns the feedback on target, or nil.
+func (e Entry) Feedback(target Target) *Feedback {
+	if target == TargetAnswer {
+		return e.AnswerFeedback
+	}
+
+	return e.CraftFeedback
+}
+
+// CraftRating returns the rating of the crafted prompt, or zero.
+func (e Entry) CraftRating() Rating {
+	if e.CraftFeedback == nil {
+		return 0
+	}
+
+	return e.CraftFeedback.Rating
+}
+
+// AnswerRating returns the rating of the answer, or zero.
+func (e Entry) AnswerRating() Rating {
+	if e.AnswerFeedback == nil {
+		return 0
+	}
+
+	return e.AnswerFeedback.Rating
+}
+
+// HasTag reports whether e is tagged with tag, ignoring case and a leading "#".
+func (e Entry) HasTag(tag string) bool {
+	return slices.Contains(e.Tags, normalizeTag(tag))
+}
+
+// Retag adds and removes tags, keeping them normalized.
+func (e *Entry) Retag(add, remove []string) {
+	removed := normalizeTags(remove)
+
+	e.Tags = normalizeTags(slices.DeleteFunc(append(slices.Clone(e.Tags), add...), func(t string) bool {
+		return slices.Contains(removed, normalizeTag(t))
+	}))
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+}
+
+// normalizeTags lowercases, sorts and deduplicates tags, dropping empty ones.
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+
+	for _, t := range tags {
+		if t = normalizeTag(t); t != "" {
+			normalized = append(normalized, t)
+		}
+	}
+
+	if len(normalized) == 0 {
+		return nil
+	}
+
+	slices.Sort(normalized)
+
+	return slices.Compact(normalized)
+}
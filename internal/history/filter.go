@@ -0,0 +1,113 @@
+package history
+
+import (
+	"strings"
+	"time"
+)
+
+// Filter selects entries. Zero fields match everything.
+type Filter struct {
+	// Since and Until bound the creation time, inclusive.
+	Since time.Time
+	Until time.Time
+	// Model matches the model of either call, case-insensitively.
+	Model   string
+	Persona string
+	// Query is searched for, case-insensitively, in the inputs and outputs.
+	Query string
+	// Tag matches entries carrying the tag.
+	Tag string
+	// CraftRating matches entries whose crafted prompt was rated so.
+	CraftRating Rating
+}
+
+// Matches reports whether e passes every criterion of f.
+func (f Filter) Matches(e Entry) bool {
+	switch {
+	case !f.Since.IsZero() && e.CreatedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && e.CreatedAt.After(f.Until):
+		return false
+	case f.Persona != "" && !strings.EqualFold(e.Persona, f.Persona):
+		return false
+	case f.Model != "" && !e.usedModel(f.Model):
+		return false
+	case f.Query != "" && !e.contains(f.Query):
+		return false
+	case f.Tag != "" && !e.HasTag(f.Tag):
+		return false
+	case f.CraftRating != 0 && e.CraftRating() != f.CraftRating:
+		return false
+	}
+
+	return true
+}
+
+// Apply returns the entries that match f, keeping their order.
+func (f Filter) Apply(entries []Entry) []Entry {
+	var matched []Entry
+
+	for _, e := range entries {
+		if f.Matches(e) {
+			matched = append(matched, e)
+		}
+	}
+
+	return matched
+}
+
+// Model returns the model that produced the latest output of e.
+func (e Entry) Model() string {
+	if e.Execute != nil {
+		return e.Execute.Model
+	}
+
+	if e.Craft != nil {
+		return e.Craft.Model
+	}
+
+	return ""
+}
+
+// Params returns the generation parameters the entry was recorded with,
+// preferring those of the craft.
+func (e Entry) Params() Params {
+	if e.Craft != nil {
+		return e.Craft.Params
+	}
+
+	if e.Execute != nil {
+		return e.Execute.Params
+	}
+
+	return Params{}
+}
+
+// Title returns the first line of the text that started the entry.
+func (e Entry) Title() string {
+	text := e.Input
+	if text == "" {
+		text = e.ExecutedPrompt
+	}
+
+	title, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+
+	return title
+}
+
+func (e Entry) usedModel(model string) bool {
+	return (e.Craft != nil && strings.EqualFold(e.Craft.Model, model)) ||
+		(e.Execute != nil && strings.EqualFold(e.Execute.Model, model))
+}
+
+func (e Entry) contains(query string) bool {
+	query = strings.ToLower(query)
+
+	for _, text := range []string{e.Input, e.CraftedPrompt, e.ExecutedPrompt, e.Answer} {
+		if strings.Contains(strings.ToLower(text), query) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,93 @@
+// Package history records every craft and execution so that past prompts
+// and answers can be recovered across sessions.
+package history
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"prompt-maker/internal/gemini"
+	"prompt-maker/internal/prompt"
+)
+
+// Sources identify the front end that recorded an entry.
+const (
+	SourceTUI = "tui"
+	SourceWeb = "web"
+	SourceCLI = "cli"
+)
+
+// ErrNotFound is returned when no entry has the requested ID.
+var ErrNotFound = errors.New("history entry not found")
+
+// Entry is one interaction: a rough input crafted into a prompt and, once
+// executed, the final answer.
+type Entry struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Source    string    `json:"source"`
+	Persona   string    `json:"persona,omitempty"`
+	// Input is the rough prompt typed by the user.
+	Input         string `json:"input,omitempty"`
+	CraftedPrompt string `json:"craftedPrompt,omitempty"`
+	// ExecutedPrompt is the prompt sent for the final answer, which may
+	// differ from CraftedPrompt when it was edited first.
+	ExecutedPrompt string   `json:"executedPrompt,omitempty"`
+	Answer         string   `json:"answer,omitempty"`
+	Attachments    []string `json:"attachments,omitempty"`
+	Craft          *Call    `json:"craft,omitempty"`
+	Execute        *Call    `json:"execute,omitempty"`
+	// Tags group entries, for example to select them for a dataset.
+	Tags []string `json:"tags,omitempty"`
+	// CraftFeedback and AnswerFeedback rate the crafted prompt and the answer.
+	CraftFeedback  *Feedback `json:"craftFeedback,omitempty"`
+	AnswerFeedback *Feedback `json:"answerFeedback,omitempty"`
+}
+
+// Call describes one request to the model.
+type Call struct {
+	At      time.Time     `json:"at"`
+	Model   string        `json:"model"`
+	Params  Params        `json:"params"`
+	Usage   prompt.Usage  `json:"usage"`
+	Latency time.Duration `json:"latency"`
+}
+
+// Params are the generation parameters of a call.
+type Params struct {
+	Temperature     float32 `json:"temperature"`
+	ThinkingBudget  *int32  `json:"thinkingBudget,omitempty"`
+	IncludeThoughts bool    `json:"includeThoughts,omitempty"`
+}
+
+// ParamsFrom converts generation options to Params.
+func ParamsFrom(opts gemini.GenerationOptions) Params {
+	return Params{
+		Temperature:     opts.Temperature,
+		ThinkingBudget:  opts.ThinkingBudget,
+		IncludeThoughts: opts.IncludeThoughts,
+	}
+}
+
+// Options converts p back to generation options, to replay a call.
+func (p Params) Options() gemini.GenerationOptions {
+	return gemini.GenerationOptions{
+		Temperature:     p.Temperature,
+		ThinkingBudget:  p.ThinkingBudget,
+		IncludeThoughts: p.IncludeThoughts,
+	}
+}
+
+// Store persists history entries.
+type Store interface {
+	// Save inserts e, or replaces the entry with the same ID.
+	Save(ctx context.Context, e Entry) error
+	// Get returns the entry with id, or ErrNotFound.
+	Get(ctx context.Context, id string) (Entry, error)
+	// List returns every entry, newest first.
+	List(ctx context.Context) ([]Entry, error)
+	// Delete removes the entry with id, or returns ErrNotFound.
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,246 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"prompt-maker/internal/gemini"
+	"prompt-maker/internal/prompt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRecorder(t *testing.T) (*Recorder, *FileStore) {
+	t.Helper()
+
+	store := NewFileStore(filepath.Join(t.TempDir(), "data", fileName))
+	r := NewRecorder(store, SourceTUI, gemini.GenerationOptions{Temperature: 0.5})
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time {
+		now = now.Add(time.Minute)
+
+		return now
+	}
+
+	return r, store
+}
+
+func TestFileStore_ListEmpty(t *testing.T) {
+	entries, err := NewFileStore(filepath.Join(t.TempDir(), fileName)).List(t.Context())
+
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestFileStore_LastSaveWinsAndNewestFirst(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), fileName))
+	older := Entry{ID: "a", CreatedAt: time.Unix(100, 0), Input: "first"}
+	newer := Entry{ID: "b", CreatedAt: time.Unix(200, 0), Input: "second"}
+
+	require.NoError(t, store.Save(t.Context(), older))
+	require.NoError(t, store.Save(t.Context(), newer))
+
+	older.Answer = "updated"
+	require.NoError(t, store.Save(t.Context(), older))
+
+	entries, err := store.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "b", entries[0].ID)
+	assert.Equal(t, "updated", entries[1].Answer)
+
+	_, err = store.Get(t.Context(), "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFileStore_SkipsCorruptLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), fileName)
+	require.NoError(t, os.WriteFile(path, []byte("not json\n{\"id\":\"ok\"}\n"), 0o600))
+
+	entries, err := NewFileStore(path).List(t.Context())
+
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "ok", entries[0].ID)
+}
+
+func TestRecorder_CraftThenExecute(t *testing.T) {
+	r, store := newTestRecorder(t)
+	ctx := t.Context()
+
+	crafted, err := r.Craft(ctx, "rough idea", []string{"spec.md"}, prompt.Result{
+		Text:    "crafted prompt",
+		Model:   "gemini-2.5-flash",
+		Usage:   prompt.Usage{PromptTokens: 10, OutputTokens: 20},
+		Latency: 2 * time.Second,
+	})
+	require.NoError(t, err)
+
+	executed, err := r.Execute(ctx, crafted.ID, "crafted prompt", []string{"spec.md"}, prompt.Result{
+		Text:  "final answer",
+		Model: "gemini-2.5-pro",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, crafted.ID, executed.ID, "Executing a crafted prompt completes its entry")
+
+	got, err := store.Get(ctx, crafted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, SourceTUI, got.Source)
+	assert.Equal(t, prompt.LyraPersona, got.Persona)
+	assert.Equal(t, "rough idea", got.Input)
+	assert.Equal(t, "crafted prompt", got.CraftedPrompt)
+	assert.Equal(t, "final answer", got.Answer)
+	assert.Equal(t, []string{"spec.md"}, got.Attachments)
+	require.NotNil(t, got.Craft)
+	assert.Equal(t, "gemini-2.5-flash", got.Craft.Model)
+	assert.Equal(t, 2*time.Second, got.Craft.Latency)
+	assert.InDelta(t, 0.5, got.Craft.Params.Temperature, 0.001)
+	assert.Equal(t, int32(20), got.Craft.Usage.OutputTokens)
+	require.NotNil(t, got.Execute)
+	assert.Equal(t, "gemini-2.5-pro", got.Execute.Model)
+}
+
+func TestRecorder_ExecuteTwiceKeepsBothAnswers(t *testing.T) {
+	r, store := newTestRecorder(t)
+	ctx := t.Context()
+
+	crafted, err := r.Craft(ctx, "rough idea", nil, prompt.Result{Text: "crafted prompt"})
+	require.NoError(t, err)
+
+	_, err = r.Execute(ctx, crafted.ID, "crafted prompt", nil, prompt.Result{Text: "first answer"})
+	require.NoError(t, err)
+
+	second, err := r.Execute(ctx, crafted.ID, "crafted prompt", nil, prompt.Result{Text: "second answer"})
+	require.NoError(t, err)
+	assert.NotEqual(t, crafted.ID, second.ID)
+	assert.Equal(t, "rough idea", second.Input, "The copy keeps the crafting details")
+
+	entries, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "second answer", entries[0].Answer)
+	assert.Equal(t, "first answer", entries[1].Answer)
+}
+
+func TestRecorder_StandaloneExecute(t *testing.T) {
+	r, _ := newTestRecorder(t)
+
+	e, err := r.Execute(t.Context(), "unknown", "my prompt", nil, prompt.Result{Text: "answer"})
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, e.ID)
+	assert.Empty(t, e.Persona)
+	assert.Equal(t, "my prompt", e.ExecutedPrompt)
+	assert.Nil(t, e.Craft)
+}
+
+func TestFilter_Matches(t *testing.T) {
+	e := Entry{
+		CreatedAt:     time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC),
+		Persona:       "lyra",
+		Input:         "Draft a Release note",
+		Answer:        "Version 2 ships today.",
+		Craft:         &Call{Model: "gemini-2.5-pro"},
+		Execute:       &Call{Model: "gemini-2.5-flash"},
+		Tags:          []string{"best"},
+		CraftFeedback: &Feedback{Rating: RatingUp},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty", Filter{}, true},
+		{"craft model", Filter{Model: "gemini-2.5-pro"}, true},
+		{"execute model", Filter{Model: "GEMINI-2.5-FLASH"}, true},
+		{"other model", Filter{Model: "gemini-2.5-flash-lite"}, false},
+		{"persona", Filter{Persona: "Lyra"}, true},
+		{"query in input", Filter{Query: "release NOTE"}, true},
+		{"query in answer", Filter{Query: "ships"}, true},
+		{"query missing", Filter{Query: "invoice"}, false},
+		{"since", Filter{Since: e.CreatedAt.Add(time.Hour)}, false},
+		{"until", Filter{Until: e.CreatedAt.Add(-time.Hour)}, false},
+		{"tag", Filter{Tag: "#Best"}, true},
+		{"other tag", Filter{Tag: "draft"}, false},
+		{"craft rating", Filter{CraftRating: RatingUp}, true},
+		{"other craft rating", Filter{CraftRating: RatingDown}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Matches(e))
+		})
+	}
+}
+
+func TestEntry_Retag(t *testing.T) {
+	e := Entry{Tags: []string{"best"}}
+
+	e.Retag([]string{"#Lyra", "best", " "}, nil)
+	assert.Equal(t, []string{"best", "lyra"}, e.Tags)
+
+	e.Retag(nil, []string{"BEST", "lyra"})
+	assert.Nil(t, e.Tags)
+}
+
+func TestParseRating(t *testing.T) {
+	for in, want := range map[string]Rating{"up": RatingUp, "+": RatingUp, "Down": RatingDown, "-": RatingDown} {
+		got, err := ParseRating(in)
+
+		require.NoError(t, err)
+		assert.Equal(t, want, got, in)
+	}
+
+	_, err := ParseRating("meh")
+	require.ErrorIs(t, err, ErrInvalidRating)
+}
+
+func TestRate(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	require.NoError(t, store.Save(t.Context(), Entry{ID: "a", CraftedPrompt: "crafted"}))
+
+	_, err := Rate(t.Context(), store, "a", TargetAnswer, Feedback{Rating: RatingUp})
+	require.ErrorIs(t, err, ErrNoAnswer)
+
+	e, err := Rate(t.Context(), store, "a", TargetPrompt, Feedback{Rating: RatingDown, Note: "too long"})
+	require.NoError(t, err)
+	assert.Equal(t, RatingDown, e.CraftRating())
+
+	e, err = store.Get(t.Context(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, "too long", e.Feedback(TargetPrompt).Note)
+	assert.Nil(t, e.Feedback(TargetAnswer))
+
+	_, err = Rate(t.Context(), store, "a", Target("thoughts"), Feedback{})
+	require.ErrorIs(t, err, ErrInvalidTarget)
+}
+
+func TestComputeStats(t *testing.T) {
+	up := &Feedback{Rating: RatingUp}
+	down := &Feedback{Rating: RatingDown}
+
+	stats := ComputeStats([]Entry{
+		{Persona: "lyra", Craft: &Call{Model: "pro"}, CraftFeedback: up, Execute: &Call{Model: "flash"}, AnswerFeedback: down},
+		{Persona: "lyra", Craft: &Call{Model: "pro"}, CraftFeedback: up},
+		{Persona: "lyra", Craft: &Call{Model: "flash"}, CraftFeedback: down},
+		{Execute: &Call{Model: "flash"}},
+	})
+
+	assert.Equal(t, 4, stats.Entries)
+	assert.Equal(t, []Group{
+		{Name: "pro", Tally: Tally{Up: 2}},
+		{Name: "flash", Tally: Tally{Down: 1}},
+	}, stats.PromptsByModel)
+	assert.Equal(t, []Group{{Name: "lyra", Tally: Tally{Up: 2, Down: 1}}}, stats.PromptsByPersona)
+	assert.Equal(t, []Group{{Name: "flash", Tally: Tally{Down: 1, Unrated: 1}}}, stats.AnswersByModel)
+	assert.Equal(t, []Group{
+		{Name: "(none)", Tally: Tally{Unrated: 1}},
+		{Name: "lyra", Tally: Tally{Down: 1}},
+	}, stats.AnswersByPersona)
+	assert.InDelta(t, 2.0/3, stats.PromptsByPersona[0].UpShare(), 0.001)
+}
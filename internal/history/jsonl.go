@@ -0,0 +1,218 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+const (
+	// fileName is the name of the history file in the data directory.
+	fileName = "history.jsonl"
+	// dirPerm and filePerm keep prompts and answers private to the user.
+	dirPerm  = 0o700
+	filePerm = 0o600
+	// maxLineSize bounds a single entry, which may hold long answers.
+	maxLineSize = 16 << 20
+)
+
+// FileStore is a Store backed by a JSON-lines file. Saves append a line, and
+// when several lines share an ID the last one wins, so updates never rewrite
+// the file. It is safe for concurrent use within one process.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the history file in the per-user config directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating user config directory: %w", err)
+	}
+
+	return filepath.Join(dir, "prompt-maker", fileName), nil
+}
+
+// NewFileStore returns a FileStore writing to path. The file and its
+// directory are created on the first save.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Save appends e to the file.
+func (s *FileStore) Save(_ context.Context, e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding history entry: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), dirPerm); err != nil {
+		return fmt.Errorf("creating history directory: %w", err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, filePerm)
+	if err != nil {
+		return fmt.Errorf("opening history file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing history entry: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the latest version of the entry with id.
+func (s *FileStore) Get(ctx context.Context, id string) (Entry, error) {
+	entries, err := s.List(ctx)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	i := slices.IndexFunc(entries, func(e Entry) bool { return e.ID == id })
+	if i < 0 {
+		return Entry{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	return entries[i], nil
+}
+
+// List reads the file and returns the latest version of every entry, newest
+// first. Lines that cannot be decoded are skipped with a warning.
+func (s *FileStore) List(ctx context.Context) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return entries, nil
+}
+
+// Delete removes the entry with id by rewriting the file without it, which
+// also drops superseded versions of other entries.
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(entries, func(e Entry) bool { return e.ID == id })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	return s.rewrite(slices.Delete(entries, i, i+1))
+}
+
+// load returns the latest version of every entry in file order. The caller
+// must hold s.mu.
+func (s *FileStore) load(ctx context.Context) ([]Entry, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("opening history file: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+
+	index := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxLineSize)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil || e.ID == "" {
+			slog.WarnContext(ctx, "skipping unreadable history line", "path", s.path, "error", err)
+
+			continue
+		}
+
+		if i, ok := index[e.ID]; ok {
+			entries[i] = e
+
+			continue
+		}
+
+		index[e.ID] = len(entries)
+		entries = append(entries, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading history file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// rewrite replaces the file with entries through a temporary file and a
+// rename. The caller must hold s.mu.
+func (s *FileStore) rewrite(entries []Entry) (err error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("encoding history entry: %w", err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("rewriting history file: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("rewriting history file: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("rewriting history file: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("rewriting history file: %w", err)
+	}
+
+	return nil
+}
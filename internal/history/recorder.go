@@ -0,0 +1,135 @@
+package history
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"time"
+
+	"prompt-maker/internal/gemini"
+	"prompt-maker/internal/prompt"
+)
+
+// idBytes is the number of random bytes in an entry ID.
+const idBytes = 6
+
+// Recorder turns prompt results into history entries for one front end.
+type Recorder struct {
+	store  Store
+	source string
+	params Params
+	now    func() time.Time
+}
+
+// NewRecorder returns a Recorder that saves to store, tagging entries with
+// source and the generation options the front end sends.
+func NewRecorder(store Store, source string, opts gemini.GenerationOptions) *Recorder {
+	return &Recorder{
+		store:  store,
+		source: source,
+		params: ParamsFrom(opts),
+		now:    time.Now,
+	}
+}
+
+// WithTemperature returns a copy of r that records calls as sampled at
+// temperature.
+func (r *Recorder) WithTemperature(temperature float32) *Recorder {
+	c := *r
+	c.params.Temperature = temperature
+
+	return &c
+}
+
+// Store returns the store the recorder saves to.
+func (r *Recorder) Store() Store {
+	return r.store
+}
+
+// Craft records a new entry for input crafted into result.
+func (r *Recorder) Craft(ctx context.Context, input string, attachments []string, result prompt.Result) (Entry, error) {
+	now := r.now()
+	e := Entry{
+		ID:            NewID(),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		Source:        r.source,
+		Persona:       prompt.LyraPersona,
+		Input:         input,
+		CraftedPrompt: result.Text,
+		Attachments:   attachments,
+		Craft:         r.call(now, result),
+	}
+
+	return e, r.store.Save(ctx, e)
+}
+
+// Execute records the answer to executed. When entryID names a crafted entry
+// that has no answer yet, that entry is completed; when it already has one,
+// a copy carrying the new answer is saved so no answer is overwritten.
+// Without a known entry, a standalone execution is recorded.
+func (r *Recorder) Execute(
+	ctx context.Context, entryID, executed string, attachments []string, result prompt.Result,
+) (Entry, error) {
+	now := r.now()
+
+	e, err := r.parent(ctx, entryID)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if e.ID == "" || e.Execute != nil {
+		e.ID = NewID()
+		e.CreatedAt = now
+		e.Source = r.source
+	}
+
+	e.UpdatedAt = now
+	e.ExecutedPrompt = executed
+	e.Answer = result.Text
+	e.AnswerFeedback = nil
+
+	for _, name := range attachments {
+		if !slices.Contains(e.Attachments, name) {
+			e.Attachments = append(e.Attachments, name)
+		}
+	}
+
+	e.Execute = r.call(now, result)
+
+	return e, r.store.Save(ctx, e)
+}
+
+// parent loads the entry an execution continues, if any.
+func (r *Recorder) parent(ctx context.Context, entryID string) (Entry, error) {
+	if entryID == "" {
+		return Entry{}, nil
+	}
+
+	e, err := r.store.Get(ctx, entryID)
+	if errors.Is(err, ErrNotFound) {
+		return Entry{}, nil
+	}
+
+	return e, err
+}
+
+func (r *Recorder) call(now time.Time, result prompt.Result) *Call {
+	return &Call{
+		At:      now.Add(-result.Latency),
+		Model:   result.Model,
+		Params:  r.params,
+		Usage:   result.Usage,
+		Latency: result.Latency,
+	}
+}
+
+// NewID returns a random entry ID.
+func NewID() string {
+	b := make([]byte, idBytes)
+	_, _ = rand.Read(b) // crypto/rand.Read never returns an error.
+
+	return hex.EncodeToString(b)
+}
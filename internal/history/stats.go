@@ -0,0 +1,116 @@
+package history
+
+import (
+	"cmp"
+	"slices"
+)
+
+// noPersona groups the entries executed without crafting.
+const noPersona = "(none)"
+
+// Tally counts the ratings of a group of responses.
+type Tally struct {
+	Up      int `json:"up"`
+	Down    int `json:"down"`
+	Unrated int `json:"unrated"`
+}
+
+// Rated returns the number of rated responses.
+func (t Tally) Rated() int {
+	return t.Up + t.Down
+}
+
+// UpShare returns the fraction of rated responses rated up, or zero.
+func (t Tally) UpShare() float64 {
+	if t.Rated() == 0 {
+		return 0
+	}
+
+	return float64(t.Up) / float64(t.Rated())
+}
+
+func (t *Tally) add(r Rating) {
+	switch r {
+	case RatingUp:
+		t.Up++
+	case RatingDown:
+		t.Down++
+	default:
+		t.Unrated++
+	}
+}
+
+// Group is the tally of one model or persona.
+type Group struct {
+	Name string `json:"name"`
+	Tally
+}
+
+// Stats are the rating distributions of crafted prompts and answers, by the
+// model that produced them and by persona.
+type Stats struct {
+	Entries          int     `json:"entries"`
+	PromptsByModel   []Group `json:"promptsByModel"`
+	PromptsByPersona []Group `json:"promptsByPersona"`
+	AnswersByModel   []Group `json:"answersByModel"`
+	AnswersByPersona []Group `json:"answersByPersona"`
+}
+
+// ComputeStats tallies the ratings of entries. Groups are sorted by the
+// number of responses, largest first.
+func ComputeStats(entries []Entry) Stats {
+	var (
+		promptsByModel   = map[string]*Tally{}
+		promptsByPersona = map[string]*Tally{}
+		answersByModel   = map[string]*Tally{}
+		answersByPersona = map[string]*Tally{}
+	)
+
+	for _, e := range entries {
+		persona := cmp.Or(e.Persona, noPersona)
+
+		if e.Craft != nil {
+			tallyOf(promptsByModel, e.Craft.Model).add(e.CraftRating())
+			tallyOf(promptsByPersona, persona).add(e.CraftRating())
+		}
+
+		if e.Execute != nil {
+			tallyOf(answersByModel, e.Execute.Model).add(e.AnswerRating())
+			tallyOf(answersByPersona, persona).add(e.AnswerRating())
+		}
+	}
+
+	return Stats{
+		Entries:          len(entries),
+		PromptsByModel:   sortedGroups(promptsByModel),
+		PromptsByPersona: sortedGroups(promptsByPersona),
+		AnswersByModel:   sortedGroups(answersByModel),
+		AnswersByPersona: sortedGroups(answersByPersona),
+	}
+}
+
+func tallyOf(tallies map[string]*Tally, name string) *Tally {
+	t, ok := tallies[name]
+	if !ok {
+		t = &Tally{}
+		tallies[name] = t
+	}
+
+	return t
+}
+
+func sortedGroups(tallies map[string]*Tally) []Group {
+	groups := make([]Group, 0, len(tallies))
+	for name, t := range tallies {
+		groups = append(groups, Group{Name: name, Tally: *t})
+	}
+
+	slices.SortFunc(groups, func(a, b Group) int {
+		return cmp.Or(
+			cmp.Compare(b.Rated()+b.Unrated, a.Rated()+a.Unrated),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+
+	return groups
+}
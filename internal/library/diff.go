@@ -0,0 +1,139 @@
+package library
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// diffOp is one line of an edit script.
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	// oldLine and newLine are the zero-based positions of the line in each
+	// text, counting the lines before it on the other side.
+	oldLine, newLine int
+}
+
+// Diff returns a unified diff of the revisions from and to, or an empty
+// string when their texts are equal.
+func Diff(name string, from, to Revision) string {
+	ops := editScript(splitLines(from.Text), splitLines(to.Text))
+
+	var b strings.Builder
+
+	for _, h := range hunks(ops) {
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s v%d\n+++ %s v%d\n", name, from.Version, name, to.Version)
+		}
+
+		writeHunk(&b, ops[h[0]:h[1]])
+	}
+
+	return b.String()
+}
+
+// splitLines splits text into lines, ignoring a final newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// editScript turns a into b through the longest common subsequence of lines.
+func editScript(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i], oldLine: i, newLine: j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{kind: '-', line: a[i], oldLine: i, newLine: j})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[j], oldLine: i, newLine: j})
+			j++
+		}
+	}
+
+	return ops
+}
+
+// hunks returns the [start, end) ranges of ops that hold changes and their
+// surrounding context, merging ranges that overlap.
+func hunks(ops []diffOp) [][2]int {
+	var ranges [][2]int
+
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+
+		start, end := max(0, i-diffContext), min(len(ops), i+diffContext+1)
+
+		if n := len(ranges); n > 0 && start <= ranges[n-1][1] {
+			ranges[n-1][1] = end
+
+			continue
+		}
+
+		ranges = append(ranges, [2]int{start, end})
+	}
+
+	return ranges
+}
+
+// writeHunk writes ops with a header giving their line ranges.
+func writeHunk(b *strings.Builder, ops []diffOp) {
+	var oldCount, newCount int
+
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldCount++
+		}
+
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(ops[0].oldLine, oldCount), hunkRange(ops[0].newLine, newCount))
+
+	for _, op := range ops {
+		fmt.Fprintf(b, "%c%s\n", op.kind, op.line)
+	}
+}
+
+// hunkRange formats a one-based line range; an empty range names the line
+// before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
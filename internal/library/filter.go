@@ -0,0 +1,46 @@
+package library
+
+import "strings"
+
+// Filter selects prompts. Zero fields match everything.
+type Filter struct {
+	// Tag must be one of the prompt's tags.
+	Tag string
+	// Query is searched for, case-insensitively, in the name, description
+	// and newest text.
+	Query string
+}
+
+// Matches reports whether p passes every criterion of f.
+func (f Filter) Matches(p Prompt) bool {
+	if f.Tag != "" && !p.HasTag(f.Tag) {
+		return false
+	}
+
+	if f.Query == "" {
+		return true
+	}
+
+	query := strings.ToLower(f.Query)
+
+	for _, text := range []string{p.Name, p.Description, p.Text()} {
+		if strings.Contains(strings.ToLower(text), query) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Apply returns the prompts that match f, keeping their order.
+func (f Filter) Apply(prompts []Prompt) []Prompt {
+	var matched []Prompt
+
+	for _, p := range prompts {
+		if f.Matches(p) {
+			matched = append(matched, p)
+		}
+	}
+
+	return matched
+}
@@ -0,0 +1,406 @@
+// Package library keeps a curated collection of named prompts with tags and
+// every revision. It is stored as plain files so that a team can keep it in
+// git: each prompt is a directory holding its metadata and one Markdown file
+// per revision.
+//
+//	release-notes/
+//	  prompt.json   name, description, tags and the revision log
+//	  v1.md         the text of each revision
+//	  v2.md
+package library
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// metaFile holds the metadata of a prompt in its directory.
+	metaFile = "prompt.json"
+	// revisionExt is the extension of revision files.
+	revisionExt = ".md"
+	// maxNameLength bounds prompt names, which are also directory names.
+	maxNameLength = 64
+	// dirPerm and filePerm are used for the files of the library.
+	dirPerm  = 0o750
+	filePerm = 0o600
+)
+
+var (
+	// ErrNotFound is returned when no prompt has the requested name.
+	ErrNotFound = errors.New("library prompt not found")
+	// ErrRevisionNotFound is returned for a version a prompt does not have.
+	ErrRevisionNotFound = errors.New("library revision not found")
+	// ErrInvalidName is returned for names that cannot be used as a directory.
+	ErrInvalidName = errors.New("invalid prompt name: use letters, digits, '.', '_' and '-'")
+	// ErrEmptyText is returned when saving a prompt without text.
+	ErrEmptyText = errors.New("prompt text is empty")
+)
+
+// validName matches normalized prompt names.
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// Prompt is a named prompt and its revisions, oldest first.
+type Prompt struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	Revisions   []Revision `json:"revisions"`
+}
+
+// Revision is one saved version of a prompt's text.
+type Revision struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Note      string    `json:"note,omitempty"`
+	// EntryID is the history entry the revision was saved from, if any.
+	EntryID string `json:"entryId,omitempty"`
+	// Text is kept in its own file rather than in the metadata.
+	Text string `json:"-"`
+}
+
+// Latest returns the newest revision of p.
+func (p Prompt) Latest() Revision {
+	if len(p.Revisions) == 0 {
+		return Revision{}
+	}
+
+	return p.Revisions[len(p.Revisions)-1]
+}
+
+// Revision returns the revision of p with version.
+func (p Prompt) Revision(version int) (Revision, error) {
+	i := slices.IndexFunc(p.Revisions, func(r Revision) bool { return r.Version == version })
+	if i < 0 {
+		return Revision{}, fmt.Errorf("%w: %s v%d", ErrRevisionNotFound, p.Name, version)
+	}
+
+	return p.Revisions[i], nil
+}
+
+// Text returns the text of the newest revision.
+func (p Prompt) Text() string {
+	return p.Latest().Text
+}
+
+// HasTag reports whether p is tagged with tag, ignoring case.
+func (p Prompt) HasTag(tag string) bool {
+	return slices.Contains(p.Tags, normalizeTag(tag))
+}
+
+// SaveOptions describe a save beyond the prompt's text.
+type SaveOptions struct {
+	// Tags replace the prompt's tags when not nil.
+	Tags []string
+	// Description replaces the prompt's description when not empty.
+	Description string
+	// Note describes the revision.
+	Note    string
+	EntryID string
+}
+
+// Library is a directory of prompts. It is safe for concurrent use within
+// one process.
+type Library struct {
+	dir string
+	now func() time.Time
+	mu  sync.Mutex
+}
+
+// DefaultDir returns the library directory in the per-user config directory.
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating user config directory: %w", err)
+	}
+
+	return filepath.Join(dir, "prompt-maker", "library"), nil
+}
+
+// New returns the library in dir. The directory is created on the first save.
+func New(dir string) *Library {
+	return &Library{dir: dir, now: time.Now}
+}
+
+// Dir returns the directory of the library.
+func (l *Library) Dir() string {
+	return l.dir
+}
+
+// NormalizeName turns a human-typed name such as "Release Notes" into the
+// name the prompt is stored under, "release-notes".
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), "-")
+}
+
+// Save stores text as a new revision of the prompt called name, creating the
+// prompt if needed. Saving the same text as the newest revision only updates
+// the metadata.
+func (l *Library) Save(_ context.Context, name, text string, opts SaveOptions) (Prompt, error) {
+	if strings.TrimSpace(text) == "" {
+		return Prompt{}, ErrEmptyText
+	}
+
+	dir, err := l.promptDir(name)
+	if err != nil {
+		return Prompt{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	p, err := l.load(dir)
+	if errors.Is(err, ErrNotFound) {
+		p, err = Prompt{Name: filepath.Base(dir), CreatedAt: now}, nil
+	}
+
+	if err != nil {
+		return Prompt{}, err
+	}
+
+	if opts.Tags != nil {
+		p.Tags = normalizeTags(opts.Tags)
+	}
+
+	p.Description = cmp.Or(opts.Description, p.Description)
+	p.UpdatedAt = now
+
+	if len(p.Revisions) == 0 || p.Text() != text {
+		rev := Revision{Version: p.Latest().Version + 1, CreatedAt: now, Note: opts.Note, EntryID: opts.EntryID, Text: text}
+
+		if err := os.MkdirAll(dir, dirPerm); err != nil {
+			return Prompt{}, fmt.Errorf("creating library directory: %w", err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, revisionFile(rev.Version)), []byte(text), filePerm); err != nil {
+			return Prompt{}, fmt.Errorf("writing library revision: %w", err)
+		}
+
+		p.Revisions = append(p.Revisions, rev)
+	}
+
+	if err := writeMeta(dir, p); err != nil {
+		return Prompt{}, err
+	}
+
+	return p, nil
+}
+
+// Tag adds and removes tags of the prompt called name.
+func (l *Library) Tag(_ context.Context, name string, add, remove []string) (Prompt, error) {
+	dir, err := l.promptDir(name)
+	if err != nil {
+		return Prompt{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	p, err := l.load(dir)
+	if err != nil {
+		return Prompt{}, err
+	}
+
+	removed := normalizeTags(remove)
+	tags := slices.DeleteFunc(append(p.Tags, add...), func(t string) bool {
+		return slices.Contains(removed, normalizeTag(t))
+	})
+
+	p.Tags = normalizeTags(tags)
+	p.UpdatedAt = l.now()
+
+	if err := writeMeta(dir, p); err != nil {
+		return Prompt{}, err
+	}
+
+	return p, nil
+}
+
+// Get returns the prompt called name with the text of every revision.
+func (l *Library) Get(_ context.Context, name string) (Prompt, error) {
+	dir, err := l.promptDir(name)
+	if err != nil {
+		return Prompt{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.load(dir)
+}
+
+// List returns every prompt, sorted by name. Prompts that cannot be read are
+// skipped with a warning.
+func (l *Library) List(ctx context.Context) ([]Prompt, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dirs, err := os.ReadDir(l.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("reading library directory: %w", err)
+	}
+
+	var prompts []Prompt
+
+	for _, d := range dirs {
+		if !d.IsDir() || !validName.MatchString(d.Name()) {
+			continue
+		}
+
+		p, err := l.load(filepath.Join(l.dir, d.Name()))
+		if err != nil {
+			slog.WarnContext(ctx, "skipping unreadable library prompt", "name", d.Name(), "error", err)
+
+			continue
+		}
+
+		prompts = append(prompts, p)
+	}
+
+	return prompts, nil
+}
+
+// Delete removes the prompt called name and all its revisions.
+func (l *Library) Delete(_ context.Context, name string) error {
+	dir, err := l.promptDir(name)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := os.Stat(filepath.Join(dir, metaFile)); err != nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, filepath.Base(dir))
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("deleting library prompt: %w", err)
+	}
+
+	return nil
+}
+
+// promptDir returns the directory of the prompt called name.
+func (l *Library) promptDir(name string) (string, error) {
+	name = NormalizeName(name)
+	if len(name) > maxNameLength || !validName.MatchString(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	return filepath.Join(l.dir, name), nil
+}
+
+// load reads the prompt in dir with the text of every revision. The caller
+// must hold l.mu.
+func (l *Library) load(dir string) (Prompt, error) {
+	data, err := os.ReadFile(filepath.Join(dir, metaFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Prompt{}, fmt.Errorf("%w: %s", ErrNotFound, filepath.Base(dir))
+	}
+
+	if err != nil {
+		return Prompt{}, fmt.Errorf("reading library prompt: %w", err)
+	}
+
+	var p Prompt
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Prompt{}, fmt.Errorf("decoding %s: %w", filepath.Join(filepath.Base(dir), metaFile), err)
+	}
+
+	for i, rev := range p.Revisions {
+		text, err := os.ReadFile(filepath.Join(dir, revisionFile(rev.Version)))
+		if err != nil {
+			return Prompt{}, fmt.Errorf("reading library revision: %w", err)
+		}
+
+		p.Revisions[i].Text = string(text)
+	}
+
+	return p, nil
+}
+
+// writeMeta replaces the metadata of p in dir through a temporary file and
+// a rename, so a crash never leaves it half written.
+func writeMeta(dir string, p Prompt) (err error) {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding library prompt: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return fmt.Errorf("creating library directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, metaFile+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("writing library prompt: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("writing library prompt: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("writing library prompt: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), filepath.Join(dir, metaFile)); err != nil {
+		return fmt.Errorf("writing library prompt: %w", err)
+	}
+
+	return nil
+}
+
+// revisionFile is the name of the file holding the text of version.
+func revisionFile(version int) string {
+	return "v" + strconv.Itoa(version) + revisionExt
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+}
+
+// normalizeTags lowercases, sorts and deduplicates tags, dropping empty ones.
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+
+	for _, t := range tags {
+		if t = normalizeTag(t); t != "" {
+			normalized = append(normalized, t)
+		}
+	}
+
+	slices.Sort(normalized)
+
+	return slices.Compact(normalized)
+}
@@ -0,0 +1,160 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLibrary(t *testing.T) *Library {
+	t.Helper()
+
+	l := New(filepath.Join(t.TempDir(), "library"))
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time {
+		now = now.Add(time.Minute)
+
+		return now
+	}
+
+	return l
+}
+
+func TestLibrary_SaveKeepsRevisions(t *testing.T) {
+	l := newTestLibrary(t)
+	ctx := t.Context()
+
+	_, err := l.Save(ctx, "Release Notes", "Write release notes.", SaveOptions{Tags: []string{"Docs", "#team", "docs"}, Note: "first"})
+	require.NoError(t, err)
+
+	p, err := l.Save(ctx, "release-notes", "Write release notes for {{version}}.", SaveOptions{Description: "Changelog helper"})
+	require.NoError(t, err)
+	require.Len(t, p.Revisions, 2)
+
+	p, err = l.Save(ctx, "release-notes", "Write release notes for {{version}}.", SaveOptions{Tags: []string{"docs"}})
+	require.NoError(t, err)
+	assert.Len(t, p.Revisions, 2, "Saving the same text adds no revision")
+
+	got, err := l.Get(ctx, "release-notes")
+	require.NoError(t, err)
+	assert.Equal(t, "release-notes", got.Name)
+	assert.Equal(t, "Changelog helper", got.Description)
+	assert.Equal(t, []string{"docs"}, got.Tags)
+	assert.Equal(t, "Write release notes for {{version}}.", got.Text())
+	assert.Equal(t, "first", got.Revisions[0].Note)
+
+	first, err := got.Revision(1)
+	require.NoError(t, err)
+	assert.Equal(t, "Write release notes.", first.Text)
+
+	_, err = got.Revision(3)
+	require.ErrorIs(t, err, ErrRevisionNotFound)
+
+	text, err := os.ReadFile(filepath.Join(l.Dir(), "release-notes", "v2.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "Write release notes for {{version}}.", string(text), "Revisions are plain files")
+}
+
+func TestLibrary_Errors(t *testing.T) {
+	l := newTestLibrary(t)
+	ctx := t.Context()
+
+	_, err := l.Save(ctx, "../escape", "text", SaveOptions{})
+	require.ErrorIs(t, err, ErrInvalidName)
+
+	_, err = l.Save(ctx, "empty", " \n", SaveOptions{})
+	require.ErrorIs(t, err, ErrEmptyText)
+
+	_, err = l.Get(ctx, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.ErrorIs(t, l.Delete(ctx, "missing"), ErrNotFound)
+
+	prompts, err := l.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, prompts)
+}
+
+func TestLibrary_TagListAndDelete(t *testing.T) {
+	l := newTestLibrary(t)
+	ctx := t.Context()
+
+	_, err := l.Save(ctx, "b-review", "Review this code.", SaveOptions{Tags: []string{"code"}})
+	require.NoError(t, err)
+	_, err = l.Save(ctx, "a-email", "Write an email.", SaveOptions{})
+	require.NoError(t, err)
+
+	p, err := l.Tag(ctx, "b-review", []string{"Go", "team"}, []string{"code"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "team"}, p.Tags)
+
+	prompts, err := l.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, prompts, 2)
+	assert.Equal(t, "a-email", prompts[0].Name)
+
+	tagged := Filter{Tag: "GO"}.Apply(prompts)
+	require.Len(t, tagged, 1)
+	assert.Equal(t, "b-review", tagged[0].Name)
+	assert.Len(t, Filter{Query: "email"}.Apply(prompts), 1)
+
+	require.NoError(t, l.Delete(ctx, "a-email"))
+
+	_, err = l.Get(ctx, "a-email")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRender(t *testing.T) {
+	text := "Write {{ kind }} notes for {{version}}. Mention {{version}} twice."
+
+	assert.Equal(t, []string{"kind", "version"}, Variables(text))
+
+	got, err := Render(text, map[string]string{"kind": "release", "version": "v2"})
+	require.NoError(t, err)
+	assert.Equal(t, "Write release notes for v2. Mention v2 twice.", got)
+
+	_, err = Render(text, map[string]string{"kind": "release"})
+	require.ErrorIs(t, err, ErrMissingVariables)
+	require.ErrorContains(t, err, "version")
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{"equal", "a\nb\n", "a\nb", ""},
+		{
+			"changed line",
+			"one\ntwo\nthree\n",
+			"one\n2\nthree\n",
+			"--- p v1\n+++ p v2\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			"added to empty",
+			"",
+			"new",
+			"--- p v1\n+++ p v2\n@@ -0,0 +1,1 @@\n+new\n",
+		},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			"x\n2\n3\n4\n5\n6\n7\n8\n9\ny\n",
+			"--- p v1\n+++ p v2\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+y\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff("p", Revision{Version: 1, Text: tt.from}, Revision{Version: 2, Text: tt.to})
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ErrMissingVariables is returned when rendering a prompt without a value for
+// each of its variables.
+var ErrMissingVariables = errors.New("missing values for variables")
+
+// variablePattern matches variables written as {{name}}.
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// Variables returns the names of the {{variables}} in text, in order of first
+// appearance.
+func Variables(text string) []string {
+	var names []string
+
+	for _, m := range variablePattern.FindAllStringSubmatch(text, -1) {
+		if !slices.Contains(names, m[1]) {
+			names = append(names, m[1])
+		}
+	}
+
+	return names
+}
+
+// Render replaces the {{variables}} in text with values. Every variable
+// needs a value.
+func Render(text string, values map[string]string) (string, error) {
+	var missing []string
+
+	for _, name := range Variables(text) {
+		if _, ok := values[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrMissingVariables, strings.Join(missing, ", "))
+	}
+
+	return variablePattern.ReplaceAllStringFunc(text, func(m string) string {
+		return values[variablePattern.FindStringSubmatch(m)[1]]
+	}), nil
+}
@@ -0,0 +1,38 @@
+// Package markdown converts model output to HTML with the goldmark pipeline
+// shared by the web interface and exported transcripts.
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// Renderer converts Markdown to HTML.
+type Renderer struct {
+	md goldmark.Markdown
+}
+
+// New creates a Renderer. Raw HTML in the Markdown is kept, as models use it
+// for layout.
+func New() *Renderer {
+	return &Renderer{
+		md: goldmark.New(
+			goldmark.WithRendererOptions(
+				html.WithUnsafe(), // Allow raw HTML in markdown
+			),
+		),
+	}
+}
+
+// ToHTML converts str to HTML, returning str unchanged if it cannot be
+// converted.
+func (r *Renderer) ToHTML(str string) string {
+	var buf bytes.Buffer
+	if err := r.md.Convert([]byte(str), &buf); err != nil {
+		return str // Return raw text on error
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,126 @@
+package placeholder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+const (
+	// fileName is the name of the memory file in the config directory.
+	fileName = "placeholders.json"
+	// maxRemembered caps the values kept for each placeholder.
+	maxRemembered = 5
+	// dirPerm and filePerm keep the values private to the user.
+	dirPerm  = 0o700
+	filePerm = 0o600
+)
+
+// Values maps placeholder keys to the values used for them, most recent
+// first.
+type Values map[string][]string
+
+// Recent returns the values used before for token, most recent first.
+func (v Values) Recent(token string) []string {
+	return v[Key(token)]
+}
+
+// Last returns the value used last time for token, or an empty string.
+func (v Values) Last(token string) string {
+	if recent := v.Recent(token); len(recent) > 0 {
+		return recent[0]
+	}
+
+	return ""
+}
+
+// Memory remembers placeholder values in a JSON file. It is safe for
+// concurrent use within one process.
+type Memory struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the memory file in the per-user config directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating user config directory: %w", err)
+	}
+
+	return filepath.Join(dir, "prompt-maker", fileName), nil
+}
+
+// NewMemory returns a Memory kept at path. The file and its directory are
+// created on the first Remember.
+func NewMemory(path string) *Memory {
+	return &Memory{path: path}
+}
+
+// Load returns every remembered value. A missing file remembers nothing.
+func (m *Memory) Load() (Values, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.load()
+}
+
+// Remember records values, keyed by placeholder token, as the most recent
+// ones. Empty values are skipped.
+func (m *Memory) Remember(values map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	remembered, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	for token, value := range values {
+		if value == "" {
+			continue
+		}
+
+		key := Key(token)
+		recent := slices.DeleteFunc(slices.Clone(remembered[key]), func(v string) bool { return v == value })
+		remembered[key] = slices.Concat([]string{value}, recent[:min(len(recent), maxRemembered-1)])
+	}
+
+	data, err := json.MarshalIndent(remembered, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding placeholder values: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), dirPerm); err != nil {
+		return fmt.Errorf("creating placeholder directory: %w", err)
+	}
+
+	if err := os.WriteFile(m.path, data, filePerm); err != nil {
+		return fmt.Errorf("writing placeholder values: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Memory) load() (Values, error) {
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Values{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("reading placeholder values: %w", err)
+	}
+
+	values := Values{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("decoding placeholder values: %w", err)
+	}
+
+	return values, nil
+}
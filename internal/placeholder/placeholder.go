@@ -0,0 +1,176 @@
+// Package placeholder finds the blanks a crafted prompt leaves for the user,
+// such as [Your Company Name], {audience} or <insert data>, fills them in and
+// remembers the values used before. Library {{variables}} count as
+// placeholders too, so prompts loaded from the library are filled the same
+// way.
+package placeholder
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"prompt-maker/internal/library"
+)
+
+// maxNameLength bounds the text between brackets; longer spans are prose.
+const maxNameLength = 80
+
+// pattern matches [square], {{double curly}}, {curly} and <angle> brackets on
+// a single line. The double curly alternative comes first so that {{name}}
+// is one placeholder rather than {name} wrapped in braces; those written as
+// library variables are returned as {{name}} and filled by library.Render.
+var pattern = regexp.MustCompile(`\[([^\[\]\n]+)\]|\{\{([^{}\n]+)\}\}|\{([^{}\n]+)\}|<([^<>\n]+)>`)
+
+// codePattern matches fenced code blocks and inline code, where brackets are
+// code rather than blanks.
+var codePattern = regexp.MustCompile("(?s)```.*?(?:```|$)|`[^`\n]*`")
+
+// match is a placeholder and where it occurs in the text. Library variables
+// are filled by library.Render rather than by position.
+type match struct {
+	start, end int
+	token      string
+	variable   bool
+}
+
+// Find returns the distinct placeholders in text, brackets included, in order
+// of first appearance.
+func Find(text string) []string {
+	var tokens []string
+
+	for _, m := range find(text) {
+		if !slices.Contains(tokens, m.token) {
+			tokens = append(tokens, m.token)
+		}
+	}
+
+	return tokens
+}
+
+// Fill replaces each placeholder in text with its value in values, keyed by
+// the token Find returns. Placeholders without a value are left as they are.
+func Fill(text string, values map[string]string) string {
+	var b strings.Builder
+
+	last := 0
+
+	for _, m := range find(text) {
+		value, ok := values[m.token]
+		if !ok || m.variable {
+			continue
+		}
+
+		b.WriteString(text[last:m.start])
+		b.WriteString(value)
+		last = m.end
+	}
+
+	b.WriteString(text[last:])
+
+	return renderVariables(b.String(), values)
+}
+
+// renderVariables fills the library variables in text with library.Render.
+// Variables without a value render as themselves.
+func renderVariables(text string, values map[string]string) string {
+	names := library.Variables(text)
+	if len(names) == 0 {
+		return text
+	}
+
+	vars := make(map[string]string, len(names))
+
+	for _, name := range names {
+		value, ok := values[variableToken(name)]
+		if !ok {
+			value = variableToken(name)
+		}
+
+		vars[name] = value
+	}
+
+	rendered, err := library.Render(text, vars)
+	if err != nil {
+		// Unreachable: every variable has a value.
+		return text
+	}
+
+	return rendered
+}
+
+// variableToken is the token Find returns for the library variable name.
+func variableToken(name string) string {
+	return "{{" + name + "}}"
+}
+
+// Name returns the text between the brackets of token, trimmed.
+func Name(token string) string {
+	return strings.TrimSpace(strings.Trim(token, "[]{}<>"))
+}
+
+// Key identifies placeholders that ask for the same value, so that
+// [Company Name] and {company name} share what was used before.
+func Key(token string) string {
+	return strings.ToLower(strings.Join(strings.Fields(Name(token)), " "))
+}
+
+// find returns every placeholder in text outside code.
+func find(text string) []match {
+	masked := codePattern.ReplaceAllStringFunc(text, func(code string) string {
+		return strings.Repeat(" ", len(code))
+	})
+
+	var matches []match
+
+	for _, loc := range pattern.FindAllStringSubmatchIndex(masked, -1) {
+		if !isPlaceholder(masked, loc) {
+			continue
+		}
+
+		m := match{start: loc[0], end: loc[1], token: text[loc[0]:loc[1]]}
+		if names := library.Variables(m.token); len(names) == 1 {
+			m.token, m.variable = variableToken(names[0]), true
+		}
+
+		matches = append(matches, m)
+	}
+
+	return matches
+}
+
+// isPlaceholder rejects bracketed text that is markdown, markup or data
+// rather than a blank to fill in.
+func isPlaceholder(text string, loc []int) bool {
+	// Exactly one of the four groups matched.
+	group := slices.IndexFunc([]int{loc[2], loc[4], loc[6], loc[8]}, func(start int) bool { return start >= 0 })
+	name := strings.TrimSpace(text[loc[2+group*2]:loc[3+group*2]])
+
+	if !isName(name) {
+		return false
+	}
+
+	switch group {
+	case 0:
+		// A task list checkbox, a link or a reference-style link.
+		rest := text[loc[1]:]
+		return !strings.EqualFold(name, "x") &&
+			!strings.HasPrefix(rest, "(") && !strings.HasPrefix(rest, "[") && !strings.HasPrefix(rest, ":")
+	case 3:
+		// Tags such as <br> or <div>, autolinks and addresses read as
+		// markup; blanks have a space, an underscore or capitals.
+		return strings.ContainsAny(name, " _") || strings.ToUpper(name) == name
+	default:
+		return true
+	}
+}
+
+// isName reports whether s reads like the label of a blank: short, with a
+// letter, and free of the punctuation of code and data.
+func isName(s string) bool {
+	return s != "" &&
+		len(s) <= maxNameLength &&
+		strings.IndexFunc(s, unicode.IsLetter) >= 0 &&
+		!strings.ContainsAny(s, "\"=;/@`\\")
+}
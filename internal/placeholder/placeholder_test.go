@@ -0,0 +1,58 @@
+package placeholder
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFind(t *testing.T) {
+	text := "Write to [Your Company Name] for {audience} using <insert data> and {{tone}}.\n" +
+		"Sign as [Your Company Name]. <SENDER> too.\n" +
+		"- [ ] todo\n- [x] done\nSee [the docs](https://example.com) and [ref][1].\n" +
+		"Keep <br> and <b>bold</b>, {\"json\": true}, `[in code]` and:\n```\nfmt.Println(\"{x}\")\n[also code]\n```\n"
+
+	assert.Equal(t, []string{"[Your Company Name]", "{audience}", "<insert data>", "{{tone}}", "<SENDER>"}, Find(text))
+}
+
+func TestFill(t *testing.T) {
+	text := "Dear [Name], from [Company]. Keep `[Name]` as code."
+
+	got := Fill(text, map[string]string{"[Name]": "Ada"})
+
+	assert.Equal(t, "Dear Ada, from [Company]. Keep `[Name]` as code.", got)
+}
+
+func TestFill_LibraryVariables(t *testing.T) {
+	text := "Notes for {{ version }} and {{version}}, by [Author], for {{audience}}."
+
+	assert.Equal(t, []string{"{{version}}", "[Author]", "{{audience}}"}, Find(text), "Variables are found as library.Variables names them")
+
+	got := Fill(text, map[string]string{"{{version}}": "v2", "[Author]": "Ada"})
+
+	assert.Equal(t, "Notes for v2 and v2, by Ada, for {{audience}}.", got)
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "company name", Key("[Company  Name]"))
+	assert.Equal(t, Key("{company name}"), Key("<Company Name>"))
+}
+
+func TestMemory_RemembersRecentValuesFirst(t *testing.T) {
+	m := NewMemory(filepath.Join(t.TempDir(), "config", fileName))
+
+	values, err := m.Load()
+	require.NoError(t, err)
+	assert.Empty(t, values, "A missing file remembers nothing")
+
+	for _, v := range []string{"Acme", "Globex", "Initech", "Umbrella", "Hooli", "Acme", "Pied Piper"} {
+		require.NoError(t, m.Remember(map[string]string{"[Company Name]": v, "{audience}": ""}))
+	}
+
+	values, err = m.Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Pied Piper", "Acme", "Hooli", "Umbrella", "Initech"}, values.Recent("{company name}"))
+	assert.Empty(t, values.Recent("{audience}"), "Empty values are not remembered")
+}
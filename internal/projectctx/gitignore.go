@@ -0,0 +1,124 @@
+package projectctx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is one pattern line of a .gitignore file.
+type ignoreRule struct {
+	base    string // directory of the .gitignore, relative to the root
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// ignorer accumulates .gitignore rules as the walk descends into directories.
+type ignorer struct {
+	rules []ignoreRule
+}
+
+// enterDir skips ignored directories and loads their .gitignore otherwise.
+func (ig *ignorer) enterDir(dir, rel string) error {
+	if rel == "." {
+		return ig.load(dir, "")
+	}
+
+	if ig.ignored(rel, true) {
+		return filepath.SkipDir
+	}
+
+	return ig.load(dir, rel)
+}
+
+// load reads the .gitignore in dir, if any. relDir is dir relative to the root.
+func (ig *ignorer) load(dir, relDir string) error {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("reading .gitignore: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text(), relDir); ok {
+			ig.rules = append(ig.rules, rule)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading .gitignore: %w", err)
+	}
+
+	return nil
+}
+
+func parseIgnoreLine(line, base string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+
+	line = strings.TrimPrefix(line, "\\")
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	// A pattern with a leading or inner slash is anchored to its base;
+	// otherwise it matches at any depth below it.
+	anchored := strings.Contains(line, "/")
+
+	line = strings.TrimPrefix(line, "/")
+	if !anchored {
+		line = "**/" + line
+	}
+
+	rule.pattern = line
+
+	return rule, rule.pattern != ""
+}
+
+// ignored reports whether rel, a slash-separated path relative to the root,
+// is excluded. As in git, the last matching rule wins.
+func (ig *ignorer) ignored(rel string, isDir bool) bool {
+	ignored := false
+
+	for _, rule := range ig.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		sub := rel
+		if rule.base != "" {
+			var ok bool
+			if sub, ok = strings.CutPrefix(rel, rule.base+"/"); !ok {
+				continue
+			}
+		}
+
+		if matchGlob(rule.pattern, sub) {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored || path.Base(rel) == ".git"
+}
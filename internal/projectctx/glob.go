@@ -0,0 +1,87 @@
+package projectctx
+
+import (
+	"path"
+	"strings"
+)
+
+// matchGlob reports whether the slash-separated name matches pattern. In
+// addition to path.Match syntax, a "**" segment matches zero or more
+// directories.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(splitPath(pattern), splitPath(name))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse repeated "**" and try every possible split point.
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+
+			if len(pattern) == 0 {
+				return true
+			}
+
+			for i := range name {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// mayContain reports whether files below dir could match pattern, so that
+// directories outside every pattern are not walked.
+func mayContain(pattern, dir string) bool {
+	p := splitPath(pattern)
+
+	for i, seg := range splitPath(dir) {
+		if i < len(p) && p[i] == "**" {
+			return true
+		}
+
+		// The last pattern segment names files, so dir must stop before it.
+		if i >= len(p)-1 {
+			return false
+		}
+
+		if ok, err := path.Match(p[i], seg); err != nil || !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizePattern strips a leading "./" so patterns match paths relative to the root.
+func normalizePattern(pattern string) string {
+	pattern = path.Clean(strings.ReplaceAll(pattern, "\\", "/"))
+
+	return strings.TrimPrefix(pattern, "./")
+}
+
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" || p == "." {
+		return nil
+	}
+
+	return strings.Split(p, "/")
+}
@@ -0,0 +1,274 @@
+// Package projectctx collects project files matching glob patterns, honoring
+// .gitignore, and selects the most relevant ones that fit a token budget so
+// they can be sent to the model as context.
+package projectctx
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"prompt-maker/internal/attachment"
+)
+
+const (
+	// DefaultBudget is the token budget used when none is configured.
+	DefaultBudget = 100_000
+	// charsPerToken is the rough ratio used to estimate token counts.
+	charsPerToken = 4
+	// minTruncateTokens is the smallest remainder worth filling with a truncated file.
+	minTruncateTokens = 256
+	// truncatedMarker ends a file that was cut to fit the budget.
+	truncatedMarker = "\n... [truncated to fit the context budget]"
+	// pathMatchWeight makes a query term in the path count more than one in the content.
+	pathMatchWeight = 5
+	// maxContentHits caps how much a single query term can add from the content.
+	maxContentHits = 5
+	// minTermLength skips short words when scoring relevance.
+	minTermLength = 3
+)
+
+// ErrNoGlobs is returned when Collect is called without patterns.
+var ErrNoGlobs = errors.New("no context globs given")
+
+// File is a collected text file.
+type File struct {
+	// Path is slash-separated and relative to the collection root.
+	Path      string
+	Content   string
+	Truncated bool
+	// glob is the index of the first pattern that matched, used as a tie-breaker.
+	glob int
+}
+
+// Tokens estimates the token count of the file's content.
+func (f File) Tokens() int {
+	return EstimateTokens(f.Content)
+}
+
+// EstimateTokens approximates the token count of s.
+func EstimateTokens(s string) int {
+	return (len(s) + charsPerToken - 1) / charsPerToken
+}
+
+// Collect walks root and returns every text file matching one of globs that
+// is not excluded by a .gitignore. Globs are relative to root and support
+// "**" for any number of directories, e.g. "./internal/**/*.go". Files and
+// directories below root that cannot be read are skipped.
+func Collect(root string, globs []string) ([]File, error) {
+	if len(globs) == 0 {
+		return nil, ErrNoGlobs
+	}
+
+	patterns := make([]string, len(globs))
+	for i, g := range globs {
+		patterns[i] = normalizePattern(g)
+	}
+
+	var (
+		files []File
+		ig    ignorer
+	)
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return skipUnreadable(root, p, d, err)
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return fmt.Errorf("resolving %s: %w", p, err)
+		}
+
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel != "." && !slices.ContainsFunc(patterns, func(pattern string) bool { return mayContain(pattern, rel) }) {
+				return filepath.SkipDir
+			}
+
+			if err := ig.enterDir(p, rel); err != nil {
+				return skipUnreadable(root, p, d, err)
+			}
+
+			return nil
+		}
+
+		if ig.ignored(rel, false) || !d.Type().IsRegular() {
+			return nil
+		}
+
+		if i := slices.IndexFunc(patterns, func(pattern string) bool { return matchGlob(pattern, rel) }); i >= 0 {
+			if f, ok := readFile(p, rel); ok {
+				f.glob = i
+				files = append(files, f)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("collecting context files: %w", err)
+	}
+
+	return files, nil
+}
+
+// skipUnreadable skips the entry at p that failed with err, unless it is
+// root itself, so that the rest of the project is still collected.
+func skipUnreadable(root, p string, d fs.DirEntry, err error) error {
+	if p == root || errors.Is(err, filepath.SkipDir) {
+		return err
+	}
+
+	slog.Debug("skipping unreadable context entry", "path", p, "error", err)
+
+	if d != nil && d.IsDir() {
+		return filepath.SkipDir
+	}
+
+	return nil
+}
+
+// readFile loads p as a text file, skipping unreadable, binary and
+// oversized files.
+func readFile(p, rel string) (File, bool) {
+	a, err := attachment.Load(p)
+	if err != nil {
+		slog.Debug("skipping context file", "path", p, "error", err)
+
+		return File{}, false
+	}
+
+	if a.IsImage() {
+		return File{}, false
+	}
+
+	return File{Path: rel, Content: string(a.Data)}, true
+}
+
+// Select ranks files by relevance to query and returns, in rank order, as
+// many as fit in budget tokens. When the next file does not fit whole and a
+// useful amount of budget remains, it is truncated to fill it.
+func Select(files []File, query string, budget int) []File {
+	if budget <= 0 {
+		budget = DefaultBudget
+	}
+
+	ranked := rank(files, query)
+	selected := make([]File, 0, len(ranked))
+	remaining := budget
+
+	for _, f := range ranked {
+		tokens := f.Tokens()
+		if tokens <= remaining {
+			selected = append(selected, f)
+			remaining -= tokens
+
+			continue
+		}
+
+		if remaining >= minTruncateTokens {
+			selected = append(selected, truncate(f, remaining))
+			remaining = 0
+		}
+	}
+
+	return selected
+}
+
+// Attachments wraps each file as a text attachment labeled with its path.
+func Attachments(files []File) []attachment.Attachment {
+	attachments := make([]attachment.Attachment, len(files))
+	for i, f := range files {
+		attachments[i] = attachment.Attachment{Name: f.Path, MIMEType: "text/plain", Data: []byte(f.Content)}
+	}
+
+	return attachments
+}
+
+// TotalTokens sums the estimated token counts of files.
+func TotalTokens(files []File) int {
+	total := 0
+	for _, f := range files {
+		total += f.Tokens()
+	}
+
+	return total
+}
+
+// rank orders files by query relevance, then by pattern order, shallower
+// paths and smaller files first.
+func rank(files []File, query string) []File {
+	terms := queryTerms(query)
+	scores := make(map[string]int, len(files))
+
+	for _, f := range files {
+		scores[f.Path] = score(f, terms)
+	}
+
+	ranked := slices.Clone(files)
+	slices.SortStableFunc(ranked, func(a, b File) int {
+		return cmp.Or(
+			cmp.Compare(scores[b.Path], scores[a.Path]),
+			cmp.Compare(a.glob, b.glob),
+			cmp.Compare(strings.Count(a.Path, "/"), strings.Count(b.Path, "/")),
+			cmp.Compare(len(a.Content), len(b.Content)),
+			strings.Compare(a.Path, b.Path),
+		)
+	})
+
+	return ranked
+}
+
+func score(f File, terms []string) int {
+	lowerPath := strings.ToLower(f.Path)
+	lowerContent := strings.ToLower(f.Content)
+	total := 0
+
+	for _, term := range terms {
+		if strings.Contains(lowerPath, term) {
+			total += pathMatchWeight
+		}
+
+		total += min(strings.Count(lowerContent, term), maxContentHits)
+	}
+
+	return total
+}
+
+// queryTerms splits query into distinct lower-case words worth matching.
+func queryTerms(query string) []string {
+	var terms []string
+
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		if len(word) >= minTermLength && !slices.Contains(terms, word) {
+			terms = append(terms, word)
+		}
+	}
+
+	return terms
+}
+
+// truncate cuts f at a line boundary so that it fits in tokens.
+func truncate(f File, tokens int) File {
+	limit := max(0, tokens*charsPerToken-len(truncatedMarker))
+	content := strings.ToValidUTF8(f.Content[:min(limit, len(f.Content))], "")
+
+	if i := strings.LastIndexByte(content, '\n'); i > 0 {
+		content = content[:i]
+	}
+
+	f.Content = content + truncatedMarker
+	f.Truncated = true
+
+	return f
+}
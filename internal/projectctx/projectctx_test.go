@@ -0,0 +1,147 @@
+package projectctx
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o750))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o600))
+	}
+}
+
+func paths(files []File) []string {
+	out := make([]string, len(files))
+	for i, f := range files {
+		out[i] = f.Path
+	}
+
+	return out
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "internal/tui/model.go", true},
+		{"internal/**/*.go", "internal/tui/model.go", true},
+		{"internal/**/*.go", "cmd/cli.go", false},
+		{"internal/**", "internal/tui/model.go", true},
+		{"docs/*.md", "docs/guide/intro.md", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchGlob(tt.pattern, tt.name))
+		})
+	}
+}
+
+func TestMayContain(t *testing.T) {
+	assert.True(t, mayContain("internal/**/*.go", "internal/tui"))
+	assert.True(t, mayContain("**/*.go", "vendor"))
+	assert.True(t, mayContain("cmd/*.go", "cmd"))
+	assert.False(t, mayContain("cmd/*.go", "cmd/sub"))
+	assert.False(t, mayContain("internal/**/*.go", "docs"))
+	assert.False(t, mayContain("*.go", "internal"))
+}
+
+func TestCollect_HonorsGitignore(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":            "build/\n*.gen.go\n!keep.gen.go\n",
+		"main.go":               "package main\n",
+		"keep.gen.go":           "package main\n",
+		"api.gen.go":            "package main\n",
+		"build/out.go":          "package build\n",
+		"internal/a/a.go":       "package a\n",
+		"internal/a/.gitignore": "secret.go\n",
+		"internal/a/secret.go":  "package a\n",
+		"internal/a/notes.md":   "# Notes\n",
+		"internal/a/logo.png":   "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+	})
+
+	files, err := Collect(root, []string{"./**/*.go", "internal/**/*.png"})
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"main.go", "keep.gen.go", "internal/a/a.go"}, paths(files))
+}
+
+func TestCollect_SkipsUnreadableEntries(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("File permissions do not apply to root")
+	}
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.go":           "package main\n",
+		"locked.go":         "package main\n",
+		"private/hidden.go": "package private\n",
+	})
+	require.NoError(t, os.Chmod(filepath.Join(root, "locked.go"), 0))
+	require.NoError(t, os.Chmod(filepath.Join(root, "private"), 0))
+	t.Cleanup(func() { _ = os.Chmod(filepath.Join(root, "private"), 0o700) })
+
+	files, err := Collect(root, []string{"**/*.go"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go"}, paths(files))
+}
+
+func TestCollect_NoGlobs(t *testing.T) {
+	_, err := Collect(t.TempDir(), nil)
+
+	require.ErrorIs(t, err, ErrNoGlobs)
+}
+
+func TestSelect_RanksByQuery(t *testing.T) {
+	files := []File{
+		{Path: "internal/cache/cache.go", Content: "package cache"},
+		{Path: "internal/tui/model.go", Content: "package tui // renders the viewport"},
+		{Path: "README.md", Content: "# readme", glob: 1},
+	}
+
+	selected := Select(files, "Why does the viewport flicker in the TUI?", DefaultBudget)
+
+	assert.Equal(t, []string{"internal/tui/model.go", "internal/cache/cache.go", "README.md"}, paths(selected))
+}
+
+func TestSelect_TruncatesToBudget(t *testing.T) {
+	files := []File{
+		{Path: "small.go", Content: strings.Repeat("a", 400)},
+		{Path: "big.go", Content: strings.Repeat("line\n", 2000)},
+		{Path: "other.go", Content: strings.Repeat("b", 4000)},
+	}
+
+	selected := Select(files, "", 500)
+
+	require.Len(t, selected, 2)
+	assert.Equal(t, "small.go", selected[0].Path)
+	assert.False(t, selected[0].Truncated)
+	assert.Equal(t, "other.go", selected[1].Path)
+	assert.True(t, selected[1].Truncated)
+	assert.LessOrEqual(t, TotalTokens(selected), 500)
+	assert.True(t, strings.HasSuffix(selected[1].Content, truncatedMarker))
+}
+
+func TestAttachments_LabeledByPath(t *testing.T) {
+	attachments := Attachments([]File{{Path: "cmd/cli.go", Content: "package cmd"}})
+
+	require.Len(t, attachments, 1)
+	assert.Contains(t, attachments[0].Part().Text, "--- Attached file: cmd/cli.go (text/plain) ---")
+}
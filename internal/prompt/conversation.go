@@ -0,0 +1,35 @@
+package prompt
+
+import (
+	"context"
+
+	"prompt-maker/internal/attachment"
+
+	"google.golang.org/genai"
+)
+
+// Turn is one message of a conversation with the model.
+type Turn struct {
+	// Role is genai.RoleUser or genai.RoleModel.
+	Role string
+	Text string
+}
+
+// Contents converts a transcript to the history of a chat session.
+func Contents(transcript []Turn) []*genai.Content {
+	contents := make([]*genai.Content, len(transcript))
+	for i, turn := range transcript {
+		contents[i] = genai.NewContentFromText(turn.Text, genai.Role(turn.Role))
+	}
+
+	return contents
+}
+
+// FollowUp continues the conversation in transcript with question, without
+// any system prompt. The session is rebuilt from the transcript, so it can
+// continue on another model of the fallback chain.
+func (r *Runner) FollowUp(
+	ctx context.Context, model string, transcript []Turn, question string, attachments ...attachment.Attachment,
+) (Result, error) {
+	return r.run(ctx, model, Contents(transcript), question, attachments, Execute)
+}
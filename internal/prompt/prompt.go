@@ -7,11 +7,15 @@ import (
 	"fmt"
 	"strings"
 
+	"prompt-maker/internal/attachment"
 	"prompt-maker/internal/gemini"
 
 	"google.golang.org/genai"
 )
 
+// LyraPersona names the persona defined by LyraPrompt.
+const LyraPersona = "lyra"
+
 // LyraPrompt contains the embedded system prompt used to craft optimized prompts.
 //
 //go:embed lyra.txt
@@ -24,40 +28,71 @@ var ErrSendMessage = errors.New("error sending message to Gemini")
 var ErrNoResponseCandidates = errors.New("received no response candidates from model")
 
 // Generate creates an optimized prompt by sending the user's input along with the Lyra system prompt to the Gemini model.
-func Generate(ctx context.Context, cs gemini.ChatSession, userInput string) (string, error) {
+// Attachments follow the text as additional parts.
+func Generate(ctx context.Context, cs gemini.ChatSession, userInput string, attachments ...attachment.Attachment) (Result, error) {
 	fullPrompt := LyraPrompt + userInput
 
-	resp, err := cs.SendMessage(ctx, genai.Part{Text: fullPrompt})
+	return send(ctx, cs, buildParts(fullPrompt, attachments)...)
+}
+
+// Execute sends a prompt to the Gemini model without any system prompt.
+// Attachments follow the text as additional parts.
+func Execute(ctx context.Context, cs gemini.ChatSession, userInput string, attachments ...attachment.Attachment) (Result, error) {
+	return send(ctx, cs, buildParts(userInput, attachments)...)
+}
+
+// buildParts puts the prompt text first, followed by one part per attachment.
+func buildParts(text string, attachments []attachment.Attachment) []genai.Part {
+	parts := make([]genai.Part, 0, len(attachments)+1)
+	parts = append(parts, genai.Part{Text: text})
+
+	for _, a := range attachments {
+		parts = append(parts, a.Part())
+	}
+
+	return parts
+}
+
+// send delivers parts to the session and splits the first candidate into
+// answer text and thought summaries.
+func send(ctx context.Context, cs gemini.ChatSession, parts ...genai.Part) (Result, error) {
+	resp, err := cs.SendMessage(ctx, parts...)
 	if err != nil {
-		return "", fmt.Errorf("%w: %w", ErrSendMessage, err)
+		return Result{}, fmt.Errorf("%w: %w", ErrSendMessage, err)
 	}
 
 	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
-		return "", ErrNoResponseCandidates
+		return Result{}, ErrNoResponseCandidates
 	}
 
-	var b strings.Builder
+	var text, thoughts strings.Builder
 
 	for _, part := range resp.Candidates[0].Content.Parts {
-		if txt := part.Text; txt != "" {
-			b.WriteString(txt)
+		switch {
+		case part.Text == "":
+			continue
+		case part.Thought:
+			thoughts.WriteString(part.Text)
+		default:
+			text.WriteString(part.Text)
 		}
 	}
 
-	return b.String(), nil
+	return Result{
+		Text:     text.String(),
+		Thoughts: thoughts.String(),
+		Usage:    usageFrom(resp.UsageMetadata),
+	}, nil
 }
 
-// Execute sends a prompt to the Gemini model without any system prompt.
-func Execute(ctx context.Context, cs gemini.ChatSession, userInput string) (string, error) {
-	resp, err := cs.SendMessage(ctx, genai.Part{Text: userInput})
-	if err != nil {
-		return "", fmt.Errorf("%w: %w", ErrSendMessage, err)
+func usageFrom(md *genai.GenerateContentResponseUsageMetadata) Usage {
+	if md == nil {
+		return Usage{}
 	}
 
-	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
-		// Corrected the error variable name.
-		return "", ErrNoResponseCandidates
+	return Usage{
+		PromptTokens:   md.PromptTokenCount,
+		OutputTokens:   md.CandidatesTokenCount,
+		ThinkingTokens: md.ThoughtsTokenCount,
 	}
-
-	return resp.Text(), nil
 }
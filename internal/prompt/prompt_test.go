@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"prompt-maker/internal/attachment"
 	"prompt-maker/internal/testutil"
 
 	"github.com/stretchr/testify/require"
@@ -40,9 +41,62 @@ func TestGenerate(t *testing.T) {
 	answer, err := Generate(ctx, mockCS, userInput)
 
 	require.NoError(t, err)
-	require.Equal(t, expectedAnswer, answer)
+	require.Equal(t, expectedAnswer, answer.Text)
+}
+
+func TestExecute_SeparatesThoughtsAndUsage(t *testing.T) {
+	mockCS := &testutil.MockChatSession{
+		SendMessageFunc: func(_ context.Context, _ ...genai.Part) (*genai.GenerateContentResponse, error) {
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{
+					{
+						Content: &genai.Content{
+							Parts: []*genai.Part{
+								{Text: "Weighing the options.", Thought: true},
+								{Text: "The answer."},
+							},
+						},
+					},
+				},
+				UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+					PromptTokenCount:     10,
+					CandidatesTokenCount: 3,
+					ThoughtsTokenCount:   42,
+				},
+			}, nil
+		},
+	}
+
+	result, err := Execute(context.Background(), mockCS, "question")
+
+	require.NoError(t, err)
+	require.Equal(t, "The answer.", result.Text)
+	require.Equal(t, "Weighing the options.", result.Thoughts)
+	require.Equal(t, Usage{PromptTokens: 10, OutputTokens: 3, ThinkingTokens: 42}, result.Usage)
 }
 
 func TestLyraPrompt(t *testing.T) {
 	require.NotEmpty(t, LyraPrompt, "LyraPrompt should not be empty")
 }
+
+func TestExecute_SendsAttachmentsAfterText(t *testing.T) {
+	spec, err := attachment.New("spec.md", []byte("# Spec"))
+	require.NoError(t, err)
+
+	mockCS := &testutil.MockChatSession{
+		SendMessageFunc: func(_ context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+			require.Len(t, parts, 2)
+			require.Equal(t, "summarize the spec", parts[0].Text)
+			require.Contains(t, parts[1].Text, "--- Attached file: spec.md")
+
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{Content: &genai.Content{Parts: []*genai.Part{{Text: "summary"}}}}},
+			}, nil
+		},
+	}
+
+	result, err := Execute(context.Background(), mockCS, "summarize the spec", spec)
+
+	require.NoError(t, err)
+	require.Equal(t, "summary", result.Text)
+}
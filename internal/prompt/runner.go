@@ -0,0 +1,118 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"prompt-maker/internal/attachment"
+	"prompt-maker/internal/gemini"
+
+	"google.golang.org/genai"
+)
+
+// ErrModelsUnavailable is returned when every model in the fallback chain is
+// out of quota, overloaded or not found.
+var ErrModelsUnavailable = errors.New("all models in the fallback chain are unavailable")
+
+// Result holds the text of a response, its thought summaries, token usage,
+// the model that actually produced it and how long the call took.
+type Result struct {
+	Text     string
+	Thoughts string
+	Model    string
+	Usage    Usage
+	// Latency covers the whole call, including any fallback attempts.
+	Latency time.Duration
+}
+
+// Usage reports the token counts of a response. Thinking tokens are kept
+// separate from output tokens.
+type Usage struct {
+	PromptTokens   int32 `json:"promptTokens"`
+	OutputTokens   int32 `json:"outputTokens"`
+	ThinkingTokens int32 `json:"thinkingTokens,omitempty"`
+}
+
+// sendFunc is the shape shared by Generate and Execute.
+type sendFunc func(ctx context.Context, cs gemini.ChatSession, userInput string, attachments ...attachment.Attachment) (Result, error)
+
+// Runner creates chat sessions and sends prompts through them, retrying on
+// the next model of its fallback list when the chosen model is unavailable.
+type Runner struct {
+	creator   gemini.ChatCreator
+	fallbacks []string
+	genOpts   gemini.GenerationOptions
+}
+
+// NewRunner returns a Runner that creates sessions configured by genOpts
+// with creator and falls back through fallbacks, in order, after the
+// requested model.
+func NewRunner(creator gemini.ChatCreator, fallbacks []string, genOpts gemini.GenerationOptions) *Runner {
+	return &Runner{
+		creator:   creator,
+		fallbacks: fallbacks,
+		genOpts:   genOpts,
+	}
+}
+
+// WithTemperature returns a copy of r that samples at temperature. r itself
+// is left unchanged for the requests already using it.
+func (r *Runner) WithTemperature(temperature float32) *Runner {
+	c := *r
+	c.genOpts.Temperature = temperature
+
+	return &c
+}
+
+// Generate crafts an optimized prompt from userInput with the Lyra system prompt.
+func (r *Runner) Generate(ctx context.Context, model, userInput string, attachments ...attachment.Attachment) (Result, error) {
+	return r.run(ctx, model, nil, userInput, attachments, Generate)
+}
+
+// Execute sends userInput to the model without any system prompt.
+func (r *Runner) Execute(ctx context.Context, model, userInput string, attachments ...attachment.Attachment) (Result, error) {
+	return r.run(ctx, model, nil, userInput, attachments, Execute)
+}
+
+// run sends userInput in a session started from history, which may be nil,
+// on each model of the chain until one can serve it.
+func (r *Runner) run(
+	ctx context.Context, model string, history []*genai.Content, userInput string,
+	attachments []attachment.Attachment, sendFn sendFunc,
+) (Result, error) {
+	var lastErr error
+
+	start := time.Now()
+
+	for _, name := range gemini.ModelChain(model, r.fallbacks) {
+		session, err := r.creator.Create(ctx, name, r.genOpts.ContentConfig(), history)
+		if err != nil {
+			return Result{}, fmt.Errorf("creating chat session: %w", err)
+		}
+
+		result, err := sendFn(ctx, session, userInput, attachments...)
+		if err == nil {
+			result.Model = name
+			result.Latency = time.Since(start)
+
+			return result, nil
+		}
+
+		if !gemini.IsFallbackError(err) {
+			return Result{}, err
+		}
+
+		slog.WarnContext(ctx, "model unavailable, trying next in fallback chain", "model", name, "error", err)
+
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		return Result{}, ErrModelsUnavailable
+	}
+
+	return Result{}, fmt.Errorf("%w: %w", ErrModelsUnavailable, lastErr)
+}
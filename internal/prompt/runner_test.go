@@ -0,0 +1,135 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"prompt-maker/internal/gemini"
+	"prompt-maker/internal/testutil"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+var errBadRequest = genai.APIError{Code: http.StatusBadRequest, Message: "bad request"}
+
+// mockChatCreator hands out sessions whose SendMessage is answered by sendFunc.
+type mockChatCreator struct {
+	models   []string
+	sendFunc func(model string) (*genai.GenerateContentResponse, error)
+}
+
+func (m *mockChatCreator) Create(
+	_ context.Context, model string, _ *genai.GenerateContentConfig, _ []*genai.Content,
+) (gemini.ChatSession, error) {
+	m.models = append(m.models, model)
+
+	return &testutil.MockChatSession{
+		SendMessageFunc: func(_ context.Context, _ ...genai.Part) (*genai.GenerateContentResponse, error) {
+			return m.sendFunc(model)
+		},
+	}, nil
+}
+
+func textResponse(text string) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{Content: &genai.Content{Parts: []*genai.Part{{Text: text}}}}},
+	}
+}
+
+func TestRunner_FallsBackOnUnavailableModel(t *testing.T) {
+	creator := &mockChatCreator{
+		sendFunc: func(model string) (*genai.GenerateContentResponse, error) {
+			switch model {
+			case "pro":
+				return nil, genai.APIError{Code: http.StatusServiceUnavailable}
+			case "flash":
+				return nil, genai.APIError{Code: http.StatusTooManyRequests}
+			}
+
+			return textResponse("answer from " + model), nil
+		},
+	}
+
+	runner := NewRunner(creator, []string{"flash", "flash-lite"}, gemini.GenerationOptions{})
+
+	result, err := runner.Execute(context.Background(), "pro", "hello")
+
+	require.NoError(t, err)
+	require.Positive(t, result.Latency, "Latency covers every attempt")
+
+	result.Latency = 0
+	require.Equal(t, Result{Text: "answer from flash-lite", Model: "flash-lite"}, result)
+	require.Equal(t, []string{"pro", "flash", "flash-lite"}, creator.models)
+}
+
+func TestRunner_StopsOnNonFallbackError(t *testing.T) {
+	creator := &mockChatCreator{
+		sendFunc: func(_ string) (*genai.GenerateContentResponse, error) {
+			return nil, errBadRequest
+		},
+	}
+
+	runner := NewRunner(creator, []string{"flash"}, gemini.GenerationOptions{})
+
+	_, err := runner.Generate(context.Background(), "pro", "hello")
+
+	require.ErrorIs(t, err, ErrSendMessage)
+	require.NotErrorIs(t, err, ErrModelsUnavailable)
+	require.Equal(t, []string{"pro"}, creator.models)
+}
+
+func TestRunner_AllModelsUnavailable(t *testing.T) {
+	creator := &mockChatCreator{
+		sendFunc: func(_ string) (*genai.GenerateContentResponse, error) {
+			return nil, genai.APIError{Code: http.StatusServiceUnavailable}
+		},
+	}
+
+	runner := NewRunner(creator, []string{"flash"}, gemini.GenerationOptions{})
+
+	_, err := runner.Execute(context.Background(), "pro", "hello")
+
+	require.ErrorIs(t, err, ErrModelsUnavailable)
+
+	_, ok := errors.AsType[genai.APIError](err)
+	require.True(t, ok, "The last API error should stay in the chain")
+}
+
+func TestRunner_FollowUpStartsFromTranscript(t *testing.T) {
+	var history []*genai.Content
+
+	creator := &historyRecordingCreator{history: &history}
+	runner := NewRunner(creator, nil, gemini.GenerationOptions{})
+
+	result, err := runner.FollowUp(context.Background(), "flash", []Turn{
+		{Role: genai.RoleUser, Text: "Write a haiku about Go."},
+		{Role: genai.RoleModel, Text: "Gophers dig tunnels"},
+	}, "Now do the same for Rust.")
+
+	require.NoError(t, err)
+	require.Equal(t, "answer to Now do the same for Rust.", result.Text)
+	require.Len(t, history, 2)
+	require.Equal(t, genai.RoleModel, history[1].Role)
+	require.Equal(t, "Gophers dig tunnels", history[1].Parts[0].Text)
+}
+
+// historyRecordingCreator keeps the history its session was started from and
+// echoes the question.
+type historyRecordingCreator struct {
+	history *[]*genai.Content
+}
+
+func (c *historyRecordingCreator) Create(
+	_ context.Context, _ string, _ *genai.GenerateContentConfig, history []*genai.Content,
+) (gemini.ChatSession, error) {
+	*c.history = history
+
+	return &testutil.MockChatSession{
+		SendMessageFunc: func(_ context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+			return textResponse("answer to " + parts[0].Text), nil
+		},
+	}, nil
+}
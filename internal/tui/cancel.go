@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// canceledMsg is what a request returns once it was canceled.
+type canceledMsg struct{}
+
+// requestOrigin is the view a request was started from, restored when the
+// request is canceled.
+type requestOrigin struct {
+	state         viewState
+	craftedPrompt string
+	editorValue   string
+}
+
+// startRequest shows the busy view and sends req under a context of its
+// own, so that cancelRequest can stop it without quitting.
+func (m *model) startRequest(busyText string, req promptRequest) tea.Cmd {
+	ctx, cancel := context.WithCancel(m.ctx)
+
+	m.cancelActive = cancel
+	m.requestOrigin = requestOrigin{state: m.state, craftedPrompt: m.craftedPrompt, editorValue: m.editor.Value()}
+	m.state = viewBusy
+	m.busyText = busyText
+
+	return tea.Batch(m.spinner.Tick, sendPromptCmd(ctx, m.runner, m.recorder, req))
+}
+
+// finishRequest releases the context of the request that just returned.
+func (m *model) finishRequest() {
+	if m.cancelActive != nil {
+		m.cancelActive()
+		m.cancelActive = nil
+	}
+}
+
+// cancelRequest stops the request in flight and returns to the view it was
+// started from, with the crafted prompt and the input as they were.
+func (m *model) cancelRequest() (tea.Model, tea.Cmd) {
+	m.finishRequest()
+
+	origin := m.requestOrigin
+	m.state = origin.state
+	m.craftedPrompt = origin.craftedPrompt
+	m.editor.SetValue(origin.editorValue)
+
+	cmds := []tea.Cmd{func() tea.Msg { return statusMessage("Request canceled.") }}
+
+	// The placeholder values stay filled in, ready to execute again.
+	if m.state == viewFilling {
+		cmds = append(cmds, m.fillInput.Focus())
+	}
+
+	return m, tea.Batch(cmds...)
+}
@@ -0,0 +1,97 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+
+	"prompt-maker/internal/prompt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/genai"
+)
+
+// Role labels of the chat transcript.
+const (
+	userRoleLabel  = "You"
+	modelRoleLabel = "Model"
+)
+
+// openChat starts a conversation about the answer shown, seeded with the
+// prompt it answers.
+func (m *model) openChat() (tea.Model, tea.Cmd) {
+	m.transcript = []prompt.Turn{
+		{Role: genai.RoleUser, Text: m.executedPrompt},
+		{Role: genai.RoleModel, Text: m.rawViewportContent},
+	}
+	m.state = viewChat
+	m.editor.Reset()
+	m.editor.Placeholder = placeholderFollowUp
+	m.renderViewport()
+	m.viewport.GotoBottom()
+
+	return m, m.editor.Focus()
+}
+
+func (m *model) updateChat(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(externalEditedMsg); ok {
+		return m.handleExternalEdited(msg)
+	}
+
+	if model, cmd, ok := m.handleCommonMsg(msg); ok {
+		return model, cmd
+	}
+
+	return m.updateComponents(msg)
+}
+
+// askFollowUpQuestion sends the question in the editor with the transcript.
+func (m *model) askFollowUpQuestion() (tea.Model, tea.Cmd) {
+	question := m.editor.Value()
+	if strings.TrimSpace(question) == "" {
+		return m, func() tea.Msg { return statusMessage("Type a question first.") }
+	}
+
+	req := m.newRequest(question, false)
+	req.transcript = slices.Clone(m.transcript)
+
+	return m, m.startRequest(thinkingTextGettingAnswer, req)
+}
+
+// handleFollowUpResponse adds the question and its answer to the transcript.
+func (m *model) handleFollowUpResponse(msg aiResponseMsg) (tea.Model, tea.Cmd) {
+	m.transcript = append(m.transcript,
+		prompt.Turn{Role: genai.RoleUser, Text: msg.prompt},
+		prompt.Turn{Role: genai.RoleModel, Text: msg.response},
+	)
+	m.answeredModel = msg.model
+	m.usage = msg.usage
+	m.rawViewportContent = msg.response
+	m.state = viewChat
+	m.editor.Reset()
+	m.renderViewport()
+	m.viewport.GotoBottom()
+
+	return m, nil
+}
+
+// transcriptView renders each turn of the chat under its styled role label.
+func (m *model) transcriptView() string {
+	var b strings.Builder
+
+	for i, turn := range m.transcript {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		label := m.styles.ModelRole.Render(modelRoleLabel)
+		if turn.Role == genai.RoleUser {
+			label = m.styles.UserRole.Render(userRoleLabel)
+		}
+
+		b.WriteString(label)
+		b.WriteString("\n")
+		b.WriteString(m.renderMarkdown(turn.Text))
+	}
+
+	return b.String()
+}
@@ -4,15 +4,26 @@ import (
 	"context"
 	"fmt"
 
-	"prompt-maker/internal/config"
-	"prompt-maker/internal/gemini"
+	"prompt-maker/internal/attachment"
+	"prompt-maker/internal/history"
 	"prompt-maker/internal/prompt"
 
 	"github.com/atotto/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
-	"google.golang.org/genai"
 )
 
+// promptRequest holds everything a prompt command sends to the model.
+type promptRequest struct {
+	model       string
+	input       string
+	useLyra     bool
+	attachments []attachment.Attachment
+	// entryID is the history entry of the crafted prompt being executed.
+	entryID string
+	// transcript, when set, makes input a follow-up question to it.
+	transcript []prompt.Turn
+}
+
 func copyToClipboardCmd(content string) tea.Cmd {
 	return func() tea.Msg {
 		err := clipboard.WriteAll(content)
@@ -24,44 +35,99 @@ func copyToClipboardCmd(content string) tea.Cmd {
 	}
 }
 
-// sendPromptCmd creates a tea.Cmd that sends a prompt to the AI model.
-// It captures ctx, chatSvc, and selectedModel by value to avoid a data
-// race with the main Update goroutine.
-func sendPromptCmd(ctx context.Context, chatSvc chatCreator, selectedModel, userPrompt string, useLyra bool) tea.Cmd {
+// sendPromptCmd creates a tea.Cmd that sends a prompt to the AI model and
+// records the result with recorder, which may be nil. It captures ctx,
+// runner, recorder and req by value to avoid a data race with the main
+// Update goroutine. Once ctx is canceled, whatever the request returns is
+// reported as canceledMsg.
+func sendPromptCmd(ctx context.Context, runner *prompt.Runner, recorder *history.Recorder, req promptRequest) tea.Cmd {
 	return func() tea.Msg {
-		if userPrompt == "" {
-			return errMsg{err: errPromptEmpty}
+		msg := sendPrompt(ctx, runner, recorder, req)
+		if ctx.Err() != nil {
+			return canceledMsg{}
 		}
 
-		genConfig := &genai.GenerateContentConfig{Temperature: genai.Ptr(float32(config.DefaultModelTemperature))}
+		return msg
+	}
+}
 
-		session, err := chatSvc.Create(ctx, selectedModel, genConfig, nil)
-		if err != nil {
-			return errMsg{err: fmt.Errorf("creating chat session: %w", err)}
-		}
+func sendPrompt(ctx context.Context, runner *prompt.Runner, recorder *history.Recorder, req promptRequest) tea.Msg {
+	if req.input == "" {
+		return errMsg{err: errPromptEmpty}
+	}
 
-		if useLyra {
-			return generateCraftedPrompt(ctx, session, userPrompt)
-		}
+	if req.transcript != nil {
+		return askFollowUp(ctx, runner, req)
+	}
 
-		return getFinalAnswer(ctx, session, userPrompt)
+	if req.useLyra {
+		return generateCraftedPrompt(ctx, runner, recorder, req)
 	}
+
+	return getFinalAnswer(ctx, runner, recorder, req)
 }
 
-func generateCraftedPrompt(ctx context.Context, session gemini.ChatSession, userPrompt string) tea.Msg {
-	response, err := prompt.Generate(ctx, session, userPrompt)
+func generateCraftedPrompt(ctx context.Context, runner *prompt.Runner, recorder *history.Recorder, req promptRequest) tea.Msg {
+	result, err := runner.Generate(ctx, req.model, req.input, req.attachments...)
 	if err != nil {
 		return errMsg{err: fmt.Errorf("generating crafted prompt: %w", err)}
 	}
 
-	return aiResponseMsg{response: response}
+	msg := newAIResponseMsg(req, result)
+	msg.crafted = true
+
+	if recorder != nil {
+		entry, err := recorder.Craft(ctx, req.input, attachment.Names(req.attachments), result)
+		if err == nil {
+			msg.entryID = entry.ID
+		}
+
+		msg.historyErr = err
+	}
+
+	return msg
 }
 
-func getFinalAnswer(ctx context.Context, session gemini.ChatSession, userPrompt string) tea.Msg {
-	response, err := prompt.Execute(ctx, session, userPrompt)
+func getFinalAnswer(ctx context.Context, runner *prompt.Runner, recorder *history.Recorder, req promptRequest) tea.Msg {
+	result, err := runner.Execute(ctx, req.model, req.input, req.attachments...)
 	if err != nil {
 		return errMsg{err: fmt.Errorf("getting final answer: %w", err)}
 	}
 
-	return aiResponseMsg{response: response}
+	msg := newAIResponseMsg(req, result)
+
+	if recorder != nil {
+		entry, err := recorder.Execute(ctx, req.entryID, req.input, attachment.Names(req.attachments), result)
+		if err == nil {
+			msg.entryID = entry.ID
+		}
+
+		msg.historyErr = err
+	}
+
+	return msg
+}
+
+// askFollowUp continues the chat with the question in req.input.
+func askFollowUp(ctx context.Context, runner *prompt.Runner, req promptRequest) tea.Msg {
+	result, err := runner.FollowUp(ctx, req.model, req.transcript, req.input, req.attachments...)
+	if err != nil {
+		return errMsg{err: fmt.Errorf("answering follow-up: %w", err)}
+	}
+
+	msg := newAIResponseMsg(req, result)
+	msg.followUp = true
+
+	return msg
+}
+
+func newAIResponseMsg(req promptRequest, result prompt.Result) aiResponseMsg {
+	return aiResponseMsg{
+		response:       result.Text,
+		model:          result.Model,
+		requestedModel: req.model,
+		thoughts:       result.Thoughts,
+		usage:          result.Usage,
+		prompt:         req.input,
+	}
 }
@@ -11,7 +11,7 @@ const (
 // Styles holds all lipgloss styles used across the TUI components.
 type Styles struct {
 	Header, AppName, AppVersion, ModelName, MainContent, Input, StatusBar, StatusText,
-	ResubmitHelp, SelectedListItem, ListItem, Spinner, Error lipgloss.Style
+	HelpKey, SelectedListItem, ListItem, Spinner, Error, UserRole, ModelRole, Tab, ActiveTab lipgloss.Style
 }
 
 // NewStyles returns a Styles struct initialized with the application's default style definitions.
@@ -25,10 +25,14 @@ func NewStyles() Styles {
 		Input:            lipgloss.NewStyle().Padding(1, horizontalPadding),
 		StatusBar:        lipgloss.NewStyle().Padding(0, horizontalPadding),
 		StatusText:       lipgloss.NewStyle().Foreground(lipgloss.Color("241")),
-		ResubmitHelp:     lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("35")),
+		HelpKey:          lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("35")),
 		SelectedListItem: lipgloss.NewStyle().Padding(0, 0, 0, listHorizontalPadding).Foreground(lipgloss.Color("208")),
 		ListItem:         lipgloss.NewStyle().Padding(0, 0, 0, listHorizontalPadding),
 		Spinner:          lipgloss.NewStyle().Foreground(lipgloss.Color("205")),
 		Error:            lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true),
+		UserRole:         lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("39")),
+		ModelRole:        lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("35")),
+		Tab:              lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("241")),
+		ActiveTab:        lipgloss.NewStyle().Padding(0, 1).Bold(true).Foreground(lipgloss.Color("208")).Underline(true),
 	}
 }
@@ -0,0 +1,35 @@
+package tui
+
+import (
+	"fmt"
+
+	"prompt-maker/internal/projectctx"
+
+	"github.com/charmbracelet/bubbles/textarea"
+)
+
+// newEditor creates the multi-line prompt editor. Enter inserts a newline;
+// the Submit binding sends the prompt.
+func newEditor(charLimit int) textarea.Model {
+	ta := textarea.New()
+	ta.Placeholder = placeholderRoughPrompt
+	ta.ShowLineNumbers = false
+	ta.Prompt = ""
+	ta.CharLimit = charLimit
+	ta.MaxHeight = 0
+	ta.SetHeight(editorHeight)
+	ta.Focus()
+
+	return ta
+}
+
+// editorCounts reports the size of the editor contents in characters and
+// estimated tokens.
+func (m *model) editorCounts() string {
+	value := m.editor.Value()
+	if value == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%d/%d chars · ~%d tokens", m.editor.Length(), m.editor.CharLimit, projectctx.EstimateTokens(value))
+}
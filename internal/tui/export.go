@@ -0,0 +1,140 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"prompt-maker/internal/export"
+	"prompt-maker/internal/history"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// exportFileLayout names the default transcript file after the time of the export.
+const exportFileLayout = "prompt-maker-20060102-150405"
+
+// exportedMsg reports the outcome of exporting the session.
+type exportedMsg struct {
+	path    string
+	entries int
+	err     error
+}
+
+// newExportInput creates the input that names the transcript file.
+func newExportInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "File name ending in .md, .html or .json"
+	ti.Prompt = "Export to: "
+
+	return ti
+}
+
+// exportSessionCmd writes the history entries named by ids to path, in the
+// format its extension names.
+func exportSessionCmd(ctx context.Context, store history.Store, ids []string, path string) tea.Cmd {
+	return func() tea.Msg {
+		format, err := export.FormatForPath(path)
+		if err != nil {
+			return exportedMsg{path: path, err: err}
+		}
+
+		entries := make([]history.Entry, 0, len(ids))
+
+		for _, id := range ids {
+			e, getErr := store.Get(ctx, id)
+			if getErr != nil {
+				return exportedMsg{path: path, err: getErr}
+			}
+
+			entries = append(entries, e)
+		}
+
+		var buf bytes.Buffer
+		if err = export.Write(&buf, entries, export.Options{Format: format}); err != nil {
+			return exportedMsg{path: path, err: err}
+		}
+
+		if err = os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+			return exportedMsg{path: path, err: err}
+		}
+
+		return exportedMsg{path: path, entries: len(entries)}
+	}
+}
+
+// rememberEntry adds a history entry to the session that ctrl+e exports.
+func (m *model) rememberEntry(id string) {
+	if id != "" && !slices.Contains(m.sessionIDs, id) {
+		m.sessionIDs = append(m.sessionIDs, id)
+	}
+}
+
+// openExport asks for the file to export the session to.
+func (m *model) openExport() (tea.Model, tea.Cmd) {
+	switch {
+	case m.recorder == nil:
+		return m, func() tea.Msg { return statusMessage("History is disabled; there is nothing to export.") }
+	case len(m.sessionIDs) == 0:
+		return m, func() tea.Msg { return statusMessage("Nothing to export yet.") }
+	}
+
+	m.exportReturnState = m.state
+	m.state = viewExporting
+	m.exportInput.SetValue(time.Now().Format(exportFileLayout) + ".md")
+	m.exportInput.CursorEnd()
+
+	return m, m.exportInput.Focus()
+}
+
+// closeExport returns to the state the export was started from.
+func (m *model) closeExport() (tea.Model, tea.Cmd) {
+	m.state = m.exportReturnState
+	m.exportInput.Blur()
+
+	return m, nil
+}
+
+func (m *model) updateExporting(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case exportedMsg:
+		return m.handleExported(msg)
+	case statusMessage, clearStatusMsg:
+		model, cmd, _ := m.handleCommonMsg(msg)
+
+		return model, cmd
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Back):
+			return m.closeExport()
+		case key.Matches(msg, m.keys.Confirm):
+			if m.exportInput.Value() == "" {
+				return m, func() tea.Msg { return statusMessage("Enter a file to export to.") }
+			}
+
+			return m, exportSessionCmd(m.ctx, m.recorder.Store(), slices.Clone(m.sessionIDs), m.exportInput.Value())
+		}
+	}
+
+	var cmd tea.Cmd
+
+	m.exportInput, cmd = m.exportInput.Update(msg)
+
+	return m, cmd
+}
+
+func (m *model) handleExported(msg exportedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, func() tea.Msg { return statusMessage("Not exported: " + msg.err.Error()) }
+	}
+
+	model, cmd := m.closeExport()
+	status := fmt.Sprintf("Exported %d interactions to %s.", msg.entries, msg.path)
+
+	return model, tea.Batch(cmd, func() tea.Msg { return statusMessage(status) })
+}
@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fallbackEditor is run when neither $VISUAL nor $EDITOR is set.
+const fallbackEditor = "vi"
+
+// externalEditedMsg carries the text saved in the external editor.
+type externalEditedMsg struct {
+	text string
+	err  error
+}
+
+// externalEditorCommand returns the command that edits path with $VISUAL,
+// $EDITOR or vi. The variables may hold arguments, as in "code --wait".
+func externalEditorCommand(path string) *exec.Cmd {
+	fields := strings.Fields(cmp.Or(os.Getenv("VISUAL"), os.Getenv("EDITOR")))
+	if len(fields) == 0 {
+		fields = []string{fallbackEditor}
+	}
+
+	//nolint:gosec // Running the user's own editor is the point.
+	return exec.Command(fields[0], append(fields[1:], path)...)
+}
+
+// externalEdit is a tea.ExecCommand that edits text in a temporary Markdown
+// file. Bubble Tea runs it while the TUI is suspended, so the file I/O stays
+// out of Update.
+type externalEdit struct {
+	text           string
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+func (e *externalEdit) SetStdin(r io.Reader)  { e.stdin = r }
+func (e *externalEdit) SetStdout(w io.Writer) { e.stdout = w }
+func (e *externalEdit) SetStderr(w io.Writer) { e.stderr = w }
+
+// Run writes the text to a temporary file, runs the editor on it and reads
+// the saved text back.
+func (e *externalEdit) Run() error {
+	f, err := os.CreateTemp("", "prompt-maker-*.md")
+	if err != nil {
+		return err
+	}
+
+	path := f.Name()
+	defer os.Remove(path)
+
+	_, err = f.WriteString(e.text)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	cmd := externalEditorCommand(path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = e.stdin, e.stdout, e.stderr
+
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	e.text = string(data)
+
+	return nil
+}
+
+// openExternalEditorCmd suspends the TUI and edits text in the external
+// editor, reporting the saved text to the session in tab tabID once it exits.
+func openExternalEditorCmd(text string, tabID int) tea.Cmd {
+	edit := &externalEdit{text: text}
+
+	return tea.Exec(edit, func(err error) tea.Msg {
+		if err != nil {
+			return tagExecMsg(tabID, externalEditedMsg{err: err})
+		}
+
+		return tagExecMsg(tabID, externalEditedMsg{text: edit.text})
+	})
+}
+
+// openExternalEditor edits the input, or the crafted prompt when the input is
+// empty, in the external editor.
+func (m *model) openExternalEditor() (tea.Model, tea.Cmd) {
+	return m, openExternalEditorCmd(cmp.Or(m.editor.Value(), m.craftedPrompt), m.tabID)
+}
+
+// handleExternalEdited puts the edited text in the editor, ready to submit.
+func (m *model) handleExternalEdited(msg externalEditedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, func() tea.Msg { return statusMessage(fmt.Sprintf("Editor failed: %v", msg.err)) }
+	}
+
+	m.editor.SetValue(strings.TrimRight(msg.text, "\n"))
+
+	return m, m.editor.Focus()
+}
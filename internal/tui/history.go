@@ -0,0 +1,317 @@
+package tui
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"prompt-maker/internal/history"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// historyListShare is the fraction of the width given to the entry list;
+	// the preview takes the rest.
+	historyListShare = 0.4
+	// historyGap separates the list from the preview.
+	historyGap = 2
+	// historyTimeLayout formats entry times in the list.
+	historyTimeLayout = "Jan 2 15:04"
+)
+
+// historyLoadedMsg carries the entries read for the history browser.
+type historyLoadedMsg struct {
+	entries []history.Entry
+	err     error
+}
+
+// historyItem adapts a history entry to the list.
+type historyItem struct {
+	entry history.Entry
+}
+
+func (i historyItem) Title() string {
+	return cmp.Or(i.entry.Title(), "(empty)")
+}
+
+func (i historyItem) Description() string {
+	parts := []string{i.entry.CreatedAt.Local().Format(historyTimeLayout)}
+
+	if model := i.entry.Model(); model != "" {
+		parts = append(parts, model)
+	}
+
+	if i.entry.Persona != "" {
+		parts = append(parts, i.entry.Persona)
+	}
+
+	if i.entry.Answer != "" {
+		parts = append(parts, "answered")
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// FilterValue lets fuzzy filtering match the input, the prompts and the model.
+func (i historyItem) FilterValue() string {
+	return strings.Join([]string{i.entry.Input, i.entry.CraftedPrompt, i.entry.ExecutedPrompt, i.entry.Model()}, " ")
+}
+
+// newHistoryList creates the list used by the history browser, filtered
+// with the key bound to filter.
+func newHistoryList(filter key.Binding) list.Model {
+	l := list.New(nil, list.NewDefaultDelegate(), initialViewportWidth, initialViewportHeight)
+	l.Title = "History"
+	l.SetShowHelp(false)
+	l.SetStatusBarItemName("entry", "entries")
+	l.KeyMap.Quit.SetEnabled(false)
+	l.KeyMap.Filter = filter
+
+	return l
+}
+
+// loadHistoryCmd reads the history store off the Update goroutine.
+func loadHistoryCmd(ctx context.Context, store history.Store) tea.Cmd {
+	return func() tea.Msg {
+		entries, err := store.List(ctx)
+
+		return historyLoadedMsg{entries: entries, err: err}
+	}
+}
+
+// openHistory switches to the history browser, remembering the state to
+// return to.
+func (m *model) openHistory() (tea.Model, tea.Cmd) {
+	if m.recorder == nil {
+		return m, func() tea.Msg { return statusMessage("History is disabled.") }
+	}
+
+	m.historyReturnState = m.state
+	m.state = viewHistory
+	m.sizeBrowsers()
+
+	return m, loadHistoryCmd(m.ctx, m.recorder.Store())
+}
+
+// closeHistory returns to the state the browser was opened from.
+func (m *model) closeHistory() (tea.Model, tea.Cmd) {
+	m.state = m.historyReturnState
+	m.historyList.ResetFilter()
+
+	return m, nil
+}
+
+func (m *model) updateHistory(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case historyLoadedMsg:
+		return m.handleHistoryLoaded(msg)
+	case statusMessage, clearStatusMsg:
+		model, cmd, _ := m.handleCommonMsg(msg)
+
+		return model, cmd
+	case tea.KeyMsg:
+		// While the filter is being typed, every key belongs to the list.
+		if m.historyList.FilterState() != list.Filtering {
+			if model, cmd, ok := m.handleHistoryKey(msg); ok {
+				return model, cmd
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+
+	m.historyList, cmd = m.historyList.Update(msg)
+	m.updateHistoryPreview()
+
+	return m, cmd
+}
+
+func (m *model) handleHistoryLoaded(msg historyLoadedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.state = m.historyReturnState
+
+		return m, func() tea.Msg { return statusMessage("Could not read history: " + msg.err.Error()) }
+	}
+
+	items := make([]list.Item, len(msg.entries))
+	for i, e := range msg.entries {
+		items[i] = historyItem{entry: e}
+	}
+
+	cmd := m.historyList.SetItems(items)
+	m.historyList.Select(0)
+	m.updateHistoryPreview()
+
+	return m, cmd
+}
+
+// handleHistoryKey runs the browser actions on the selected entry.
+func (m *model) handleHistoryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	if key.Matches(msg, m.keys.Back) {
+		if m.historyList.FilterState() == list.FilterApplied {
+			m.historyList.ResetFilter()
+			m.updateHistoryPreview()
+
+			return m, nil, true
+		}
+
+		model, cmd := m.closeHistory()
+
+		return model, cmd, true
+	}
+
+	item, ok := m.historyList.SelectedItem().(historyItem)
+	if !ok {
+		return nil, nil, false
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.CopyEntry):
+		return m, copyToClipboardCmd(cmp.Or(item.entry.Answer, item.entry.CraftedPrompt, item.entry.ExecutedPrompt)), true
+	case key.Matches(msg, m.keys.OpenEntry):
+		model, cmd := m.loadHistoryEntry(item.entry)
+
+		return model, cmd, true
+	case key.Matches(msg, m.keys.RunEntry):
+		model, cmd := m.reexecuteHistoryEntry(item.entry)
+
+		return model, cmd, true
+	}
+
+	return nil, nil, false
+}
+
+// loadHistoryEntry shows the entry's crafted prompt and puts it in the input
+// for editing; Enter then executes the edited prompt.
+func (m *model) loadHistoryEntry(e history.Entry) (tea.Model, tea.Cmd) {
+	text := cmp.Or(e.CraftedPrompt, e.ExecutedPrompt)
+
+	m.resetToReady()
+	m.historyList.ResetFilter()
+	m.craftedPrompt = text
+	m.entryID = e.ID
+	m.rawViewportContent = text
+	m.editor.SetValue(text)
+	m.renderViewport()
+	m.viewport.GotoTop()
+
+	return m, m.editor.Focus()
+}
+
+// reexecuteHistoryEntry sends the entry's prompt for a fresh answer.
+func (m *model) reexecuteHistoryEntry(e history.Entry) (tea.Model, tea.Cmd) {
+	text := cmp.Or(e.ExecutedPrompt, e.CraftedPrompt)
+	if text == "" {
+		return m, func() tea.Msg { return statusMessage("This entry has no prompt to execute.") }
+	}
+
+	m.resetToReady()
+	m.historyList.ResetFilter()
+	m.craftedPrompt = text
+	m.entryID = e.ID
+
+	return m.resubmitPrompt()
+}
+
+// updateHistoryPreview shows the selected entry in the preview pane.
+func (m *model) updateHistoryPreview() {
+	item, ok := m.historyList.SelectedItem().(historyItem)
+	if !ok {
+		m.historyPreview.SetContent("No history yet.")
+
+		return
+	}
+
+	width := max(1, m.historyPreview.Width)
+	m.historyPreview.SetContent(lipgloss.NewStyle().Width(width).Render(historyPreviewText(item.entry)))
+	m.historyPreview.GotoTop()
+}
+
+// historyPreviewText lays out every part of an entry as plain text.
+func historyPreviewText(e history.Entry) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s · %s", e.CreatedAt.Local().Format(time.DateTime), e.Source)
+
+	if model := e.Model(); model != "" {
+		fmt.Fprintf(&b, " · %s", model)
+	}
+
+	if len(e.Attachments) > 0 {
+		fmt.Fprintf(&b, "\nAttached: %s", strings.Join(e.Attachments, ", "))
+	}
+
+	for _, section := range []struct{ title, text string }{
+		{"Input", e.Input},
+		{"Crafted prompt", e.CraftedPrompt},
+		{"Executed prompt", executedIfEdited(e)},
+		{"Answer", e.Answer},
+	} {
+		if section.text != "" {
+			fmt.Fprintf(&b, "\n\n── %s ──\n%s", section.title, strings.TrimSpace(section.text))
+		}
+	}
+
+	return b.String()
+}
+
+// executedIfEdited returns the executed prompt when it differs from the
+// crafted one.
+func executedIfEdited(e history.Entry) string {
+	if e.ExecutedPrompt == e.CraftedPrompt {
+		return ""
+	}
+
+	return e.ExecutedPrompt
+}
+
+// historyView lays out the list and the preview side by side.
+func (m *model) historyView() string {
+	return browserView(m.historyList, m.historyPreview)
+}
+
+// sizeBrowser fits a browser list and its preview to the space between the
+// header and the footer, calling refresh to re-render the preview when its
+// size changes.
+func (m *model) sizeBrowser(l *list.Model, preview *viewport.Model, refresh func()) {
+	height := max(1, m.height-lipgloss.Height(m.headerView())-lipgloss.Height(m.footerView()))
+	listWidth := int(float64(m.width) * historyListShare)
+	previewWidth := max(1, m.width-listWidth-historyGap-(horizontalPadding*2))
+
+	l.SetSize(listWidth, height)
+
+	if preview.Width != previewWidth || preview.Height != height {
+		preview.Width = previewWidth
+		preview.Height = height
+		refresh()
+	}
+}
+
+// sizeBrowsers fits the browser shown, if any, to the window once its size
+// is known.
+func (m *model) sizeBrowsers() {
+	if m.width == 0 {
+		return
+	}
+
+	switch m.state { //nolint:exhaustive // Only the browsers are sized here.
+	case viewHistory:
+		m.sizeBrowser(&m.historyList, &m.historyPreview, m.updateHistoryPreview)
+	case viewLibrary:
+		m.sizeBrowser(&m.libraryList, &m.libraryPreview, m.updateLibraryPreview)
+	}
+}
+
+// browserView lays out a browser list and its preview side by side.
+func browserView(l list.Model, preview viewport.Model) string {
+	gap := strings.Repeat(" ", historyGap)
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, l.View(), gap, preview.View())
+}
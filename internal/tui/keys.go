@@ -0,0 +1,343 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"prompt-maker/internal/tui/components"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// keyMap binds the actions of the views to keys. The bindings from Back on
+// act in the overlays: the browsers, the pickers, the palette and the prompts
+// for a name, file, note or placeholder value.
+type keyMap struct {
+	Submit      key.Binding
+	Cancel      key.Binding
+	Execute     key.Binding
+	Edit        key.Binding
+	NewPrompt   key.Binding
+	ResetPrompt key.Binding
+	StartOver   key.Binding
+	EndChat     key.Binding
+	Copy        key.Binding
+	Raw         key.Binding
+	Thoughts    key.Binding
+	FollowUp    key.Binding
+	Rerun       key.Binding
+	PrevStage   key.Binding
+	NextStage   key.Binding
+	RateUp      key.Binding
+	RateDown    key.Binding
+	Model       key.Binding
+	Editor      key.Binding
+	History     key.Binding
+	Library     key.Binding
+	Save        key.Binding
+	Export      key.Binding
+	NewTab      key.Binding
+	CloseTab    key.Binding
+	NextTab     key.Binding
+	PrevTab     key.Binding
+	GoToTab     key.Binding
+	Palette     key.Binding
+	Help        key.Binding
+	Quit        key.Binding
+	ForceQuit   key.Binding
+	Back        key.Binding
+	Confirm     key.Binding
+	OpenEntry   key.Binding
+	RunEntry    key.Binding
+	CopyEntry   key.Binding
+	Diff        key.Binding
+	Filter      key.Binding
+	OtherStep   key.Binding
+	NextField   key.Binding
+	PrevField   key.Binding
+	Up          key.Binding
+	Down        key.Binding
+}
+
+// defaultKeyMap returns the keys used unless the config file rebinds them.
+func defaultKeyMap() keyMap {
+	return keyMap{
+		// Most terminals send ctrl+enter as ctrl+j.
+		Submit:      key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"), key.WithHelp("alt+enter", "submit")),
+		Cancel:      key.NewBinding(key.WithKeys("ctrl+x"), key.WithHelp("ctrl+x", "cancel")),
+		Execute:     key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "execute")),
+		Edit:        key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit")),
+		NewPrompt:   key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "new prompt")),
+		ResetPrompt: key.NewBinding(key.WithKeys("ctrl+z"), key.WithHelp("ctrl+z", "reset to original")),
+		StartOver:   key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "new prompt")),
+		EndChat:     key.NewBinding(key.WithKeys("ctrl+n"), key.WithHelp("ctrl+n", "new prompt")),
+		Copy:        key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy")),
+		Raw:         key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "raw")),
+		Thoughts:    key.NewBinding(key.WithKeys("ctrl+t"), key.WithHelp("ctrl+t", "thoughts")),
+		FollowUp:    key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "follow up")),
+		Rerun:       key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "other model")),
+		PrevStage:   key.NewBinding(key.WithKeys("["), key.WithHelp("[", "previous stage")),
+		NextStage:   key.NewBinding(key.WithKeys("]"), key.WithHelp("]", "next stage")),
+		RateUp:      key.NewBinding(key.WithKeys("+"), key.WithHelp("+", "rate up")),
+		RateDown:    key.NewBinding(key.WithKeys("-"), key.WithHelp("-", "rate down")),
+		Model:       key.NewBinding(key.WithKeys("ctrl+g"), key.WithHelp("ctrl+g", "model")),
+		Editor:      key.NewBinding(key.WithKeys("ctrl+o"), key.WithHelp("ctrl+o", "$EDITOR")),
+		History:     key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "history")),
+		Library:     key.NewBinding(key.WithKeys("ctrl+l"), key.WithHelp("ctrl+l", "library")),
+		Save:        key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "save")),
+		Export:      key.NewBinding(key.WithKeys("ctrl+e"), key.WithHelp("ctrl+e", "export")),
+		NewTab:      key.NewBinding(key.WithKeys("alt+n"), key.WithHelp("alt+n", "new tab")),
+		CloseTab:    key.NewBinding(key.WithKeys("alt+w"), key.WithHelp("alt+w", "close tab")),
+		NextTab:     key.NewBinding(key.WithKeys("alt+.", "ctrl+pgdown"), key.WithHelp("alt+.", "next tab")),
+		PrevTab:     key.NewBinding(key.WithKeys("alt+,", "ctrl+pgup"), key.WithHelp("alt+,", "previous tab")),
+		Palette:     key.NewBinding(key.WithKeys("ctrl+p"), key.WithHelp("ctrl+p", "commands")),
+		Help:        key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "more keys")),
+		Quit:        key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "quit")),
+		ForceQuit:   key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit")),
+		Back:        key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+		Confirm:     key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "confirm")),
+		OpenEntry:   key.NewBinding(key.WithKeys("enter", "e"), key.WithHelp("enter/e", "edit prompt")),
+		RunEntry:    key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "execute")),
+		CopyEntry:   key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy")),
+		Diff:        key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "diff")),
+		Filter:      key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter")),
+		OtherStep:   key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "other step")),
+		NextField:   key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next")),
+		PrevField:   key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "back")),
+		Up:          key.NewBinding(key.WithKeys("up"), key.WithHelp("↑", "up")),
+		Down:        key.NewBinding(key.WithKeys("down"), key.WithHelp("↓", "down")),
+		GoToTab: key.NewBinding(
+			key.WithKeys("alt+1", "alt+2", "alt+3", "alt+4", "alt+5", "alt+6", "alt+7", "alt+8", "alt+9"),
+			key.WithHelp("alt+1-9", "go to tab"),
+		),
+	}
+}
+
+// named maps the action names used in the config file to the bindings.
+func (k *keyMap) named() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"submit":       &k.Submit,
+		"cancel":       &k.Cancel,
+		"execute":      &k.Execute,
+		"edit":         &k.Edit,
+		"new_prompt":   &k.NewPrompt,
+		"reset_prompt": &k.ResetPrompt,
+		"start_over":   &k.StartOver,
+		"end_chat":     &k.EndChat,
+		"copy":         &k.Copy,
+		"raw":          &k.Raw,
+		"thoughts":     &k.Thoughts,
+		"follow_up":    &k.FollowUp,
+		"rerun":        &k.Rerun,
+		"prev_stage":   &k.PrevStage,
+		"next_stage":   &k.NextStage,
+		"rate_up":      &k.RateUp,
+		"rate_down":    &k.RateDown,
+		"model":        &k.Model,
+		"editor":       &k.Editor,
+		"history":      &k.History,
+		"library":      &k.Library,
+		"save":         &k.Save,
+		"export":       &k.Export,
+		"new_tab":      &k.NewTab,
+		"close_tab":    &k.CloseTab,
+		"next_tab":     &k.NextTab,
+		"prev_tab":     &k.PrevTab,
+		"go_to_tab":    &k.GoToTab,
+		"palette":      &k.Palette,
+		"help":         &k.Help,
+		"quit":         &k.Quit,
+		"force_quit":   &k.ForceQuit,
+		"back":         &k.Back,
+		"confirm":      &k.Confirm,
+		"open_entry":   &k.OpenEntry,
+		"run_entry":    &k.RunEntry,
+		"copy_entry":   &k.CopyEntry,
+		"diff":         &k.Diff,
+		"filter":       &k.Filter,
+		"other_step":   &k.OtherStep,
+		"next_field":   &k.NextField,
+		"prev_field":   &k.PrevField,
+		"up":           &k.Up,
+		"down":         &k.Down,
+	}
+}
+
+// newKeyMap returns the default keys with those in overrides rebound.
+// Unknown actions and empty key lists are ignored; ValidateKeys reports them.
+func newKeyMap(overrides map[string][]string) keyMap {
+	k := defaultKeyMap()
+	bindings := k.named()
+
+	for name, keys := range overrides {
+		if b, ok := bindings[name]; ok && len(keys) > 0 {
+			b.SetKeys(keys...)
+			b.SetHelp(strings.Join(keys, "/"), b.Help().Desc)
+		}
+	}
+
+	return k
+}
+
+// ValidateKeys checks that every action in overrides exists and is bound to
+// at least one key.
+func ValidateKeys(overrides map[string][]string) error {
+	k := defaultKeyMap()
+	bindings := k.named()
+
+	for name, keys := range overrides {
+		if _, ok := bindings[name]; !ok {
+			names := make([]string, 0, len(bindings))
+			for n := range bindings {
+				names = append(names, n)
+			}
+
+			slices.Sort(names)
+
+			return fmt.Errorf("%w %q; available: %s", errUnknownKeyAction, name, strings.Join(names, ", "))
+		}
+
+		if len(keys) == 0 {
+			return fmt.Errorf("%w: %q", errNoKeys, name)
+		}
+	}
+
+	return nil
+}
+
+// newHelp creates the help that lists the keys in the status bar.
+func newHelp(styles components.Styles) help.Model {
+	h := help.New()
+	h.Styles.ShortKey = styles.HelpKey
+	h.Styles.FullKey = styles.HelpKey
+	h.Styles.ShortDesc = styles.StatusText
+	h.Styles.FullDesc = styles.StatusText
+	h.Styles.ShortSeparator = styles.StatusText
+	h.Styles.FullSeparator = styles.StatusText
+	h.Styles.Ellipsis = styles.StatusText
+
+	return h
+}
+
+// updateKeys enables the bindings that apply in the current state, so that
+// they both match keys and show in the help only when they do something.
+func (m *model) updateKeys() {
+	k := &m.keys
+	result := m.state == viewResult
+	reviewing := m.reviewingCraftedPrompt()
+	ready := m.state == viewReady
+	chat := m.state == viewChat
+	idle := m.state != viewBusy
+
+	k.Submit.SetEnabled(ready || chat)
+	k.Cancel.SetEnabled(m.state == viewBusy)
+	k.Execute.SetEnabled(reviewing)
+	k.Edit.SetEnabled(reviewing)
+	k.NewPrompt.SetEnabled(reviewing)
+	k.ResetPrompt.SetEnabled(ready && m.craftedPrompt != "")
+	k.StartOver.SetEnabled(result || m.state == viewError)
+	k.EndChat.SetEnabled(chat)
+	k.Copy.SetEnabled(result || reviewing)
+	k.Raw.SetEnabled(result || reviewing)
+	k.Thoughts.SetEnabled(m.thoughts != "" && m.state != viewError && idle)
+	k.FollowUp.SetEnabled(result && !m.showingRoughInput())
+	k.Rerun.SetEnabled((result || reviewing) && !m.showingRoughInput())
+	k.PrevStage.SetEnabled(m.browsingStages())
+	k.NextStage.SetEnabled(m.browsingStages())
+	k.RateUp.SetEnabled(m.canRate())
+	k.RateDown.SetEnabled(m.canRate())
+	k.Model.SetEnabled(ready || result || chat)
+	k.Editor.SetEnabled(ready || chat)
+	k.History.SetEnabled(m.recorder != nil && idle)
+	k.Library.SetEnabled(m.library != nil && idle)
+	k.Save.SetEnabled(ready && m.library != nil && m.craftedPrompt != "")
+	k.Export.SetEnabled(m.recorder != nil && idle)
+	k.Palette.SetEnabled(idle)
+	// While the editor takes text, ? is typed rather than showing the keys.
+	k.Help.SetEnabled(result || reviewing || m.state == viewError)
+}
+
+// ShortHelp lists the keys shown in the status bar.
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		k.Submit, k.Cancel, k.Execute, k.Rerun, k.Edit, k.NewPrompt, k.ResetPrompt, k.FollowUp, k.EndChat, k.StartOver,
+		k.Copy, k.Save, k.Raw, k.RateUp, k.RateDown, k.PrevStage, k.NextStage, k.Thoughts,
+		k.Help, k.Palette, k.Model, k.Quit,
+	}
+}
+
+// FullHelp lists every key, grouped by what they act on, for the ? view.
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Submit, k.Cancel, k.Execute, k.Edit, k.NewPrompt, k.ResetPrompt, k.StartOver, k.EndChat},
+		{k.Copy, k.Raw, k.Thoughts, k.FollowUp, k.Rerun, k.PrevStage, k.NextStage, k.RateUp, k.RateDown},
+		{k.Model, k.Editor, k.History, k.Library, k.Save, k.Export},
+		{k.NewTab, k.CloseTab, k.NextTab, k.PrevTab, k.GoToTab},
+		{k.Palette, k.Help, k.Quit, k.ForceQuit},
+	}
+}
+
+// overlayKeys lists the keys of an overlay for the status bar.
+type overlayKeys []key.Binding
+
+func (k overlayKeys) ShortHelp() []key.Binding {
+	return k
+}
+
+// FullHelp keeps the keys on one line, since an overlay has only a few.
+func (k overlayKeys) FullHelp() [][]key.Binding {
+	groups := make([][]key.Binding, len(k))
+	for i, b := range k {
+		groups[i] = []key.Binding{b}
+	}
+
+	return groups
+}
+
+// relabel returns b described as desc, for the overlays where its action
+// reads differently.
+func relabel(b key.Binding, desc string) key.Binding {
+	b.SetHelp(b.Help().Key, desc)
+
+	return b
+}
+
+// overlayHelp returns what the overlay shown asks for, when that needs
+// saying, and the keys it takes.
+func (m *model) overlayHelp() (string, overlayKeys) {
+	k := m.keys
+	cancel := relabel(k.Back, "cancel")
+
+	switch m.state { //nolint:exhaustive // The other states are not overlays.
+	case viewHistory:
+		return "", overlayKeys{k.OpenEntry, relabel(k.RunEntry, "re-execute"), k.CopyEntry, k.Filter, k.Back}
+	case viewLibrary:
+		return "", overlayKeys{k.OpenEntry, k.RunEntry, k.Diff, k.CopyEntry, k.Filter, k.Back}
+	case viewSaving:
+		return "", overlayKeys{relabel(k.Confirm, "save"), cancel}
+	case viewExporting:
+		return "", overlayKeys{relabel(k.Confirm, "export (.md, .html or .json)"), cancel}
+	case viewRating:
+		return fmt.Sprintf("Rating the %s %s", m.ratingTarget, m.pendingRating), overlayKeys{relabel(k.Confirm, "save"), cancel}
+	case viewFilling:
+		return "Fill in the placeholders", overlayKeys{
+			relabel(k.Confirm, "next"), k.NextField, k.PrevField,
+			relabel(k.Up, "previous value"), relabel(k.Down, "next value"), relabel(k.Back, "edit the prompt"),
+		}
+	case viewPalette:
+		return "", overlayKeys{relabel(k.Confirm, "run"), k.Up, k.Down, relabel(k.Back, "close")}
+	case viewPickingModel:
+		if m.rerunPrompt != "" {
+			return "", overlayKeys{relabel(k.Confirm, "execute with this model"), k.Filter, cancel}
+		}
+
+		return "", overlayKeys{
+			relabel(k.Confirm, fmt.Sprintf("use for %s", m.pickerStep)),
+			relabel(k.OtherStep, fmt.Sprintf("%s instead", m.pickerStep.other())), k.Filter, cancel,
+		}
+	default:
+		return "", nil
+	}
+}
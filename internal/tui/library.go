@@ -0,0 +1,376 @@
+package tui
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"prompt-maker/internal/library"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// placeholderSaveName explains the input of the save prompt.
+	placeholderSaveName = "Name for the library, e.g. release-notes #docs #team"
+	// minDiffRevisions is the number of revisions a diff compares.
+	minDiffRevisions = 2
+)
+
+// libraryLoadedMsg carries the prompts read for the library browser.
+type libraryLoadedMsg struct {
+	prompts []library.Prompt
+	err     error
+}
+
+// librarySavedMsg reports the outcome of saving a crafted prompt.
+type librarySavedMsg struct {
+	prompt library.Prompt
+	err    error
+}
+
+// libraryItem adapts a library prompt to the list.
+type libraryItem struct {
+	prompt library.Prompt
+}
+
+func (i libraryItem) Title() string {
+	return i.prompt.Name
+}
+
+func (i libraryItem) Description() string {
+	parts := []string{fmt.Sprintf("v%d", i.prompt.Latest().Version)}
+
+	if len(i.prompt.Tags) > 0 {
+		parts = append(parts, "#"+strings.Join(i.prompt.Tags, " #"))
+	}
+
+	if i.prompt.Description != "" {
+		parts = append(parts, i.prompt.Description)
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// FilterValue lets fuzzy filtering match the name, tags, description and text.
+func (i libraryItem) FilterValue() string {
+	return strings.Join([]string{i.prompt.Name, strings.Join(i.prompt.Tags, " "), i.prompt.Description, i.prompt.Text()}, " ")
+}
+
+// newLibraryList creates the list used by the library browser, filtered
+// with the key bound to filter.
+func newLibraryList(filter key.Binding) list.Model {
+	l := list.New(nil, list.NewDefaultDelegate(), initialViewportWidth, initialViewportHeight)
+	l.Title = "Library"
+	l.SetShowHelp(false)
+	l.SetStatusBarItemName("prompt", "prompts")
+	l.KeyMap.Quit.SetEnabled(false)
+	l.KeyMap.Filter = filter
+
+	return l
+}
+
+// newSaveInput creates the input that names a prompt being saved.
+func newSaveInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = placeholderSaveName
+	ti.Prompt = "Save as: "
+
+	return ti
+}
+
+// loadLibraryCmd reads the library off the Update goroutine.
+func loadLibraryCmd(ctx context.Context, lib *library.Library) tea.Cmd {
+	return func() tea.Msg {
+		prompts, err := lib.List(ctx)
+
+		return libraryLoadedMsg{prompts: prompts, err: err}
+	}
+}
+
+// saveToLibraryCmd saves text as a revision of the prompt named in input.
+func saveToLibraryCmd(ctx context.Context, lib *library.Library, input, text, entryID string) tea.Cmd {
+	return func() tea.Msg {
+		name, tags := parseSaveInput(input)
+		p, err := lib.Save(ctx, name, text, library.SaveOptions{Tags: tags, EntryID: entryID})
+
+		return librarySavedMsg{prompt: p, err: err}
+	}
+}
+
+// parseSaveInput splits "release notes #docs #team" into the name and the
+// tags. Without tags, the tags of an existing prompt are kept.
+func parseSaveInput(input string) (string, []string) {
+	var (
+		name []string
+		tags []string
+	)
+
+	for field := range strings.FieldsSeq(input) {
+		tag, ok := strings.CutPrefix(field, "#")
+		if !ok {
+			name = append(name, field)
+
+			continue
+		}
+
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return strings.Join(name, " "), tags
+}
+
+// openSave asks for the name to save the crafted prompt under.
+func (m *model) openSave() (tea.Model, tea.Cmd) {
+	if m.library == nil {
+		return m, func() tea.Msg { return statusMessage("The library is disabled.") }
+	}
+
+	m.state = viewSaving
+	m.saveInput.Reset()
+
+	return m, m.saveInput.Focus()
+}
+
+// closeSave returns to the crafted prompt.
+func (m *model) closeSave() (tea.Model, tea.Cmd) {
+	m.state = viewReady
+	m.saveInput.Blur()
+
+	return m, nil
+}
+
+func (m *model) updateSaving(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case librarySavedMsg:
+		return m.handleLibrarySaved(msg)
+	case statusMessage, clearStatusMsg:
+		model, cmd, _ := m.handleCommonMsg(msg)
+
+		return model, cmd
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Back):
+			return m.closeSave()
+		case key.Matches(msg, m.keys.Confirm):
+			if name, _ := parseSaveInput(m.saveInput.Value()); name == "" {
+				return m, func() tea.Msg { return statusMessage("Enter a name to save the prompt under.") }
+			}
+
+			return m, saveToLibraryCmd(m.ctx, m.library, m.saveInput.Value(), cmp.Or(m.editor.Value(), m.craftedPrompt), m.entryID)
+		}
+	}
+
+	var cmd tea.Cmd
+
+	m.saveInput, cmd = m.saveInput.Update(msg)
+
+	return m, cmd
+}
+
+func (m *model) handleLibrarySaved(msg librarySavedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, func() tea.Msg { return statusMessage("Not saved: " + msg.err.Error()) }
+	}
+
+	model, cmd := m.closeSave()
+	status := fmt.Sprintf("Saved %s v%d to the library.", msg.prompt.Name, msg.prompt.Latest().Version)
+
+	return model, tea.Batch(cmd, func() tea.Msg { return statusMessage(status) })
+}
+
+// openLibrary switches to the library browser, remembering the state to
+// return to.
+func (m *model) openLibrary() (tea.Model, tea.Cmd) {
+	if m.library == nil {
+		return m, func() tea.Msg { return statusMessage("The library is disabled.") }
+	}
+
+	m.libraryReturnState = m.state
+	m.state = viewLibrary
+	m.libraryShowDiff = false
+	m.sizeBrowsers()
+
+	return m, loadLibraryCmd(m.ctx, m.library)
+}
+
+// closeLibrary returns to the state the browser was opened from.
+func (m *model) closeLibrary() (tea.Model, tea.Cmd) {
+	m.state = m.libraryReturnState
+	m.libraryList.ResetFilter()
+
+	return m, nil
+}
+
+func (m *model) updateLibrary(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case libraryLoadedMsg:
+		return m.handleLibraryLoaded(msg)
+	case statusMessage, clearStatusMsg:
+		model, cmd, _ := m.handleCommonMsg(msg)
+
+		return model, cmd
+	case tea.KeyMsg:
+		// While the filter is being typed, every key belongs to the list.
+		if m.libraryList.FilterState() != list.Filtering {
+			if model, cmd, ok := m.handleLibraryKey(msg); ok {
+				return model, cmd
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+
+	m.libraryList, cmd = m.libraryList.Update(msg)
+	m.updateLibraryPreview()
+
+	return m, cmd
+}
+
+func (m *model) handleLibraryLoaded(msg libraryLoadedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.state = m.libraryReturnState
+
+		return m, func() tea.Msg { return statusMessage("Could not read the library: " + msg.err.Error()) }
+	}
+
+	items := make([]list.Item, len(msg.prompts))
+	for i, p := range msg.prompts {
+		items[i] = libraryItem{prompt: p}
+	}
+
+	cmd := m.libraryList.SetItems(items)
+	m.libraryList.Select(0)
+	m.updateLibraryPreview()
+
+	return m, cmd
+}
+
+// handleLibraryKey runs the browser actions on the selected prompt.
+func (m *model) handleLibraryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	if key.Matches(msg, m.keys.Back) {
+		if m.libraryList.FilterState() == list.FilterApplied {
+			m.libraryList.ResetFilter()
+			m.updateLibraryPreview()
+
+			return m, nil, true
+		}
+
+		model, cmd := m.closeLibrary()
+
+		return model, cmd, true
+	}
+
+	item, ok := m.libraryList.SelectedItem().(libraryItem)
+	if !ok {
+		return nil, nil, false
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.CopyEntry):
+		return m, copyToClipboardCmd(item.prompt.Text()), true
+	case key.Matches(msg, m.keys.Diff):
+		m.libraryShowDiff = !m.libraryShowDiff
+		m.updateLibraryPreview()
+
+		return m, nil, true
+	case key.Matches(msg, m.keys.OpenEntry):
+		model, cmd := m.loadLibraryPrompt(item.prompt)
+
+		return model, cmd, true
+	case key.Matches(msg, m.keys.RunEntry):
+		m.loadLibraryPrompt(item.prompt)
+
+		model, cmd := m.resubmitPrompt()
+
+		return model, cmd, true
+	}
+
+	return nil, nil, false
+}
+
+// loadLibraryPrompt shows the newest text of p and puts it in the input for
+// editing; Enter then executes the edited prompt.
+func (m *model) loadLibraryPrompt(p library.Prompt) (tea.Model, tea.Cmd) {
+	text := p.Text()
+
+	m.resetToReady()
+	m.libraryList.ResetFilter()
+	m.craftedPrompt = text
+	m.rawViewportContent = text
+	m.editor.SetValue(text)
+	m.renderViewport()
+	m.viewport.GotoTop()
+
+	return m, m.editor.Focus()
+}
+
+// updateLibraryPreview shows the selected prompt, or the diff of its last
+// two revisions, in the preview pane.
+func (m *model) updateLibraryPreview() {
+	item, ok := m.libraryList.SelectedItem().(libraryItem)
+	if !ok {
+		m.libraryPreview.SetContent("The library is empty. Press ctrl+s on a crafted prompt to save it.")
+
+		return
+	}
+
+	text := libraryPreviewText(item.prompt)
+	if m.libraryShowDiff {
+		text = libraryDiffText(item.prompt)
+	}
+
+	width := max(1, m.libraryPreview.Width)
+	m.libraryPreview.SetContent(lipgloss.NewStyle().Width(width).Render(text))
+	m.libraryPreview.GotoTop()
+}
+
+// libraryPreviewText lays out the metadata, revisions and text of a prompt.
+func libraryPreviewText(p library.Prompt) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s · v%d · updated %s", p.Name, p.Latest().Version, p.UpdatedAt.Local().Format(time.DateTime))
+
+	if len(p.Tags) > 0 {
+		fmt.Fprintf(&b, "\nTags: %s", strings.Join(p.Tags, ", "))
+	}
+
+	if vars := library.Variables(p.Text()); len(vars) > 0 {
+		fmt.Fprintf(&b, "\nVariables: %s", strings.Join(vars, ", "))
+	}
+
+	if p.Description != "" {
+		fmt.Fprintf(&b, "\n%s", p.Description)
+	}
+
+	fmt.Fprintf(&b, "\n\n── Prompt ──\n%s\n\n── Revisions ──", strings.TrimSpace(p.Text()))
+
+	for i := len(p.Revisions) - 1; i >= 0; i-- {
+		rev := p.Revisions[i]
+		fmt.Fprintf(&b, "\nv%d  %s  %s", rev.Version, rev.CreatedAt.Local().Format(historyTimeLayout), rev.Note)
+	}
+
+	return b.String()
+}
+
+// libraryDiffText shows what the newest revision of p changed.
+func libraryDiffText(p library.Prompt) string {
+	if len(p.Revisions) < minDiffRevisions {
+		return "Only one revision; nothing to compare."
+	}
+
+	return cmp.Or(library.Diff(p.Name, p.Revisions[len(p.Revisions)-minDiffRevisions], p.Latest()), "No changes.")
+}
+
+// libraryView lays out the list and the preview side by side.
+func (m *model) libraryView() string {
+	return browserView(m.libraryList, m.libraryPreview)
+}
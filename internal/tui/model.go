@@ -1,17 +1,28 @@
 package tui
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
+	"prompt-maker/internal/attachment"
 	"prompt-maker/internal/gemini"
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/library"
+	"prompt-maker/internal/placeholder"
+	"prompt-maker/internal/projectctx"
+	"prompt-maker/internal/prompt"
 	"prompt-maker/internal/tui/components"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -22,14 +33,57 @@ import (
 type model struct {
 	ctx                context.Context
 	cancel             context.CancelFunc
+	cancelActive       context.CancelFunc
+	tabID              int
+	requestOrigin      requestOrigin
 	state              viewState
 	modelList          list.Model
-	textInput          textinput.Model
+	editor             textarea.Model
 	spinner            spinner.Model
 	viewport           viewport.Model
 	glamourRenderer    *glamour.TermRenderer
-	chatSvc            chatCreator
+	runner             *prompt.Runner
+	recorder           *history.Recorder
+	entryID            string
+	historyList        list.Model
+	historyPreview     viewport.Model
+	historyReturnState viewState
+	library            *library.Library
+	saveInput          textinput.Model
+	libraryList        list.Model
+	libraryPreview     viewport.Model
+	libraryReturnState viewState
+	libraryShowDiff    bool
+	sessionIDs         []string
+	exportInput        textinput.Model
+	exportReturnState  viewState
+	noteInput          textinput.Model
+	pendingRating      history.Rating
+	ratingTarget       history.Target
+	ratingReturnState  viewState
+	placeholders       *placeholder.Memory
+	fillInput          textinput.Model
+	fillText           string
+	fillTokens         []string
+	fillValues         map[string]string
+	fillIndex          int
+	fillRecent         placeholder.Values
+	transcript         []prompt.Turn
+	executedPrompt     string
+	stages             []stage
+	stageIndex         int
+	rerunPrompt        string
+	pickerStep         modelStep
+	pickerReturnState  viewState
+	paletteInput       textinput.Model
+	paletteActions     []paletteAction
+	paletteMatches     []paletteAction
+	paletteCursor      int
+	paletteReturnState viewState
 	selectedModel      string
+	executeModel       string
+	requestedModel     string
+	answeredModel      string
 	appVersion         string
 	temperature        float32
 	history            string
@@ -38,14 +92,25 @@ type model struct {
 	busyText           string
 	errorMessage       string
 	statusMessage      string
+	inlineText         string
+	inlineErr          bool
 	rawViewportContent string
+	thoughts           string
+	showThoughts       bool
+	showRaw            bool
+	usage              prompt.Usage
+	attachments        []attachment.Attachment
+	contextFiles       []projectctx.File
+	contextBudget      int
 	width              int
 	height             int
+	keys               keyMap
+	help               help.Model
 	styles             components.Styles
 }
 
 // New creates and returns a new TUI model configured with the given chat service and options.
-func New(ctx context.Context, chatSvc chatCreator, version, modelName, history string, temperature float32) tea.Model {
+func New(ctx context.Context, chatSvc gemini.ChatCreator, opts Options) tea.Model {
 	ctx, cancel := context.WithCancel(ctx)
 
 	// Create items for the list.
@@ -62,10 +127,10 @@ func New(ctx context.Context, chatSvc chatCreator, version, modelName, history s
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(false)
 
-	ti := textinput.New()
-	ti.Placeholder = placeholderRoughPrompt
-	ti.Focus()
-	ti.CharLimit = textInputCharLimit
+	keys := newKeyMap(opts.Keys)
+	l.KeyMap.Filter = keys.Filter
+
+	ta := newEditor(cmp.Or(opts.InputCharLimit, DefaultInputCharLimit))
 
 	s := spinner.New()
 	s.Spinner = spinner.Dot
@@ -78,8 +143,14 @@ func New(ctx context.Context, chatSvc chatCreator, version, modelName, history s
 		renderer = nil // graceful fallback: raw markdown will be shown
 	}
 
+	runner := prompt.NewRunner(chatSvc, opts.Fallbacks, gemini.GenerationOptions{
+		Temperature:     opts.Temperature,
+		ThinkingBudget:  opts.ThinkingBudget,
+		IncludeThoughts: opts.ShowThoughts,
+	})
+
 	initialState := viewSelectingModel
-	if modelName != "" {
+	if opts.Model != "" {
 		initialState = viewReady
 	}
 
@@ -88,15 +159,33 @@ func New(ctx context.Context, chatSvc chatCreator, version, modelName, history s
 		cancel:          cancel,
 		state:           initialState,
 		modelList:       l,
-		textInput:       ti,
+		editor:          ta,
 		spinner:         s,
 		viewport:        vp,
 		glamourRenderer: renderer,
-		chatSvc:         chatSvc,
-		appVersion:      version,
-		selectedModel:   modelName,
-		temperature:     temperature,
-		history:         history,
+		runner:          runner,
+		recorder:        opts.Recorder,
+		historyList:     newHistoryList(keys.Filter),
+		historyPreview:  viewport.New(initialViewportWidth, initialViewportHeight),
+		library:         opts.Library,
+		saveInput:       newSaveInput(),
+		libraryList:     newLibraryList(keys.Filter),
+		libraryPreview:  viewport.New(initialViewportWidth, initialViewportHeight),
+		exportInput:     newExportInput(),
+		noteInput:       newNoteInput(),
+		placeholders:    opts.Placeholders,
+		fillInput:       newFillInput(),
+		paletteInput:    newPaletteInput(),
+		appVersion:      opts.Version,
+		selectedModel:   opts.Model,
+		executeModel:    opts.ExecuteModel,
+		temperature:     opts.Temperature,
+		history:         opts.History,
+		attachments:     opts.Attachments,
+		contextFiles:    opts.Context,
+		contextBudget:   opts.ContextBudget,
+		keys:            keys,
+		help:            newHelp(components.NewStyles()),
 		styles:          components.NewStyles(),
 	}
 }
@@ -112,9 +201,12 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		return m.handleWindowSize(msg)
+	case canceledMsg:
+		// The outcome of a canceled request is dropped in any state.
+		return m, nil
 	case tea.KeyMsg:
-		// Global quit works in any state.
-		if msg.Type == tea.KeyCtrlC || msg.Type == tea.KeyEsc {
+		// Global quit works in any state; the overlays take the quit key to go back instead.
+		if key.Matches(msg, m.keys.ForceQuit) || (key.Matches(msg, m.keys.Quit) && !m.state.isOverlay()) {
 			m.cancel()
 			m.quitting = true
 
@@ -134,6 +226,24 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateResult(msg)
 	case viewError:
 		return m.updateError(msg)
+	case viewHistory:
+		return m.updateHistory(msg)
+	case viewSaving:
+		return m.updateSaving(msg)
+	case viewLibrary:
+		return m.updateLibrary(msg)
+	case viewExporting:
+		return m.updateExporting(msg)
+	case viewRating:
+		return m.updateRating(msg)
+	case viewFilling:
+		return m.updateFilling(msg)
+	case viewChat:
+		return m.updateChat(msg)
+	case viewPickingModel:
+		return m.updatePickingModel(msg)
+	case viewPalette:
+		return m.updatePalette(msg)
 	default:
 		return m, nil
 	}
@@ -161,7 +271,7 @@ func (m *model) View() string {
 
 	m.viewport.Height = m.height - headerHeight - footerHeight
 	m.viewport.Width = m.width
-	m.textInput.Width = m.width - (horizontalPadding * 2)
+	m.editor.SetWidth(m.width - (horizontalPadding * 2))
 
 	mainContent := m.styles.MainContent.Render(m.mainContentView())
 
@@ -170,7 +280,7 @@ func (m *model) View() string {
 
 // updateModelSelection handles logic for the new initial view.
 func (m *model) updateModelSelection(msg tea.Msg) (tea.Model, tea.Cmd) {
-	if keyMsg, ok := msg.(tea.KeyMsg); ok && keyMsg.Type == tea.KeyEnter {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && key.Matches(keyMsg, m.keys.Confirm) {
 		if i, ok := m.modelList.SelectedItem().(components.ModelOption); ok {
 			m.selectedModel = i.Name()
 			m.state = viewReady // Transition to the main view
@@ -212,6 +322,10 @@ func (m *model) updateReady(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case aiResponseMsg:
 		return m.handleAIResponse(msg)
+	case externalEditedMsg:
+		return m.handleExternalEdited(msg)
+	case attachedMsg:
+		return m.handleAttached(msg)
 	case errMsg:
 		return m.handleError(msg)
 	}
@@ -226,9 +340,19 @@ func (m *model) updateReady(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m *model) updateBusy(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case aiResponseMsg:
+		m.finishRequest()
+
 		return m.handleAIResponse(msg)
 	case errMsg:
+		m.finishRequest()
+
 		return m.handleError(msg)
+	case tea.KeyMsg:
+		m.updateKeys()
+
+		if key.Matches(msg, m.keys.Cancel) {
+			return m.cancelRequest()
+		}
 	}
 
 	return m.updateComponents(msg)
@@ -255,6 +379,7 @@ func (m *model) handleWindowSize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 	m.height = msg.Height
 
 	m.modelList.SetWidth(msg.Width)
+	m.help.Width = msg.Width - (horizontalPadding * 2)
 
 	// Re-create the glamour renderer with the new width.
 	renderer, err := glamour.NewTermRenderer(
@@ -267,47 +392,129 @@ func (m *model) handleWindowSize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 
 	// Re-render the content with the new renderer settings.
 	if m.rawViewportContent != "" && m.glamourRenderer != nil {
-		rendered, renderErr := m.glamourRenderer.Render(m.rawViewportContent)
-		if renderErr == nil {
-			m.viewport.SetContent(rendered)
-		}
+		m.renderViewport()
 	}
 
+	m.sizeBrowsers()
+
 	return m, nil
 }
 
 func (m *model) handleAIResponse(msg aiResponseMsg) (tea.Model, tea.Cmd) {
-	var renderedContent string
-
-	if m.glamourRenderer != nil {
-		rendered, err := m.glamourRenderer.Render(msg.response)
-		if err == nil {
-			renderedContent = rendered
-		} else {
-			renderedContent = msg.response
-		}
-	} else {
-		renderedContent = msg.response
+	if msg.followUp {
+		return m.handleFollowUpResponse(msg)
 	}
 
-	if m.craftedPrompt == "" {
-		m.craftedPrompt = msg.response
-		m.textInput.Reset()
-		m.textInput.Placeholder = placeholderResubmit
-		m.rawViewportContent = msg.response
-		m.viewport.SetContent(renderedContent)
-		m.state = viewReady
+	parentID := m.entryID
+
+	m.answeredModel = msg.model
+	m.requestedModel = msg.requestedModel
+	m.thoughts = msg.thoughts
+	m.usage = msg.usage
+	m.rawViewportContent = msg.response
+	m.entryID = msg.entryID
+	m.rememberEntry(msg.entryID)
+
+	if msg.crafted {
+		m.recordCraft(msg)
+		m.showCraftedPrompt(msg.response)
 	} else {
+		m.recordAnswer(msg, parentID)
 		m.craftedPrompt = ""
-		m.rawViewportContent = msg.response
-		m.viewport.SetContent(renderedContent)
-		m.textInput.Reset()
-		m.textInput.Placeholder = placeholderNewPrompt
+		m.executedPrompt = msg.prompt
+		m.editor.Reset()
+		m.editor.Placeholder = placeholderNewPrompt
 		m.state = viewResult
 	}
 
+	m.renderViewport()
 	m.viewport.GotoTop()
 
+	if msg.historyErr != nil {
+		return m, func() tea.Msg { return statusMessage("History not saved: " + msg.historyErr.Error()) }
+	}
+
+	return m, nil
+}
+
+// showCraftedPrompt loads a crafted prompt into the editor for review. The
+// editor stays blurred, so single keys run actions until e starts editing.
+func (m *model) showCraftedPrompt(text string) {
+	m.craftedPrompt = text
+	m.editor.SetValue(text)
+	m.editor.Placeholder = placeholderResubmit
+	m.editor.Blur()
+	m.state = viewReady
+}
+
+// reviewingCraftedPrompt reports whether a crafted prompt is shown with the
+// editor blurred.
+func (m *model) reviewingCraftedPrompt() bool {
+	return m.state == viewReady && m.craftedPrompt != "" && !m.editor.Focused()
+}
+
+// editCraftedPrompt focuses the editor holding the crafted prompt.
+func (m *model) editCraftedPrompt() (tea.Model, tea.Cmd) {
+	m.editor.CursorEnd()
+
+	return m, m.editor.Focus()
+}
+
+// resetCraftedPrompt discards the edits to the crafted prompt and returns to
+// reviewing it.
+func (m *model) resetCraftedPrompt() (tea.Model, tea.Cmd) {
+	m.editor.SetValue(m.craftedPrompt)
+	m.editor.Blur()
+
+	return m, func() tea.Msg { return statusMessage("Restored the original crafted prompt.") }
+}
+
+// renderViewport renders the response, preceded by the thought summaries
+// section when the model returned any, or the chat transcript into the
+// viewport.
+func (m *model) renderViewport() {
+	if m.transcript != nil {
+		m.viewport.SetContent(m.transcriptView())
+
+		return
+	}
+
+	content := m.rawViewportContent
+
+	switch {
+	case m.thoughts == "":
+		// Nothing to prepend.
+	case m.showThoughts:
+		content = thoughtsHeading + "\n\n" + quoteMarkdown(m.thoughts) + "\n\n---\n\n" + content
+	default:
+		content = thoughtsCollapsedText + "\n\n" + content
+	}
+
+	m.viewport.SetContent(m.renderMarkdown(content))
+}
+
+// renderMarkdown renders markdown for the terminal, or returns it unchanged
+// in raw view or without a renderer.
+func (m *model) renderMarkdown(content string) string {
+	if m.glamourRenderer != nil && !m.showRaw {
+		if rendered, err := m.glamourRenderer.Render(content); err == nil {
+			return rendered
+		}
+	}
+
+	return content
+}
+
+// quoteMarkdown turns text into a markdown block quote.
+func quoteMarkdown(text string) string {
+	return "> " + strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n> ")
+}
+
+// toggleThoughts expands or collapses the thought summaries section.
+func (m *model) toggleThoughts() (tea.Model, tea.Cmd) {
+	m.showThoughts = !m.showThoughts
+	m.renderViewport()
+
 	return m, nil
 }
 
@@ -328,6 +535,8 @@ func formatError(err error) string {
 		friendlyMessage = "Oops! The prompt cannot be empty. Please enter some text."
 	case errors.Is(err, errClipboardWrite):
 		friendlyMessage = "Error: Could not write to clipboard. Please try again."
+	case errors.Is(err, prompt.ErrModelsUnavailable):
+		friendlyMessage = "All models are busy or out of quota right now. Please try again shortly."
 	default:
 		friendlyMessage = "An unexpected error occurred. Please try again."
 	}
@@ -336,53 +545,148 @@ func formatError(err error) string {
 }
 
 func (m *model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.inlineText = ""
+	m.updateKeys()
+	commandTyped := m.state == viewReady && m.editor.Focused() && strings.HasPrefix(m.editor.Value(), "/")
+
 	switch {
-	case msg.String() == "c" && (m.state == viewResult || (m.craftedPrompt != "" && m.state == viewReady)):
+	case commandTyped && msg.Type == tea.KeyTab:
+		return m.completeSlashCommand()
+	case commandTyped && (msg.Type == tea.KeyEnter || key.Matches(msg, m.keys.Submit)) && isSlashCommand(m.editor.Value()):
+		return m.runSlashCommand()
+	case key.Matches(msg, m.keys.Copy):
 		return m, copyToClipboardCmd(m.rawViewportContent)
-	case msg.String() == "r" && m.craftedPrompt != "" && m.state == viewReady:
+	case key.Matches(msg, m.keys.Execute):
 		return m.resubmitPrompt()
-	case msg.Type == tea.KeyEnter:
-		return m.handleEnterKey()
+	case key.Matches(msg, m.keys.Edit):
+		return m.editCraftedPrompt()
+	case key.Matches(msg, m.keys.NewPrompt, m.keys.EndChat, m.keys.StartOver):
+		m.resetToReady()
+
+		return m, m.editor.Focus()
+	case key.Matches(msg, m.keys.ResetPrompt):
+		return m.resetCraftedPrompt()
+	case key.Matches(msg, m.keys.Palette):
+		return m.openPalette()
+	case key.Matches(msg, m.keys.Help):
+		return m.toggleHelp()
+	case key.Matches(msg, m.keys.Raw):
+		return m.toggleRaw()
+	case key.Matches(msg, m.keys.Thoughts):
+		return m.toggleThoughts()
+	case key.Matches(msg, m.keys.History):
+		return m.openHistory()
+	case key.Matches(msg, m.keys.Save):
+		return m.openSave()
+	case key.Matches(msg, m.keys.Library):
+		return m.openLibrary()
+	case key.Matches(msg, m.keys.Export):
+		return m.openExport()
+	case key.Matches(msg, m.keys.RateUp, m.keys.RateDown):
+		target, _ := m.shownTarget()
+
+		rating := history.RatingUp
+		if key.Matches(msg, m.keys.RateDown) {
+			rating = history.RatingDown
+		}
+
+		return m.openRating(target, rating)
+	case key.Matches(msg, m.keys.Submit) && m.state == viewChat:
+		return m.askFollowUpQuestion()
+	case key.Matches(msg, m.keys.Submit):
+		return m.submitPrompt()
+	case key.Matches(msg, m.keys.Editor):
+		return m.openExternalEditor()
+	case key.Matches(msg, m.keys.FollowUp):
+		return m.openChat()
+	case key.Matches(msg, m.keys.PrevStage):
+		return m.moveStage(-1)
+	case key.Matches(msg, m.keys.NextStage):
+		return m.moveStage(1)
+	case key.Matches(msg, m.keys.Rerun):
+		return m.openRerunPicker()
+	case key.Matches(msg, m.keys.Model):
+		return m.openModelPicker(m.nextStep())
 	}
 
 	return m.updateComponents(msg)
 }
 
+// toggleHelp switches the status bar between the short and the full list of
+// keys.
+func (m *model) toggleHelp() (tea.Model, tea.Cmd) {
+	m.help.ShowAll = !m.help.ShowAll
+
+	return m, nil
+}
+
+// resubmitPrompt executes the crafted prompt as edited in the editor.
 func (m *model) resubmitPrompt() (tea.Model, tea.Cmd) {
-	m.state = viewBusy
-	m.busyText = thinkingTextGettingAnswer
+	return m.runCraftedPrompt(cmp.Or(m.editor.Value(), m.craftedPrompt))
+}
 
-	return m, tea.Batch(m.spinner.Tick, sendPromptCmd(m.ctx, m.chatSvc, m.selectedModel, m.craftedPrompt, false))
+// executePrompt sends text for the final answer.
+func (m *model) executePrompt(text string) (tea.Model, tea.Cmd) {
+	return m, m.startRequest(thinkingTextGettingAnswer, m.newRequest(text, false))
 }
 
-func (m *model) handleEnterKey() (tea.Model, tea.Cmd) {
-	switch m.state {
-	case viewReady:
-		return m.submitPrompt()
-	case viewResult, viewError:
-		m.resetToReady()
-		return m, nil
-	case viewSelectingModel, viewBusy:
-		// Do nothing in these states.
+// submitPrompt crafts the rough prompt in the editor, or executes the
+// crafted one. A doubled leading slash sends a prompt starting with one.
+func (m *model) submitPrompt() (tea.Model, tea.Cmd) {
+	userInput := m.editor.Value()
+	if strings.HasPrefix(userInput, "//") {
+		userInput = userInput[1:]
 	}
 
-	return m, nil
+	if m.craftedPrompt != "" {
+		return m.runCraftedPrompt(userInput)
+	}
+
+	return m, m.startRequest(thinkingTextCrafting, m.newRequest(userInput, true))
 }
 
-func (m *model) submitPrompt() (tea.Model, tea.Cmd) {
-	m.state = viewBusy
-	m.busyText = thinkingTextCrafting
-	userInput := m.textInput.Value()
+// newRequest snapshots the fields a prompt command needs. Crafting and
+// executing may use different models.
+func (m *model) newRequest(input string, useLyra bool) promptRequest {
+	step := stepExecute
+	if useLyra {
+		step = stepCraft
+	}
 
-	return m, tea.Batch(m.spinner.Tick, sendPromptCmd(m.ctx, m.chatSvc, m.selectedModel, userInput, m.craftedPrompt == ""))
+	return promptRequest{
+		model:       m.modelFor(step),
+		input:       input,
+		useLyra:     useLyra,
+		attachments: m.requestAttachments(input),
+		entryID:     m.entryID,
+	}
+}
+
+// requestAttachments returns the attached files followed by the project
+// context files most relevant to input.
+func (m *model) requestAttachments(input string) []attachment.Attachment {
+	if len(m.contextFiles) == 0 {
+		return m.attachments
+	}
+
+	selected := projectctx.Select(m.contextFiles, input, m.contextBudget)
+
+	return append(slices.Clone(m.attachments), projectctx.Attachments(selected)...)
 }
 
 func (m *model) resetToReady() {
 	m.state = viewReady
 	m.craftedPrompt = ""
-	m.textInput.Reset()
-	m.textInput.Placeholder = placeholderRoughPrompt
+	m.entryID = ""
+	m.editor.Reset()
+	m.editor.Placeholder = placeholderRoughPrompt
 	m.rawViewportContent = ""
+	m.thoughts = ""
+	m.usage = prompt.Usage{}
+	m.transcript = nil
+	m.executedPrompt = ""
+	m.stages = nil
+	m.stageIndex = 0
 	m.viewport.SetContent("")
 }
 
@@ -392,7 +696,7 @@ func (m *model) updateComponents(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds []tea.Cmd
 	)
 
-	m.textInput, cmd = m.textInput.Update(msg)
+	m.editor, cmd = m.editor.Update(msg)
 	cmds = append(cmds, cmd)
 
 	m.viewport, cmd = m.viewport.Update(msg)
@@ -408,7 +712,7 @@ func (m *model) updateComponents(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m *model) headerView() string {
 	left := m.styles.AppName.Render(appName) + " " + m.styles.AppVersion.Render("("+m.appVersion+")")
-	right := m.styles.ModelName.Render("Model: " + m.selectedModel)
+	right := m.styles.ModelName.Render("Model: " + m.modelLabel())
 
 	spaceWidth := max(0, m.width-lipgloss.Width(left)-lipgloss.Width(right)-(headerPadding*2))
 	space := lipgloss.NewStyle().Width(spaceWidth).Render("")
@@ -416,21 +720,43 @@ func (m *model) headerView() string {
 	return m.styles.Header.Render(lipgloss.JoinHorizontal(lipgloss.Bottom, left, space, right))
 }
 
+// modelLabel names the models crafting and executing prompts, or the model
+// that answered last when it was a fallback for the one requested.
+func (m *model) modelLabel() string {
+	if m.answeredModel != "" && m.answeredModel != m.requestedModel {
+		return fmt.Sprintf("%s (fallback from %s)", m.answeredModel, m.requestedModel)
+	}
+
+	if m.executionModel() == m.selectedModel {
+		return m.selectedModel
+	}
+
+	return fmt.Sprintf("%s → %s", m.selectedModel, m.executionModel())
+}
+
 func (m *model) mainContentView() string {
 	//nolint:exhaustive // The viewSelectingModel state is handled in the parent View() function.
 	switch m.state {
 	case viewBusy:
 		return m.spinner.View() + m.busyText
-	case viewReady:
+	case viewReady, viewSaving, viewExporting, viewFilling:
 		if m.viewport.View() != "" {
 			return m.viewport.View()
 		}
 
 		return initialInstructionText
-	case viewResult:
+	case viewResult, viewRating, viewChat:
 		return m.viewport.View()
 	case viewError:
 		return m.styles.Error.Render(m.viewport.View())
+	case viewHistory:
+		return m.historyView()
+	case viewLibrary:
+		return m.libraryView()
+	case viewPickingModel:
+		return m.modelList.View()
+	case viewPalette:
+		return m.paletteView()
 	}
 
 	return ""
@@ -440,9 +766,34 @@ func (m *model) footerView() string {
 	var footerContent strings.Builder
 	footerContent.WriteString("\n")
 
-	if m.state != viewResult {
-		footerContent.WriteString(m.styles.Input.Render(m.textInput.View()))
+	switch m.state { //nolint:exhaustive // The other states show the prompt input.
+	case viewResult, viewHistory, viewLibrary, viewPickingModel, viewPalette:
+		// No input.
+	case viewSaving:
+		footerContent.WriteString(m.styles.Input.Render(m.saveInput.View()))
+		footerContent.WriteString("\n")
+	case viewExporting:
+		footerContent.WriteString(m.styles.Input.Render(m.exportInput.View()))
+		footerContent.WriteString("\n")
+	case viewRating:
+		footerContent.WriteString(m.styles.Input.Render(m.noteInput.View()))
+		footerContent.WriteString("\n")
+	case viewFilling:
+		footerContent.WriteString(m.styles.Input.Render(m.fillInput.View()))
 		footerContent.WriteString("\n")
+	default:
+		footerContent.WriteString(m.styles.Input.Render(m.editor.View()))
+		footerContent.WriteString("\n")
+
+		if m.inlineText != "" {
+			style := m.styles.StatusText
+			if m.inlineErr {
+				style = m.styles.Error
+			}
+
+			footerContent.WriteString(m.styles.Input.Render(style.Render(m.inlineText)))
+			footerContent.WriteString("\n")
+		}
 	}
 
 	footerContent.WriteString(m.statusBarView())
@@ -455,14 +806,62 @@ func (m *model) statusBarView() string {
 		return m.styles.StatusBar.Render(m.statusMessage)
 	}
 
-	help := "esc: quit"
+	if m.state.isOverlay() {
+		what, keys := m.overlayHelp()
+		if what == "" {
+			return m.styles.StatusBar.Render(m.help.View(keys))
+		}
+
+		return m.styles.StatusBar.Render(m.styles.StatusText.Render(what) + " | " + m.help.View(keys))
+	}
+
+	var info []string
+
+	if len(m.contextFiles) > 0 {
+		info = append(info, fmt.Sprintf("context: %d files", len(m.contextFiles)))
+	}
+
+	if len(m.attachments) > 0 {
+		info = append(info, "attached: "+strings.Join(attachment.Names(m.attachments), ", "))
+	}
+
+	if m.browsingStages() {
+		info = append(info, m.stageText())
+	}
+
+	if usage := usageText(m.usage); usage != "" {
+		info = append(info, usage)
+	}
+
+	if m.state == viewReady && m.editor.Focused() {
+		info = append(info, "/help: commands")
+
+		if counts := m.editorCounts(); counts != "" {
+			info = append(info, counts)
+		}
+	}
+
+	m.updateKeys()
+	keys := m.help.View(m.keys)
+
+	if len(info) == 0 {
+		return m.styles.StatusBar.Render(keys)
+	}
+
+	// The full help spans several lines, so the details go above it.
+	separator := " | "
+	if m.help.ShowAll {
+		separator = "\n"
+	}
+
+	return m.styles.StatusBar.Render(m.styles.StatusText.Render(strings.Join(info, " | ")) + separator + keys)
+}
 
-	if m.craftedPrompt != "" && m.state == viewReady {
-		resubmitHelp := m.styles.ResubmitHelp.Render("r: resubmit")
-		help = fmt.Sprintf("%s | c: copy | %s", resubmitHelp, help)
-	} else if m.state == viewResult {
-		help = "c: copy | " + help
+// usageText summarizes token usage, reporting thinking tokens separately.
+func usageText(u prompt.Usage) string {
+	if u == (prompt.Usage{}) {
+		return ""
 	}
 
-	return m.styles.StatusBar.Render(m.styles.StatusText.Render(help))
+	return fmt.Sprintf("tokens in %d · out %d · thinking %d", u.PromptTokens, u.OutputTokens, u.ThinkingTokens)
 }
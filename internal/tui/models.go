@@ -0,0 +1,167 @@
+package tui
+
+import (
+	"cmp"
+
+	"prompt-maker/internal/tui/components"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// modelStep names the step a model is picked for: crafting prompts or
+// executing them.
+type modelStep int
+
+const (
+	stepCraft modelStep = iota
+	stepExecute
+)
+
+func (s modelStep) String() string {
+	if s == stepExecute {
+		return "executing prompts"
+	}
+
+	return "crafting prompts"
+}
+
+// other returns the step s is not.
+func (s modelStep) other() modelStep {
+	if s == stepExecute {
+		return stepCraft
+	}
+
+	return stepExecute
+}
+
+// executionModel returns the model that executes prompts and answers
+// follow-ups, which is the crafting model unless another was picked.
+func (m *model) executionModel() string {
+	return cmp.Or(m.executeModel, m.selectedModel)
+}
+
+// modelFor returns the model used for step.
+func (m *model) modelFor(step modelStep) string {
+	if step == stepExecute {
+		return m.executionModel()
+	}
+
+	return m.selectedModel
+}
+
+// nextStep returns the step the shown prompt or answer goes through next.
+func (m *model) nextStep() modelStep {
+	if m.craftedPrompt != "" || m.state == viewResult || m.state == viewChat {
+		return stepExecute
+	}
+
+	return stepCraft
+}
+
+// openModelPicker lists the models to choose the one used for step, keeping
+// the input and crafted prompt as they are.
+func (m *model) openModelPicker(step modelStep) (tea.Model, tea.Cmd) {
+	m.pickerStep = step
+	m.pickerReturnState = m.state
+	m.state = viewPickingModel
+	m.modelList.SetFilteringEnabled(true)
+	m.showPickerStep()
+
+	return m, nil
+}
+
+// openRerunPicker lists the models to execute the shown crafted prompt, or
+// the prompt of the shown answer, again with.
+func (m *model) openRerunPicker() (tea.Model, tea.Cmd) {
+	st, ok := m.shownStage()
+	if !ok || st.kind == stageInput {
+		return m, nil
+	}
+
+	m.rerunPrompt = st.text
+	if st.kind == stageAnswer {
+		m.rerunPrompt = st.prompt
+	}
+
+	return m.openModelPicker(stepExecute)
+}
+
+// showPickerStep titles the picker after its step and selects the model
+// used for it.
+func (m *model) showPickerStep() {
+	m.modelList.Title = "Select the model for " + m.pickerStep.String()
+
+	for i, item := range m.modelList.Items() {
+		if opt, ok := item.(components.ModelOption); ok && opt.Name() == m.modelFor(m.pickerStep) {
+			m.modelList.Select(i)
+
+			break
+		}
+	}
+}
+
+// closeModelPicker returns to the state the picker was opened from.
+func (m *model) closeModelPicker() {
+	m.state = m.pickerReturnState
+	m.rerunPrompt = ""
+	m.modelList.SetFilteringEnabled(false)
+}
+
+func (m *model) updatePickingModel(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// While the filter is being typed, every key belongs to the list.
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && m.modelList.FilterState() != list.Filtering {
+		switch {
+		case key.Matches(keyMsg, m.keys.Back):
+			if m.modelList.FilterState() == list.FilterApplied {
+				m.modelList.ResetFilter()
+			} else {
+				m.closeModelPicker()
+			}
+
+			return m, nil
+		case key.Matches(keyMsg, m.keys.OtherStep):
+			if m.rerunPrompt == "" {
+				m.pickerStep = m.pickerStep.other()
+				m.showPickerStep()
+			}
+
+			return m, nil
+		case key.Matches(keyMsg, m.keys.Confirm):
+			return m.pickModel()
+		}
+	}
+
+	var cmd tea.Cmd
+
+	m.modelList, cmd = m.modelList.Update(msg)
+
+	return m, cmd
+}
+
+// pickModel uses the selected model for the step picked for, then executes
+// the prompt again when the picker was opened to do so.
+func (m *model) pickModel() (tea.Model, tea.Cmd) {
+	opt, ok := m.modelList.SelectedItem().(components.ModelOption)
+	if !ok {
+		return m, nil
+	}
+
+	if m.pickerStep == stepExecute {
+		m.executeModel = opt.Name()
+	} else {
+		m.selectedModel = opt.Name()
+	}
+
+	rerun := m.rerunPrompt
+	m.closeModelPicker()
+
+	if rerun != "" {
+		return m.runCraftedPrompt(rerun)
+	}
+
+	status := statusMessage("Using " + opt.Name() + " for " + m.pickerStep.String() + ".")
+
+	return m, func() tea.Msg { return status }
+}
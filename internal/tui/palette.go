@@ -0,0 +1,355 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"prompt-maker/internal/history"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// paletteHeight caps the actions listed in the command palette.
+const paletteHeight = 12
+
+// paletteAction is an action the command palette can run.
+type paletteAction struct {
+	title string
+	// keys is the shortcut, or slash command, that runs the action directly.
+	keys string
+	// when reports whether the action applies in the state the palette was
+	// opened from.
+	when func(m *model) bool
+	run  func(m *model) (tea.Model, tea.Cmd)
+}
+
+// paletteActions lists every action of the TUI in the order the palette
+// shows them before a query is typed, with the keys k binds to them.
+//
+//nolint:funlen // A flat table reads best.
+func paletteActions(k keyMap) []paletteAction {
+	return []paletteAction{
+		{
+			title: "Submit the prompt", keys: k.Submit.Help().Key,
+			when: func(m *model) bool { return m.state == viewReady },
+			run:  (*model).submitPrompt,
+		},
+		{
+			title: "Execute the crafted prompt", keys: k.Execute.Help().Key,
+			when: (*model).reviewingCraftedPrompt,
+			run:  (*model).resubmitPrompt,
+		},
+		{
+			title: "Edit the crafted prompt", keys: k.Edit.Help().Key,
+			when: (*model).reviewingCraftedPrompt,
+			run:  (*model).editCraftedPrompt,
+		},
+		{
+			title: "Reset the crafted prompt to the original", keys: k.ResetPrompt.Help().Key,
+			when: func(m *model) bool { return m.craftedPrompt != "" && m.state == viewReady },
+			run:  (*model).resetCraftedPrompt,
+		},
+		{
+			title: "Start a new prompt", keys: k.NewPrompt.Help().Key + ", " + k.StartOver.Help().Key,
+			when: func(m *model) bool { return m.state != viewBusy },
+			run: func(m *model) (tea.Model, tea.Cmd) {
+				m.resetToReady()
+
+				return m, m.editor.Focus()
+			},
+		},
+		{
+			title: "Copy the response", keys: k.Copy.Help().Key,
+			when: func(m *model) bool { return m.state == viewResult || m.reviewingCraftedPrompt() },
+			run: func(m *model) (tea.Model, tea.Cmd) {
+				return m, copyToClipboardCmd(m.rawViewportContent)
+			},
+		},
+		{
+			title: "Ask a follow-up question", keys: k.FollowUp.Help().Key,
+			when: func(m *model) bool { return m.state == viewResult && !m.showingRoughInput() },
+			run:  (*model).openChat,
+		},
+		{
+			title: "Switch model", keys: k.Model.Help().Key + ", /model",
+			when: func(m *model) bool { return m.state == viewReady || m.state == viewResult || m.state == viewChat },
+			run:  func(m *model) (tea.Model, tea.Cmd) { return m.openModelPicker(m.nextStep()) },
+		},
+		{
+			title: "Execute again with another model", keys: k.Rerun.Help().Key,
+			when: func(m *model) bool {
+				return (m.state == viewResult || m.reviewingCraftedPrompt()) && !m.showingRoughInput()
+			},
+			run: (*model).openRerunPicker,
+		},
+		{
+			title: "Show the previous stage", keys: k.PrevStage.Help().Key,
+			when: (*model).browsingStages,
+			run:  func(m *model) (tea.Model, tea.Cmd) { return m.moveStage(-1) },
+		},
+		{
+			title: "Show the next stage", keys: k.NextStage.Help().Key,
+			when: (*model).browsingStages,
+			run:  func(m *model) (tea.Model, tea.Cmd) { return m.moveStage(1) },
+		},
+		{
+			title: "Toggle raw view", keys: k.Raw.Help().Key,
+			when: (*model).showsResponse,
+			run:  (*model).toggleRaw,
+		},
+		{
+			title: "Show or hide thoughts", keys: k.Thoughts.Help().Key,
+			when: func(m *model) bool { return m.thoughts != "" && m.state != viewError },
+			run:  (*model).toggleThoughts,
+		},
+		{
+			title: "Rate up", keys: k.RateUp.Help().Key,
+			when: (*model).canRate,
+			run: func(m *model) (tea.Model, tea.Cmd) {
+				target, _ := m.shownTarget()
+
+				return m.openRating(target, history.RatingUp)
+			},
+		},
+		{
+			title: "Rate down", keys: k.RateDown.Help().Key,
+			when: (*model).canRate,
+			run: func(m *model) (tea.Model, tea.Cmd) {
+				target, _ := m.shownTarget()
+
+				return m.openRating(target, history.RatingDown)
+			},
+		},
+		{
+			title: "Open history", keys: k.History.Help().Key + ", /history",
+			when: func(m *model) bool { return m.recorder != nil },
+			run:  (*model).openHistory,
+		},
+		{
+			title: "Open the library", keys: k.Library.Help().Key,
+			when: func(m *model) bool { return m.library != nil },
+			run:  (*model).openLibrary,
+		},
+		{
+			title: "Save the crafted prompt to the library", keys: k.Save.Help().Key + ", /save",
+			when: func(m *model) bool { return m.library != nil && m.craftedPrompt != "" && m.state == viewReady },
+			run:  (*model).openSave,
+		},
+		{
+			title: "Export the session", keys: k.Export.Help().Key + ", /export",
+			when: func(m *model) bool { return m.recorder != nil && len(m.sessionIDs) > 0 },
+			run:  (*model).openExport,
+		},
+		{
+			title: "Edit in $EDITOR", keys: k.Editor.Help().Key,
+			when: func(m *model) bool { return m.state == viewReady || m.state == viewChat },
+			run:  (*model).openExternalEditor,
+		},
+		{
+			title: "Change persona", keys: "/persona",
+			when: func(m *model) bool { return m.state == viewReady },
+			run:  func(m *model) (tea.Model, tea.Cmd) { return m.startSlashCommand("/persona ") },
+		},
+		{
+			title: "Set the temperature", keys: "/temp",
+			when: func(m *model) bool { return m.state == viewReady },
+			run:  func(m *model) (tea.Model, tea.Cmd) { return m.startSlashCommand("/temp ") },
+		},
+		{
+			title: "Attach a file", keys: "/attach",
+			when: func(m *model) bool { return m.state == viewReady },
+			run:  func(m *model) (tea.Model, tea.Cmd) { return m.startSlashCommand("/attach ") },
+		},
+		{
+			title: "List the slash commands", keys: "/help",
+			when: func(m *model) bool { return m.state == viewReady },
+			run:  func(m *model) (tea.Model, tea.Cmd) { return m.showInline(slashHelp(), false) },
+		},
+		{
+			title: "Open a new tab", keys: k.NewTab.Help().Key,
+			when: func(*model) bool { return true },
+			run:  func(m *model) (tea.Model, tea.Cmd) { return m, func() tea.Msg { return newTabMsg{} } },
+		},
+		{
+			title: "Close this tab", keys: k.CloseTab.Help().Key,
+			when: func(*model) bool { return true },
+			run:  func(m *model) (tea.Model, tea.Cmd) { return m, func() tea.Msg { return closeTabMsg{} } },
+		},
+		{
+			title: "Show or hide all keys", keys: k.Help.Help().Key,
+			when: func(*model) bool { return true },
+			run:  (*model).toggleHelp,
+		},
+		{
+			title: "Quit", keys: k.Quit.Help().Key + ", " + k.ForceQuit.Help().Key,
+			when: func(*model) bool { return true },
+			run: func(m *model) (tea.Model, tea.Cmd) {
+				m.cancel()
+				m.quitting = true
+
+				return m, tea.Quit
+			},
+		},
+	}
+}
+
+// newPaletteInput creates the input that takes the palette query.
+func newPaletteInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "Type to search actions"
+	ti.Prompt = "> "
+
+	return ti
+}
+
+// openPalette lists the actions that apply in the current state.
+func (m *model) openPalette() (tea.Model, tea.Cmd) {
+	m.paletteActions = nil
+
+	for _, a := range paletteActions(m.keys) {
+		if a.when(m) {
+			m.paletteActions = append(m.paletteActions, a)
+		}
+	}
+
+	m.paletteReturnState = m.state
+	m.state = viewPalette
+	m.paletteInput.Reset()
+	m.filterPalette()
+
+	return m, m.paletteInput.Focus()
+}
+
+// closePalette returns to the state the palette was opened from.
+func (m *model) closePalette() {
+	m.state = m.paletteReturnState
+	m.paletteInput.Blur()
+}
+
+// filterPalette ranks the actions by how well their titles fuzzy-match the
+// query.
+func (m *model) filterPalette() {
+	m.paletteCursor = 0
+
+	query := m.paletteInput.Value()
+	if query == "" {
+		m.paletteMatches = m.paletteActions
+
+		return
+	}
+
+	titles := make([]string, len(m.paletteActions))
+	for i, a := range m.paletteActions {
+		titles[i] = a.title
+	}
+
+	ranks := list.DefaultFilter(query, titles)
+
+	m.paletteMatches = make([]paletteAction, len(ranks))
+	for i, r := range ranks {
+		m.paletteMatches[i] = m.paletteActions[r.Index]
+	}
+}
+
+func (m *model) updatePalette(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(keyMsg, m.keys.Back, m.keys.Palette):
+			m.closePalette()
+
+			return m, nil
+		case key.Matches(keyMsg, m.keys.Up, m.keys.PrevField):
+			m.paletteCursor = max(0, m.paletteCursor-1)
+
+			return m, nil
+		case key.Matches(keyMsg, m.keys.Down, m.keys.NextField):
+			m.paletteCursor = min(len(m.paletteMatches)-1, m.paletteCursor+1)
+
+			return m, nil
+		case key.Matches(keyMsg, m.keys.Confirm):
+			if len(m.paletteMatches) == 0 {
+				return m, nil
+			}
+
+			action := m.paletteMatches[m.paletteCursor]
+			m.closePalette()
+
+			return action.run(m)
+		}
+	}
+
+	var cmd tea.Cmd
+
+	m.paletteInput, cmd = m.paletteInput.Update(msg)
+	m.filterPalette()
+
+	return m, cmd
+}
+
+// paletteView renders the query and the matching actions with their keys.
+func (m *model) paletteView() string {
+	var b strings.Builder
+
+	b.WriteString(m.paletteInput.View())
+	b.WriteString("\n\n")
+
+	if len(m.paletteMatches) == 0 {
+		b.WriteString(m.styles.StatusText.Render("No matching action."))
+
+		return b.String()
+	}
+
+	width := 0
+	for _, a := range m.paletteMatches {
+		width = max(width, lipgloss.Width(a.title))
+	}
+
+	first := max(0, m.paletteCursor-paletteHeight+1)
+	for i := first; i < min(len(m.paletteMatches), first+paletteHeight); i++ {
+		a := m.paletteMatches[i]
+		line := fmt.Sprintf("%-*s  %s", width, a.title, m.styles.StatusText.Render(a.keys))
+
+		if i == m.paletteCursor {
+			b.WriteString(m.styles.SelectedListItem.Render("> " + line))
+		} else {
+			b.WriteString(m.styles.ListItem.Render("  " + line))
+		}
+
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// startSlashCommand puts the start of a slash command in the editor for its
+// argument to be typed.
+func (m *model) startSlashCommand(command string) (tea.Model, tea.Cmd) {
+	m.editor.SetValue(command)
+
+	return m, m.editor.Focus()
+}
+
+// showsResponse reports whether a response is on screen to be shown raw or
+// rendered.
+func (m *model) showsResponse() bool {
+	return m.rawViewportContent != "" && (m.state == viewResult || m.state == viewChat || m.reviewingCraftedPrompt())
+}
+
+// canRate reports whether the shown response can be rated.
+func (m *model) canRate() bool {
+	_, ok := m.shownTarget()
+
+	return ok && m.recorder != nil && m.entryID != ""
+}
+
+// toggleRaw switches the viewport between rendered markdown and the raw text.
+func (m *model) toggleRaw() (tea.Model, tea.Cmd) {
+	m.showRaw = !m.showRaw
+	m.renderViewport()
+
+	return m, nil
+}
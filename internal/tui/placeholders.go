@@ -0,0 +1,190 @@
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+
+	"prompt-maker/internal/placeholder"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// placeholderValuesMsg carries the values remembered for placeholders.
+type placeholderValuesMsg struct {
+	values placeholder.Values
+	err    error
+}
+
+// newFillInput creates the input that takes the value of a placeholder.
+func newFillInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "Value to put in place of the placeholder"
+
+	return ti
+}
+
+// loadPlaceholderValuesCmd reads the remembered values off the Update goroutine.
+func loadPlaceholderValuesCmd(memory *placeholder.Memory) tea.Cmd {
+	return func() tea.Msg {
+		values, err := memory.Load()
+
+		return placeholderValuesMsg{values: values, err: err}
+	}
+}
+
+// rememberPlaceholderValuesCmd stores the values used, reporting only failures.
+func rememberPlaceholderValuesCmd(memory *placeholder.Memory, values map[string]string) tea.Cmd {
+	return func() tea.Msg {
+		if err := memory.Remember(values); err != nil {
+			return statusMessage("Placeholder values not remembered: " + err.Error())
+		}
+
+		return nil
+	}
+}
+
+// runCraftedPrompt executes text, first asking for the value of each of its
+// placeholders.
+func (m *model) runCraftedPrompt(text string) (tea.Model, tea.Cmd) {
+	tokens := placeholder.Find(text)
+	if len(tokens) == 0 {
+		return m.executePrompt(text)
+	}
+
+	m.state = viewFilling
+	m.fillText = text
+	m.fillTokens = tokens
+	m.fillValues = make(map[string]string, len(tokens))
+	m.fillIndex = 0
+	m.fillRecent = nil
+	m.showFillInput()
+
+	cmds := []tea.Cmd{m.fillInput.Focus()}
+	if m.placeholders != nil {
+		cmds = append(cmds, loadPlaceholderValuesCmd(m.placeholders))
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+// showFillInput prepares the input for the current placeholder, suggesting
+// the value used last time.
+func (m *model) showFillInput() {
+	token := m.fillTokens[m.fillIndex]
+
+	m.fillInput.Prompt = fmt.Sprintf("%s (%d/%d): ", token, m.fillIndex+1, len(m.fillTokens))
+	m.fillInput.SetValue(cmp.Or(m.fillValues[token], m.fillRecent.Last(token)))
+	m.fillInput.CursorEnd()
+}
+
+// cycleFillValue replaces the input with the next or previous remembered value.
+func (m *model) cycleFillValue(step int) {
+	recent := m.fillRecent.Recent(m.fillTokens[m.fillIndex])
+	if len(recent) == 0 {
+		return
+	}
+
+	i := (slices.Index(recent, m.fillInput.Value()) + step + len(recent)) % len(recent)
+	m.fillInput.SetValue(recent[i])
+	m.fillInput.CursorEnd()
+}
+
+// closeFilling returns to the crafted prompt without executing it.
+func (m *model) closeFilling() (tea.Model, tea.Cmd) {
+	m.state = viewReady
+	m.fillInput.Blur()
+
+	return m, nil
+}
+
+func (m *model) updateFilling(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case placeholderValuesMsg:
+		return m.handlePlaceholderValues(msg)
+	case statusMessage, clearStatusMsg:
+		model, cmd, _ := m.handleCommonMsg(msg)
+
+		return model, cmd
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Back):
+			return m.closeFilling()
+		case key.Matches(msg, m.keys.Up):
+			m.cycleFillValue(-1)
+
+			return m, nil
+		case key.Matches(msg, m.keys.Down):
+			m.cycleFillValue(1)
+
+			return m, nil
+		case key.Matches(msg, m.keys.PrevField):
+			if m.fillIndex > 0 {
+				m.fillValues[m.fillTokens[m.fillIndex]] = m.fillInput.Value()
+				m.fillIndex--
+				m.showFillInput()
+			}
+
+			return m, nil
+		case key.Matches(msg, m.keys.Confirm, m.keys.NextField):
+			return m.nextPlaceholder()
+		}
+	}
+
+	var cmd tea.Cmd
+
+	m.fillInput, cmd = m.fillInput.Update(msg)
+
+	return m, cmd
+}
+
+func (m *model) handlePlaceholderValues(msg placeholderValuesMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, func() tea.Msg { return statusMessage("Could not read placeholder values: " + msg.err.Error()) }
+	}
+
+	m.fillRecent = msg.values
+
+	if m.fillInput.Value() == "" {
+		m.showFillInput()
+	}
+
+	return m, nil
+}
+
+// nextPlaceholder keeps the value of the current placeholder and moves to the
+// next one, executing the filled prompt after the last.
+func (m *model) nextPlaceholder() (tea.Model, tea.Cmd) {
+	token := m.fillTokens[m.fillIndex]
+
+	value := m.fillInput.Value()
+	if value == "" {
+		return m, func() tea.Msg {
+			return statusMessage(fmt.Sprintf("Fill in %s, or press esc to edit the prompt.", token))
+		}
+	}
+
+	m.fillValues[token] = value
+
+	if m.fillIndex < len(m.fillTokens)-1 {
+		m.fillIndex++
+		m.showFillInput()
+
+		return m, nil
+	}
+
+	m.fillInput.Blur()
+
+	filled := placeholder.Fill(m.fillText, m.fillValues)
+	m.editor.SetValue(filled)
+
+	model, cmd := m.executePrompt(filled)
+	if m.placeholders != nil {
+		cmd = tea.Batch(cmd, rememberPlaceholderValuesCmd(m.placeholders, maps.Clone(m.fillValues)))
+	}
+
+	return model, cmd
+}
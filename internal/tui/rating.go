@@ -0,0 +1,111 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"prompt-maker/internal/history"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ratedMsg reports the outcome of rating a response.
+type ratedMsg struct {
+	entry  history.Entry
+	target history.Target
+	err    error
+}
+
+// newNoteInput creates the input that takes the note of a rating.
+func newNoteInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "Optional note, e.g. what made it good or bad"
+	ti.Prompt = "Note: "
+
+	return ti
+}
+
+// rateCmd stores the rating off the Update goroutine.
+func rateCmd(ctx context.Context, store history.Store, id string, target history.Target, f history.Feedback) tea.Cmd {
+	return func() tea.Msg {
+		e, err := history.Rate(ctx, store, id, target, f)
+
+		return ratedMsg{entry: e, target: target, err: err}
+	}
+}
+
+// shownTarget returns the response shown in the current state, if it can be
+// rated.
+func (m *model) shownTarget() (history.Target, bool) {
+	switch {
+	case m.reviewingCraftedPrompt():
+		return history.TargetPrompt, true
+	case m.state == viewResult && !m.showingRoughInput():
+		return history.TargetAnswer, true
+	default:
+		return "", false
+	}
+}
+
+// openRating rates the shown response and asks for an optional note.
+func (m *model) openRating(target history.Target, rating history.Rating) (tea.Model, tea.Cmd) {
+	if m.recorder == nil || m.entryID == "" {
+		return m, func() tea.Msg { return statusMessage("Ratings are stored with history, which is disabled.") }
+	}
+
+	m.pendingRating = rating
+	m.ratingTarget = target
+	m.ratingReturnState = m.state
+	m.state = viewRating
+	m.noteInput.Reset()
+
+	return m, m.noteInput.Focus()
+}
+
+// closeRating returns to the rated response.
+func (m *model) closeRating() (tea.Model, tea.Cmd) {
+	m.state = m.ratingReturnState
+	m.noteInput.Blur()
+
+	return m, nil
+}
+
+func (m *model) updateRating(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case ratedMsg:
+		return m.handleRated(msg)
+	case statusMessage, clearStatusMsg:
+		model, cmd, _ := m.handleCommonMsg(msg)
+
+		return model, cmd
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Back):
+			return m.closeRating()
+		case key.Matches(msg, m.keys.Confirm):
+			f := history.Feedback{Rating: m.pendingRating, Note: m.noteInput.Value(), At: time.Now()}
+
+			return m, rateCmd(m.ctx, m.recorder.Store(), m.entryID, m.ratingTarget, f)
+		}
+	}
+
+	var cmd tea.Cmd
+
+	m.noteInput, cmd = m.noteInput.Update(msg)
+
+	return m, cmd
+}
+
+func (m *model) handleRated(msg ratedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, func() tea.Msg { return statusMessage("Not rated: " + msg.err.Error()) }
+	}
+
+	model, cmd := m.closeRating()
+	status := fmt.Sprintf("Rated the %s %s.", msg.target, msg.entry.Feedback(msg.target).Rating)
+
+	return model, tea.Batch(cmd, func() tea.Msg { return statusMessage(status) })
+}
@@ -0,0 +1,342 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"prompt-maker/internal/attachment"
+	"prompt-maker/internal/prompt"
+	"prompt-maker/internal/tui/components"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxTemperature is the highest temperature the Gemini models accept.
+const maxTemperature = 2
+
+// slashCommand is a command typed in the editor after a slash.
+type slashCommand struct {
+	name string
+	args string
+	desc string
+}
+
+// slashCommands lists the commands in the order /help shows them.
+func slashCommands() []slashCommand {
+	return []slashCommand{
+		{name: "model", args: "[name]", desc: "pick the model for the next step, or use name"},
+		{name: "temp", args: "<0-2>", desc: "set the temperature"},
+		{name: "persona", args: "[name]", desc: "show or set the persona crafting prompts"},
+		{name: "save", args: "<name> [#tag...]", desc: "save the crafted prompt to the library"},
+		{name: "export", args: "<file>", desc: "export the session to .md, .html or .json"},
+		{name: "history", desc: "open the history browser"},
+		{name: "clear", desc: "start over with an empty prompt"},
+		{name: "attach", args: "<path>", desc: "attach an image or text file"},
+		{name: "help", desc: "list the commands"},
+	}
+}
+
+// attachedMsg reports the outcome of loading a file for /attach.
+type attachedMsg struct {
+	attachment attachment.Attachment
+	err        error
+}
+
+// loadAttachmentCmd reads the file at path off the Update goroutine.
+func loadAttachmentCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		a, err := attachment.Load(path)
+
+		return attachedMsg{attachment: a, err: err}
+	}
+}
+
+// isSlashCommand reports whether text is a command rather than a prompt: a
+// single line starting with one slash. Prompts starting with a slash are
+// sent by doubling it.
+func isSlashCommand(text string) bool {
+	return strings.HasPrefix(text, "/") && !strings.HasPrefix(text, "//") && !strings.Contains(text, "\n")
+}
+
+// showInline shows text under the editor until the next key, styled as an
+// error when isErr is set.
+func (m *model) showInline(text string, isErr bool) (tea.Model, tea.Cmd) {
+	m.inlineText = text
+	m.inlineErr = isErr
+
+	return m, nil
+}
+
+// runSlashCommand runs the command typed in the editor. On success the
+// editor gets back the crafted prompt, or is emptied; on error the command
+// stays for fixing and the error is shown under it.
+func (m *model) runSlashCommand() (tea.Model, tea.Cmd) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(m.editor.Value()), "/"), " ")
+	arg = strings.TrimSpace(arg)
+
+	if !slices.ContainsFunc(slashCommands(), func(c slashCommand) bool { return c.name == name }) {
+		return m.showInline(fmt.Sprintf("Unknown command /%s; /help lists them, and // starts a prompt with a slash.", name), true)
+	}
+
+	if problem := m.slashCommandProblem(name, arg); problem != "" {
+		return m.showInline(problem, true)
+	}
+
+	m.restoreEditor()
+
+	switch name {
+	case "model":
+		return m.modelCommand(arg)
+	case "temp":
+		return m.tempCommand(arg)
+	case "persona":
+		status := statusMessage("Prompts are crafted by the " + prompt.LyraPersona + " persona.")
+
+		return m, func() tea.Msg { return status }
+	case "save":
+		m.openSave()
+		m.saveInput.SetValue(arg)
+
+		return m, saveToLibraryCmd(m.ctx, m.library, arg, m.craftedPrompt, m.entryID)
+	case "export":
+		m.openExport()
+		m.exportInput.SetValue(arg)
+
+		return m, exportSessionCmd(m.ctx, m.recorder.Store(), slices.Clone(m.sessionIDs), arg)
+	case "history":
+		return m.openHistory()
+	case "clear":
+		m.resetToReady()
+
+		return m, m.editor.Focus()
+	case "attach":
+		return m, loadAttachmentCmd(arg)
+	default:
+		return m.showInline(slashHelp(), false)
+	}
+}
+
+// slashCommandProblem explains why the command cannot run, or returns an
+// empty string when it can.
+func (m *model) slashCommandProblem(name, arg string) string {
+	switch {
+	case name == "model" && arg != "":
+		_, problem := m.matchModel(arg)
+
+		return problem
+	case name == "temp":
+		if _, err := parseTemperature(arg); err != nil {
+			return "Usage: /temp <0-2>, for example /temp 0.7."
+		}
+	case name == "persona" && arg != "" && arg != prompt.LyraPersona:
+		return fmt.Sprintf("Unknown persona %q; available: %s.", arg, prompt.LyraPersona)
+	case name == "save" && m.library == nil:
+		return "The library is disabled."
+	case name == "save" && m.craftedPrompt == "":
+		return "Craft a prompt before saving it."
+	case name == "save" && arg == "":
+		return "Usage: /save <name> [#tag...]."
+	case name == "export" && m.recorder == nil:
+		return "History is disabled; there is nothing to export."
+	case name == "export" && len(m.sessionIDs) == 0:
+		return "Nothing to export yet."
+	case name == "export" && arg == "":
+		return "Usage: /export <file>, ending in .md, .html or .json."
+	case name == "history" && m.recorder == nil:
+		return "History is disabled."
+	case name == "attach" && arg == "":
+		return "Usage: /attach <path>."
+	}
+
+	return ""
+}
+
+// restoreEditor puts the crafted prompt back in the editor for review, or
+// empties it, once a command has run.
+func (m *model) restoreEditor() {
+	if m.craftedPrompt != "" {
+		m.showCraftedPrompt(m.craftedPrompt)
+
+		return
+	}
+
+	m.editor.Reset()
+}
+
+// modelCommand opens the model picker for the next step, or uses the model
+// named by arg for it.
+func (m *model) modelCommand(arg string) (tea.Model, tea.Cmd) {
+	if arg == "" {
+		return m.openModelPicker(m.nextStep())
+	}
+
+	name, _ := m.matchModel(arg)
+	step := m.nextStep()
+
+	if step == stepExecute {
+		m.executeModel = name
+	} else {
+		m.selectedModel = name
+	}
+
+	status := statusMessage("Using " + name + " for " + step.String() + ".")
+
+	return m, func() tea.Msg { return status }
+}
+
+// matchModel returns the model named arg, or the only one whose name
+// contains it, or explains why there is none.
+func (m *model) matchModel(arg string) (string, string) {
+	var matches []string
+
+	for _, name := range m.modelNames() {
+		if name == arg {
+			return name, ""
+		}
+
+		if strings.Contains(name, arg) {
+			matches = append(matches, name)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Sprintf("Unknown model %q; available: %s.", arg, strings.Join(m.modelNames(), ", "))
+	case 1:
+		return matches[0], ""
+	default:
+		return "", fmt.Sprintf("%q matches %s; be more specific.", arg, strings.Join(matches, ", "))
+	}
+}
+
+// modelNames returns the names of the models in the picker.
+func (m *model) modelNames() []string {
+	items := m.modelList.Items()
+	names := make([]string, 0, len(items))
+
+	for _, item := range items {
+		if opt, ok := item.(components.ModelOption); ok {
+			names = append(names, opt.Name())
+		}
+	}
+
+	return names
+}
+
+// tempCommand samples the following requests at the temperature in arg.
+func (m *model) tempCommand(arg string) (tea.Model, tea.Cmd) {
+	temperature, _ := parseTemperature(arg)
+
+	m.temperature = temperature
+	m.runner = m.runner.WithTemperature(temperature)
+
+	if m.recorder != nil {
+		m.recorder = m.recorder.WithTemperature(temperature)
+	}
+
+	status := statusMessage(fmt.Sprintf("Temperature set to %g.", temperature))
+
+	return m, func() tea.Msg { return status }
+}
+
+// parseTemperature parses a temperature from 0 to maxTemperature.
+func parseTemperature(arg string) (float32, error) {
+	t, err := strconv.ParseFloat(arg, 32)
+	if err != nil {
+		return 0, fmt.Errorf("parsing temperature: %w", err)
+	}
+
+	if t < 0 || t > maxTemperature {
+		return 0, fmt.Errorf("%w: %g is outside 0-%d", errTemperature, t, maxTemperature)
+	}
+
+	return float32(t), nil
+}
+
+// handleAttached adds the file loaded by /attach to every following prompt.
+func (m *model) handleAttached(msg attachedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		return m.showInline("Not attached: "+msg.err.Error(), true)
+	}
+
+	m.attachments = append(slices.Clone(m.attachments), msg.attachment)
+	status := statusMessage("Attached " + msg.attachment.Name + ".")
+
+	return m, func() tea.Msg { return status }
+}
+
+// completeSlashCommand completes the command name, or the model name after
+// /model, typed in the editor, listing the candidates when there are
+// several.
+func (m *model) completeSlashCommand() (tea.Model, tea.Cmd) {
+	value := m.editor.Value()
+
+	if arg, ok := strings.CutPrefix(value, "/model "); ok {
+		return m.complete("/model ", arg, m.modelNames(), "")
+	}
+
+	if strings.Contains(value, " ") {
+		return m, nil
+	}
+
+	commands := slashCommands()
+
+	names := make([]string, len(commands))
+	for i, c := range commands {
+		names[i] = c.name
+	}
+
+	return m.complete("/", strings.TrimPrefix(value, "/"), names, " ")
+}
+
+// complete replaces word, typed after prefix, with the candidate it starts,
+// followed by suffix, or with the longest start the candidates share.
+func (m *model) complete(prefix, word string, candidates []string, suffix string) (tea.Model, tea.Cmd) {
+	var matches []string
+
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			matches = append(matches, c)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return m.showInline(fmt.Sprintf("Nothing matches %q.", word), true)
+	case 1:
+		m.editor.SetValue(prefix + matches[0] + suffix)
+
+		return m, nil
+	default:
+		m.editor.SetValue(prefix + commonPrefix(matches))
+
+		return m.showInline(strings.Join(matches, "  "), false)
+	}
+}
+
+// commonPrefix returns the longest start shared by words.
+func commonPrefix(words []string) string {
+	shared := words[0]
+
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, shared) {
+			shared = shared[:len(shared)-1]
+		}
+	}
+
+	return shared
+}
+
+// slashHelp lists the commands with their arguments.
+func slashHelp() string {
+	commands := slashCommands()
+
+	lines := make([]string, len(commands))
+	for i, c := range commands {
+		lines[i] = fmt.Sprintf("/%-8s %-18s %s", c.name, c.args, c.desc)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,157 @@
+package tui
+
+import (
+	"fmt"
+
+	"prompt-maker/internal/prompt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// stageKind names a step of the prompt chain.
+type stageKind int
+
+const (
+	stageInput stageKind = iota
+	stageCrafted
+	stageAnswer
+)
+
+func (k stageKind) String() string {
+	switch k {
+	case stageInput:
+		return "rough input"
+	case stageCrafted:
+		return "crafted prompt"
+	default:
+		return "answer"
+	}
+}
+
+// stage is a step of the prompt chain: the rough input, a crafted prompt, or
+// an answer to one.
+type stage struct {
+	kind stageKind
+	text string
+	// prompt is the text an answer answers.
+	prompt string
+	// model answered the request sent to requestedModel; they differ when a
+	// fallback answered.
+	model          string
+	requestedModel string
+	thoughts       string
+	usage          prompt.Usage
+	// entryID is the history entry recording the stage, if any.
+	entryID string
+}
+
+// addStages appends stages to the chain and makes the last one current.
+func (m *model) addStages(stages ...stage) {
+	m.stages = append(m.stages, stages...)
+	m.stageIndex = len(m.stages) - 1
+}
+
+// recordCraft adds the rough input and the prompt crafted from it to the
+// chain.
+func (m *model) recordCraft(msg aiResponseMsg) {
+	m.addStages(
+		stage{kind: stageInput, text: msg.prompt},
+		stage{
+			kind: stageCrafted, text: msg.response, model: msg.model, requestedModel: msg.requestedModel,
+			thoughts: msg.thoughts, usage: msg.usage, entryID: msg.entryID,
+		},
+	)
+}
+
+// recordAnswer adds an answer to the chain, preceded by the prompt it answers
+// when that is not the last crafted prompt, as when it was edited or loaded
+// from history.
+func (m *model) recordAnswer(msg aiResponseMsg, parentID string) {
+	if crafted, ok := m.lastCraftedStage(); !ok || crafted.text != msg.prompt {
+		m.addStages(stage{kind: stageCrafted, text: msg.prompt, entryID: parentID})
+	}
+
+	m.addStages(stage{
+		kind: stageAnswer, text: msg.response, prompt: msg.prompt, model: msg.model, requestedModel: msg.requestedModel,
+		thoughts: msg.thoughts, usage: msg.usage, entryID: msg.entryID,
+	})
+}
+
+// lastCraftedStage returns the newest crafted prompt of the chain.
+func (m *model) lastCraftedStage() (stage, bool) {
+	for i := len(m.stages) - 1; i >= 0; i-- {
+		if m.stages[i].kind == stageCrafted {
+			return m.stages[i], true
+		}
+	}
+
+	return stage{}, false
+}
+
+// shownStage returns the stage of the chain on screen, if any.
+func (m *model) shownStage() (stage, bool) {
+	if m.stageIndex < 0 || m.stageIndex >= len(m.stages) {
+		return stage{}, false
+	}
+
+	return m.stages[m.stageIndex], true
+}
+
+// browsingStages reports whether the keys moving along the chain apply.
+func (m *model) browsingStages() bool {
+	return len(m.stages) > 1 && (m.state == viewResult || m.reviewingCraftedPrompt())
+}
+
+// showingRoughInput reports whether the rough input of the chain is on screen.
+func (m *model) showingRoughInput() bool {
+	st, ok := m.shownStage()
+
+	return ok && m.state == viewResult && st.kind == stageInput
+}
+
+// moveStage shows the stage step places before or after the current one.
+func (m *model) moveStage(step int) (tea.Model, tea.Cmd) {
+	i := m.stageIndex + step
+	if i < 0 || i >= len(m.stages) {
+		return m, nil
+	}
+
+	m.showStage(i)
+
+	return m, nil
+}
+
+// showStage puts stage i of the chain on screen: a crafted prompt for review,
+// an answer or the rough input as a result.
+func (m *model) showStage(i int) {
+	st := m.stages[i]
+
+	m.stageIndex = i
+	m.transcript = nil
+	m.answeredModel = st.model
+	m.requestedModel = st.requestedModel
+	m.thoughts = st.thoughts
+	m.usage = st.usage
+	m.entryID = st.entryID
+	m.rawViewportContent = st.text
+
+	if st.kind == stageCrafted {
+		m.showCraftedPrompt(st.text)
+	} else {
+		m.craftedPrompt = ""
+		m.executedPrompt = st.prompt
+		m.editor.Reset()
+		m.editor.Placeholder = placeholderNewPrompt
+		m.state = viewResult
+	}
+
+	m.renderViewport()
+	m.viewport.GotoTop()
+}
+
+// stageText describes the position in the chain for the status bar.
+func (m *model) stageText() string {
+	st, _ := m.shownStage()
+
+	return fmt.Sprintf("stage %d/%d (%s)", m.stageIndex+1, len(m.stages), st.kind)
+}
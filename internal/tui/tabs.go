@@ -0,0 +1,346 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"prompt-maker/internal/gemini"
+	"prompt-maker/internal/tui/components"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// tabBarHeight is the height the tab bar takes from the sessions.
+	tabBarHeight = 1
+	// tabTitleWidth caps the rough input shown as a tab's title.
+	tabTitleWidth = 20
+)
+
+// tabMsg carries a message produced by the commands of the session in tab
+// id, so it reaches that session even when another tab is shown.
+type tabMsg struct {
+	id  int
+	msg tea.Msg
+}
+
+// newTabMsg and closeTabMsg ask the tabs to open a tab or close the one
+// sending them, for the actions of the command palette.
+type (
+	newTabMsg   struct{}
+	closeTabMsg struct{}
+)
+
+// tab is a session shown in its own tab.
+type tab struct {
+	id      int
+	session *model
+	// unseen marks a response that arrived while another tab was shown.
+	unseen bool
+}
+
+// tabs holds independent sessions, each with its own model, input, prompt
+// chain and request, and shows one at a time. Requests keep running in the
+// tabs that are not shown.
+type tabs struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	creator gemini.ChatCreator
+	opts    Options
+	keys    keyMap
+	styles  components.Styles
+	tabs    []*tab
+	active  int
+	nextID  int
+	size    tea.WindowSizeMsg
+}
+
+// NewTabs creates the TUI with a first session configured by opts. Later
+// tabs start with the models of the tab they were opened from.
+func NewTabs(ctx context.Context, chatSvc gemini.ChatCreator, opts Options) tea.Model {
+	ctx, cancel := context.WithCancel(ctx)
+
+	t := &tabs{
+		ctx:     ctx,
+		cancel:  cancel,
+		creator: chatSvc,
+		opts:    opts,
+		keys:    newKeyMap(opts.Keys),
+		styles:  components.NewStyles(),
+	}
+	t.addTab(opts)
+
+	return t
+}
+
+func (t *tabs) Init() tea.Cmd {
+	return tagCmd(t.current().id, t.current().session.Init())
+}
+
+func (t *tabs) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		return t.resize(msg)
+	case tabMsg:
+		return t.updateTab(msg)
+	case tea.KeyMsg:
+		if model, cmd, ok := t.handleTabKey(msg); ok {
+			return model, cmd
+		}
+	}
+
+	return t.route(t.current(), msg)
+}
+
+func (t *tabs) View() string {
+	session := t.current().session
+	if session.quitting || t.size.Width == 0 {
+		return session.View()
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, t.tabBarView(), session.View())
+}
+
+// current returns the tab shown.
+func (t *tabs) current() *tab {
+	return t.tabs[t.active]
+}
+
+// addTab appends a session configured by opts and shows it.
+func (t *tabs) addTab(opts Options) *tab {
+	t.nextID++
+
+	tb := &tab{id: t.nextID, session: New(t.ctx, t.creator, opts).(*model)}
+	tb.session.tabID = tb.id
+	t.tabs = append(t.tabs, tb)
+	t.active = len(t.tabs) - 1
+
+	return tb
+}
+
+// openTab shows a new session that crafts and executes with the models of
+// the current one.
+func (t *tabs) openTab() (tea.Model, tea.Cmd) {
+	from := t.current().session
+
+	opts := t.opts
+	opts.Model = from.selectedModel
+	opts.ExecuteModel = from.executeModel
+
+	tb := t.addTab(opts)
+	_, sizeCmd := t.route(tb, t.sessionSize())
+
+	return t, tea.Batch(tagCmd(tb.id, tb.session.Init()), sizeCmd)
+}
+
+// closeTab closes tb, canceling its request. The last tab stays open.
+func (t *tabs) closeTab(tb *tab) (tea.Model, tea.Cmd) {
+	if len(t.tabs) == 1 {
+		return t.route(tb, statusMessage(fmt.Sprintf("This is the only tab; %s quits.", t.keys.Quit.Help().Key)))
+	}
+
+	tb.session.cancel()
+
+	i := slices.Index(t.tabs, tb)
+	t.tabs = slices.Delete(t.tabs, i, i+1)
+
+	if t.active > i || t.active == len(t.tabs) {
+		t.active--
+	}
+
+	t.current().unseen = false
+
+	return t, nil
+}
+
+// show switches to tab i, wrapping around at either end.
+func (t *tabs) show(i int) (tea.Model, tea.Cmd) {
+	t.active = (i + len(t.tabs)) % len(t.tabs)
+	t.current().unseen = false
+
+	return t, nil
+}
+
+// handleTabKey opens, closes and switches tabs. Every other key goes to the
+// session shown.
+func (t *tabs) handleTabKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	var (
+		model tea.Model
+		cmd   tea.Cmd
+	)
+
+	switch {
+	case key.Matches(msg, t.keys.NewTab):
+		model, cmd = t.openTab()
+	case key.Matches(msg, t.keys.CloseTab):
+		model, cmd = t.closeTab(t.current())
+	case key.Matches(msg, t.keys.NextTab):
+		model, cmd = t.show(t.active + 1)
+	case key.Matches(msg, t.keys.PrevTab):
+		model, cmd = t.show(t.active - 1)
+	case key.Matches(msg, t.keys.GoToTab):
+		// The keys go to the tabs in order, the first to the first tab.
+		if i := slices.Index(t.keys.GoToTab.Keys(), msg.String()); i >= 0 && i < len(t.tabs) {
+			model, cmd = t.show(i)
+		} else {
+			model = t
+		}
+	default:
+		return nil, nil, false
+	}
+
+	return model, cmd, true
+}
+
+// updateTab delivers msg to the session of its tab, unless the tab was
+// closed, and marks responses arriving in the background.
+func (t *tabs) updateTab(msg tabMsg) (tea.Model, tea.Cmd) {
+	i := slices.IndexFunc(t.tabs, func(tb *tab) bool { return tb.id == msg.id })
+	if i < 0 {
+		return t, nil
+	}
+
+	tb := t.tabs[i]
+
+	switch msg.msg.(type) {
+	case newTabMsg:
+		return t.openTab()
+	case closeTabMsg:
+		return t.closeTab(tb)
+	case aiResponseMsg, errMsg:
+		tb.unseen = tb != t.current()
+	}
+
+	return t.route(tb, msg.msg)
+}
+
+// resize gives every session the window less the tab bar.
+func (t *tabs) resize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
+	t.size = msg
+
+	cmds := make([]tea.Cmd, 0, len(t.tabs))
+
+	for _, tb := range t.tabs {
+		_, cmd := t.route(tb, t.sessionSize())
+		cmds = append(cmds, cmd)
+	}
+
+	return t, tea.Batch(cmds...)
+}
+
+// sessionSize is the size of the window left for a session.
+func (t *tabs) sessionSize() tea.WindowSizeMsg {
+	return tea.WindowSizeMsg{Width: t.size.Width, Height: t.size.Height - tabBarHeight}
+}
+
+// route updates the session of tb with msg and tags the commands it returns.
+// A session that quits ends every request.
+func (t *tabs) route(tb *tab, msg tea.Msg) (tea.Model, tea.Cmd) {
+	_, cmd := tb.session.Update(msg)
+
+	if tb.session.quitting {
+		t.cancel()
+	}
+
+	return t, tagCmd(tb.id, cmd)
+}
+
+// tabBarView lists the tabs, numbered for the keys going to them.
+func (t *tabs) tabBarView() string {
+	labels := make([]string, len(t.tabs))
+
+	for i, tb := range t.tabs {
+		label := fmt.Sprintf("%d %s", i+1, tb.session.tabTitle())
+
+		switch {
+		case tb.session.state == viewBusy:
+			label += " ⋯"
+		case tb.unseen:
+			label += " •"
+		}
+
+		style := t.styles.Tab
+		if i == t.active {
+			style = t.styles.ActiveTab
+		}
+
+		labels[i] = style.Render(label)
+	}
+
+	return t.styles.Header.Render(lipgloss.JoinHorizontal(lipgloss.Top, labels...))
+}
+
+// tabTitle names the session by the start of its rough input.
+func (m *model) tabTitle() string {
+	title := m.editor.Value()
+	if len(m.stages) > 0 {
+		title = m.stages[0].text
+	}
+
+	title, _, _ = strings.Cut(strings.TrimSpace(title), "\n")
+	if title == "" {
+		return "new prompt"
+	}
+
+	if runes := []rune(title); len(runes) > tabTitleWidth {
+		return string(runes[:tabTitleWidth-1]) + "…"
+	}
+
+	return title
+}
+
+// teaPkgPath is the package of the messages the Bubble Tea runtime handles
+// itself, such as tea.QuitMsg and the one running an external process.
+func teaPkgPath() string {
+	return reflect.TypeFor[tea.QuitMsg]().PkgPath()
+}
+
+// tagCmd wraps the messages cmd produces in tabMsg for tab id, so they are
+// routed back to its session. Batches and sequences are tagged command by
+// command, and the runtime's own messages are passed through.
+func tagCmd(id int, cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+
+	return func() tea.Msg {
+		msg := cmd()
+		if msg == nil {
+			return nil
+		}
+
+		// tea.BatchMsg, and the unexported message of tea.Sequence, are
+		// lists of commands the runtime runs for the session.
+		if v := reflect.ValueOf(msg); v.Kind() == reflect.Slice && v.Type().Elem() == reflect.TypeFor[tea.Cmd]() {
+			tagged := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+			for i := range v.Len() {
+				c, _ := v.Index(i).Interface().(tea.Cmd)
+				tagged.Index(i).Set(reflect.ValueOf(tagCmd(id, c)))
+			}
+
+			return tagged.Interface()
+		}
+
+		if reflect.TypeOf(msg).PkgPath() == teaPkgPath() {
+			return msg
+		}
+
+		return tabMsg{id: id, msg: msg}
+	}
+}
+
+// tagExecMsg tags msg, returned by the callback of an external process the
+// session in tab id ran. The runtime delivers it without passing through
+// tagCmd. Sessions outside tabs have id 0 and get msg as is.
+func tagExecMsg(id int, msg tea.Msg) tea.Msg {
+	if id == 0 || msg == nil {
+		return msg
+	}
+
+	return tabMsg{id: id, msg: msg}
+}
@@ -6,8 +6,15 @@ import (
 	"fmt"
 	"time"
 
+	"prompt-maker/internal/attachment"
+	"prompt-maker/internal/cache"
 	"prompt-maker/internal/config"
 	"prompt-maker/internal/gemini"
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/library"
+	"prompt-maker/internal/placeholder"
+	"prompt-maker/internal/projectctx"
+	"prompt-maker/internal/prompt"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"google.golang.org/genai"
@@ -16,61 +23,99 @@ import (
 // Constants.
 const (
 	appName                   = "Prompt Maker"
-	textInputCharLimit        = 2000
+	editorHeight              = 5
 	horizontalPadding         = 2
 	headerPadding             = 1
 	initialViewportWidth      = 130
 	initialViewportHeight     = 36
 	copyStatusDuration        = time.Second * 2
-	placeholderRoughPrompt    = "Enter your rough prompt here..."
+	placeholderRoughPrompt    = "Enter your rough prompt here; alt+enter submits."
 	placeholderNewPrompt      = "Press Enter to start a new prompt."
-	placeholderResubmit       = "Press 'r' to resubmit, or type a new prompt."
+	placeholderResubmit       = "Type a prompt to execute, or press ctrl+z to restore the crafted one."
+	placeholderFollowUp       = "Ask a follow-up question; alt+enter sends it."
 	thinkingTextCrafting      = "Crafting prompt..."
 	thinkingTextGettingAnswer = "Getting a response..."
 	initialInstructionText    = "Enter a rough prompt for Lyra to improve."
 	goodbyeText               = "Goodbye!\n"
 	modelListHeight           = 14
+	thoughtsCollapsedText     = "▸ *Thoughts hidden — press ctrl+t to show.*"
+	thoughtsHeading           = "▾ **Thoughts** (ctrl+t to hide)"
 )
 
 var (
 	errPromptEmpty    = errors.New("prompt cannot be empty")
 	errClipboardWrite = errors.New("failed to write to clipboard")
+	errTemperature    = errors.New("temperature out of range")
+
+	errUnknownKeyAction = errors.New("unknown key action")
+	errNoKeys           = errors.New("no keys given for action")
 )
 
 // TUI Messages.
-type aiResponseMsg struct{ response string }
+type aiResponseMsg struct {
+	response string
+	// model answered the request sent to requestedModel; they differ when a
+	// fallback answered.
+	model          string
+	requestedModel string
+	thoughts       string
+	usage          prompt.Usage
+	// prompt is the text the response answers.
+	prompt string
+	// crafted marks a crafted prompt, and followUp the answer to a
+	// follow-up question in the chat; other responses are answers.
+	crafted  bool
+	followUp bool
+	// entryID is the history entry recording the response, if any.
+	entryID    string
+	historyErr error
+}
 type errMsg struct{ err error }
 type statusMessage string
 type clearStatusMsg struct{}
 
 func (e errMsg) Error() string { return e.err.Error() }
 
-// --- TUI Model ---
-
-// chatCreator defines an interface for creating chat sessions.
-type chatCreator interface {
-	Create(
-		ctx context.Context,
-		model string,
-		genConfig *genai.GenerateContentConfig,
-		history []*genai.Content,
-	) (gemini.ChatSession, error)
-}
-
-// genaiChatCreator holds the genai.Client to satisfy the chatCreator interface.
-type genaiChatCreator struct {
-	client *genai.Client
+// --- TUI Options ---
+
+// Options configures a new TUI model.
+type Options struct {
+	Version string
+	// Model crafts prompts, and ExecuteModel executes them; when it is
+	// empty, Model does both.
+	Model        string
+	ExecuteModel string
+	History      string
+	Temperature  float32
+	// Fallbacks are tried, in order, when Model cannot serve a request.
+	Fallbacks []string
+	// ThinkingBudget caps thinking tokens; nil keeps the model default.
+	ThinkingBudget *int32
+	// ShowThoughts requests thought summaries so they can be toggled in view.
+	ShowThoughts bool
+	// Attachments are sent as context with every prompt.
+	Attachments []attachment.Attachment
+	// Context holds project files; the most relevant ones that fit
+	// ContextBudget tokens are sent with each prompt.
+	Context       []projectctx.File
+	ContextBudget int
+	// Cache, when set, answers repeated temperature 0 requests.
+	Cache *cache.Store
+	// Recorder, when set, saves every craft and execution to history.
+	Recorder *history.Recorder
+	// Library, when set, enables saving crafted prompts and browsing them.
+	Library *library.Library
+	// Placeholders, when set, remembers the values filled into crafted
+	// prompts and suggests them again.
+	Placeholders *placeholder.Memory
+	// InputCharLimit caps the prompt editor; zero uses DefaultInputCharLimit.
+	InputCharLimit int
+	// Keys rebinds actions by name; see ValidateKeys.
+	Keys map[string][]string
 }
 
-// Create satisfies the chatCreator interface for the real implementation.
-func (c *genaiChatCreator) Create(
-	ctx context.Context,
-	model string,
-	genConfig *genai.GenerateContentConfig,
-	history []*genai.Content,
-) (gemini.ChatSession, error) {
-	return c.client.Chats.Create(ctx, model, genConfig, history)
-}
+// DefaultInputCharLimit is the default size limit of the prompt editor.
+const DefaultInputCharLimit = 100_000
 
 type viewState int
 
@@ -80,12 +125,33 @@ const (
 	viewBusy
 	viewResult
 	viewError
+	viewHistory
+	viewSaving
+	viewLibrary
+	viewExporting
+	viewRating
+	viewFilling
+	viewChat
+	viewPickingModel
+	viewPalette
 )
 
+// isOverlay reports whether the view is an overlay, which its Back key
+// closes and where the quit key does not quit.
+func (s viewState) isOverlay() bool {
+	switch s { //nolint:exhaustive // The other states quit on the quit key.
+	case viewHistory, viewSaving, viewLibrary, viewExporting, viewRating, viewFilling, viewPickingModel,
+		viewPalette:
+		return true
+	default:
+		return false
+	}
+}
+
 // --- TUI Starter ---
 
-// Start no longer takes a modelName.
-func Start(cfg *config.Config, version, modelName, history string, temperature float32) error {
+// Start creates the Gemini client and runs the TUI program until it exits.
+func Start(cfg *config.Config, opts Options) error {
 	ctx := context.Background()
 
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: cfg.APIKey, Backend: genai.BackendGeminiAPI})
@@ -93,9 +159,12 @@ func Start(cfg *config.Config, version, modelName, history string, temperature f
 		return fmt.Errorf("failed to create generative AI client: %w", err)
 	}
 
-	creator := &genaiChatCreator{client: client}
+	creator := gemini.NewChatCreator(client)
+	if opts.Cache != nil {
+		creator = gemini.NewCachingChatCreator(creator, opts.Cache)
+	}
 
-	p := tea.NewProgram(New(ctx, creator, version, modelName, history, temperature), tea.WithAltScreen(), tea.WithMouseCellMotion())
+	p := tea.NewProgram(NewTabs(ctx, creator, opts), tea.WithAltScreen(), tea.WithMouseCellMotion())
 	if _, err := p.Run(); err != nil {
 		return fmt.Errorf("error running TUI program: %w", err)
 	}
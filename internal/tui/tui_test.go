@@ -2,9 +2,21 @@ package tui
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"prompt-maker/internal/attachment"
 	"prompt-maker/internal/gemini"
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/library"
+	"prompt-maker/internal/placeholder"
+	"prompt-maker/internal/projectctx"
+	"prompt-maker/internal/prompt"
 	"prompt-maker/internal/testutil"
 	"prompt-maker/internal/tui/components"
 
@@ -14,7 +26,7 @@ import (
 	"google.golang.org/genai"
 )
 
-// mockChatCreator implements the chatCreator interface for testing.
+// mockChatCreator implements the gemini.ChatCreator interface for testing.
 type mockChatCreator struct {
 	createFunc func(
 		ctx context.Context, model string, genConfig *genai.GenerateContentConfig, history []*genai.Content,
@@ -31,7 +43,7 @@ func (m *mockChatCreator) Create(
 	return &testutil.MockChatSession{}, nil
 }
 
-// runCmds executes a tea.Cmd, handling batches, and returns all resulting messages.
+// runCmds executes a tea.Cmd, handling nested batches, and returns all resulting messages.
 func runCmds(cmd tea.Cmd) []tea.Msg {
 	if cmd == nil {
 		return nil
@@ -43,7 +55,7 @@ func runCmds(cmd tea.Cmd) []tea.Msg {
 
 	if batch, ok := msg.(tea.BatchMsg); ok {
 		for _, c := range batch {
-			msgs = append(msgs, c())
+			msgs = append(msgs, runCmds(c)...)
 		}
 	} else {
 		msgs = append(msgs, msg)
@@ -72,14 +84,14 @@ func (m mockModelOption) FilterValue() string {
 
 func TestUpdate_SubmitEmptyPrompt_ReturnsError(t *testing.T) {
 	// Arrange
-	m := New(context.Background(), &mockChatCreator{}, "v1", "", "", 0.0).(*model)
+	m := New(context.Background(), &mockChatCreator{}, Options{Version: "v1"}).(*model)
 	// Manually advance state past model selection for the test.
 	m.state = viewReady
 	m.selectedModel = "test-model"
-	m.textInput.SetValue("") // Ensure input is empty
+	m.editor.SetValue("") // Ensure input is empty
 
 	// Act
-	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
 	msgs := runCmds(cmd)
 
 	// Assert
@@ -100,7 +112,7 @@ func TestUpdate_SubmitEmptyPrompt_ReturnsError(t *testing.T) {
 
 func TestUpdate_ModelSelection_UpdatesState(t *testing.T) {
 	// Arrange
-	m := New(context.Background(), &mockChatCreator{}, "v1", "", "", 0.0).(*model)
+	m := New(context.Background(), &mockChatCreator{}, Options{Version: "v1"}).(*model)
 	require.Equal(t, viewSelectingModel, m.state)
 
 	// Act
@@ -179,15 +191,15 @@ func TestUpdate_SubmitRoughPrompt_GeneratesCraftedPrompt(t *testing.T) {
 
 	creator := newMockCreator(t, testModel, mockSession)
 
-	m := New(ctx, creator, "v1", "", "", 0.0).(*model)
+	m := New(ctx, creator, Options{Version: "v1"}).(*model)
 	// Manually advance state past model selection for the test.
 	m.state = viewReady
 	m.selectedModel = testModel
-	m.textInput.SetValue(userInput)
+	m.editor.SetValue(userInput)
 
 	// Act
 	// 1. User presses Enter, model becomes busy; command runs and returns the crafted prompt.
-	m, aiMsg := runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m, aiMsg := runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
 	require.Equal(t, craftedPrompt, aiMsg.response)
 
 	// 3. The model processes the AI response.
@@ -200,7 +212,55 @@ func TestUpdate_SubmitRoughPrompt_GeneratesCraftedPrompt(t *testing.T) {
 	require.Equal(t, viewReady, m.state)
 	require.Equal(t, craftedPrompt, m.craftedPrompt)
 	require.Contains(t, m.viewport.View(), craftedPrompt)
-	require.Equal(t, placeholderResubmit, m.textInput.Placeholder)
+	require.Equal(t, craftedPrompt, m.editor.Value(), "The crafted prompt is loaded for editing")
+	require.False(t, m.editor.Focused(), "The crafted prompt is reviewed before it is edited")
+	require.Equal(t, placeholderResubmit, m.editor.Placeholder)
+}
+
+func TestUpdate_EditCraftedPromptBeforeExecuting(t *testing.T) {
+	const testModel = "test-model"
+
+	mockSession := &testutil.MockChatSession{
+		SendMessageFunc: func(_ context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+			require.Equal(t, "crafted prompt, tweaked", parts[0].Text, "Should send the edited prompt")
+
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{Content: genai.NewContentFromText("answer", genai.RoleModel)}},
+			}, nil
+		},
+	}
+
+	m := New(context.Background(), newMockCreator(t, testModel, mockSession), Options{Version: "v1"}).(*model)
+	m.selectedModel = testModel
+	m.showCraftedPrompt("crafted prompt")
+
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune{'e'}},
+		{Type: tea.KeyRunes, Runes: []rune(", oops")},
+	} {
+		updatedModel, _ := m.Update(msg)
+		m = updatedModel.(*model)
+	}
+
+	require.Equal(t, "crafted prompt, oops", m.editor.Value())
+	require.Contains(t, m.statusBarView(), "ctrl+z reset to original")
+
+	updatedModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlZ})
+	m = updatedModel.(*model)
+	require.Equal(t, "crafted prompt", m.editor.Value())
+	require.True(t, m.reviewingCraftedPrompt())
+
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune{'e'}},
+		{Type: tea.KeyRunes, Runes: []rune(", tweaked")},
+	} {
+		updatedModel, _ = m.Update(msg)
+		m = updatedModel.(*model)
+	}
+
+	m, aiMsg := runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
+	require.Equal(t, "answer", aiMsg.response)
+	require.Equal(t, thinkingTextGettingAnswer, m.busyText)
 }
 
 func TestUpdate_ResubmitCraftedPrompt_GetsFinalAnswer(t *testing.T) {
@@ -228,11 +288,9 @@ func TestUpdate_ResubmitCraftedPrompt_GetsFinalAnswer(t *testing.T) {
 	creator := newMockCreator(t, testModel, mockSession)
 
 	// Start the model in the state where a prompt has been crafted.
-	m := New(ctx, creator, "v1", "", "", 0.0).(*model)
+	m := New(ctx, creator, Options{Version: "v1"}).(*model)
 	m.selectedModel = testModel // Set the model
-	m.state = viewReady
-	m.craftedPrompt = craftedPrompt
-	m.textInput.Placeholder = placeholderResubmit
+	m.showCraftedPrompt(craftedPrompt)
 
 	// Act
 	// 1. User presses 'r', model becomes busy; command runs and returns the final answer.
@@ -249,7 +307,291 @@ func TestUpdate_ResubmitCraftedPrompt_GetsFinalAnswer(t *testing.T) {
 	require.Equal(t, viewResult, m.state)
 	require.Empty(t, m.craftedPrompt)
 	require.Contains(t, m.viewport.View(), finalAnswer)
-	require.Equal(t, placeholderNewPrompt, m.textInput.Placeholder)
+	require.Equal(t, placeholderNewPrompt, m.editor.Placeholder)
+}
+
+func TestChat_FollowUpSendsTranscript(t *testing.T) {
+	const testModel = "test-model"
+
+	var history []*genai.Content
+
+	creator := &mockChatCreator{
+		createFunc: func(
+			_ context.Context, _ string, _ *genai.GenerateContentConfig, h []*genai.Content,
+		) (gemini.ChatSession, error) {
+			history = h
+
+			return &testutil.MockChatSession{
+				SendMessageFunc: func(_ context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+					require.Equal(t, "make it shorter", parts[0].Text)
+
+					return &genai.GenerateContentResponse{
+						Candidates: []*genai.Candidate{{Content: genai.NewContentFromText("short answer", genai.RoleModel)}},
+					}, nil
+				},
+			}, nil
+		},
+	}
+
+	m := New(context.Background(), creator, Options{Version: "v1"}).(*model)
+	m.selectedModel = testModel
+	m.state = viewResult
+	m.executedPrompt = "crafted prompt"
+	m.rawViewportContent = "long answer"
+
+	updatedModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	m = updatedModel.(*model)
+	require.Equal(t, viewChat, m.state)
+
+	m.editor.SetValue("make it shorter")
+
+	m, aiMsg := runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
+	require.Len(t, history, 2, "The follow-up continues from the prompt and its answer")
+	require.Equal(t, "long answer", history[1].Parts[0].Text)
+
+	updatedModel, _ = m.Update(aiMsg)
+	m = updatedModel.(*model)
+
+	require.Equal(t, viewChat, m.state)
+	require.Len(t, m.transcript, 4)
+	require.Empty(t, m.editor.Value())
+	require.Contains(t, m.viewport.View(), "short answer")
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlN})
+	m = updatedModel.(*model)
+	require.Equal(t, viewReady, m.state)
+	require.Nil(t, m.transcript)
+}
+
+func TestStages_NavigateAndRerunWithAnotherModel(t *testing.T) {
+	creator := &mockChatCreator{
+		createFunc: func(
+			_ context.Context, model string, _ *genai.GenerateContentConfig, _ []*genai.Content,
+		) (gemini.ChatSession, error) {
+			return &testutil.MockChatSession{
+				SendMessageFunc: func(_ context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+					text := "crafted prompt"
+					if parts[0].Text == "crafted prompt" {
+						text = "answer from " + model
+					}
+
+					return &genai.GenerateContentResponse{
+						Candidates: []*genai.Candidate{{Content: genai.NewContentFromText(text, genai.RoleModel)}},
+					}, nil
+				},
+			}, nil
+		},
+	}
+
+	m := New(context.Background(), creator, Options{Version: "v1", Model: "gemini-2.5-flash"}).(*model)
+	m.editor.SetValue("rough idea")
+
+	m, aiMsg := runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
+	updatedModel, _ := m.Update(aiMsg)
+	m = updatedModel.(*model)
+
+	m, aiMsg = runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	updatedModel, _ = m.Update(aiMsg)
+	m = updatedModel.(*model)
+	require.Equal(t, viewResult, m.state)
+	require.Len(t, m.stages, 3)
+	require.Contains(t, m.statusBarView(), "stage 3/3 (answer)")
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'['}})
+	m = updatedModel.(*model)
+	require.True(t, m.reviewingCraftedPrompt(), "The crafted prompt is kept after execution")
+	require.Equal(t, "crafted prompt", m.editor.Value())
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'['}})
+	m = updatedModel.(*model)
+	require.True(t, m.showingRoughInput())
+	require.Contains(t, m.viewport.View(), "rough idea")
+
+	for range 2 {
+		updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{']'}})
+		m = updatedModel.(*model)
+	}
+
+	require.Contains(t, m.viewport.View(), "answer from gemini-2.5-flash")
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	m = updatedModel.(*model)
+	require.Equal(t, viewPickingModel, m.state)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = updatedModel.(*model)
+
+	m, aiMsg = runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	updatedModel, _ = m.Update(aiMsg)
+	m = updatedModel.(*model)
+
+	require.Equal(t, "gemini-2.5-flash", m.selectedModel, "Crafting keeps its model")
+	require.Equal(t, "gemini-2.5-pro", m.executionModel())
+	require.Len(t, m.stages, 4, "The second answer to the same crafted prompt is added to the chain")
+	require.Contains(t, m.viewport.View(), "answer from gemini-2.5-pro")
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'['}})
+	m = updatedModel.(*model)
+	require.Contains(t, m.viewport.View(), "answer from gemini-2.5-flash")
+}
+
+func TestModelPicker_SeparateCraftAndExecuteModels(t *testing.T) {
+	var models []string
+
+	creator := &mockChatCreator{
+		createFunc: func(
+			_ context.Context, model string, _ *genai.GenerateContentConfig, _ []*genai.Content,
+		) (gemini.ChatSession, error) {
+			models = append(models, model)
+
+			return &testutil.MockChatSession{
+				SendMessageFunc: func(_ context.Context, _ ...genai.Part) (*genai.GenerateContentResponse, error) {
+					return &genai.GenerateContentResponse{
+						Candidates: []*genai.Candidate{{Content: genai.NewContentFromText("crafted prompt", genai.RoleModel)}},
+					}, nil
+				},
+			}, nil
+		},
+	}
+
+	m := New(context.Background(), creator, Options{Version: "v1", Model: "gemini-2.5-flash"}).(*model)
+	m.editor.SetValue("rough idea")
+
+	updatedModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	m = updatedModel.(*model)
+	require.Equal(t, viewPickingModel, m.state)
+	require.Equal(t, stepCraft, m.pickerStep)
+
+	for _, msg := range []tea.KeyMsg{{Type: tea.KeyTab}, {Type: tea.KeyDown}, {Type: tea.KeyEnter}} {
+		updatedModel, _ = m.Update(msg)
+		m = updatedModel.(*model)
+	}
+
+	require.Equal(t, viewReady, m.state)
+	require.Equal(t, "rough idea", m.editor.Value(), "The input is kept")
+	require.Equal(t, "gemini-2.5-flash → gemini-2.5-pro", m.modelLabel())
+
+	m, aiMsg := runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
+	updatedModel, _ = m.Update(aiMsg)
+	m = updatedModel.(*model)
+
+	_, _ = runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	require.Equal(t, []string{"gemini-2.5-flash", "gemini-2.5-pro"}, models)
+}
+
+func TestUpdate_CraftAndExecute_RecordsHistory(t *testing.T) {
+	const testModel = "test-model"
+
+	replies := []string{"crafted", "answer"}
+	mockSession := &testutil.MockChatSession{
+		SendMessageFunc: func(context.Context, ...genai.Part) (*genai.GenerateContentResponse, error) {
+			reply := replies[0]
+			replies = replies[1:]
+
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{Content: genai.NewContentFromText(reply, genai.RoleModel)}},
+			}, nil
+		},
+	}
+
+	store := history.NewFileStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	m := New(context.Background(), newMockCreator(t, testModel, mockSession), Options{
+		Version:  "v1",
+		Recorder: history.NewRecorder(store, history.SourceTUI, gemini.GenerationOptions{}),
+	}).(*model)
+	m.state = viewReady
+	m.selectedModel = testModel
+	m.editor.SetValue("rough idea")
+
+	m, aiMsg := runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
+	updatedModel, _ := m.Update(aiMsg)
+	m = updatedModel.(*model)
+
+	_, aiMsg = runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	require.NoError(t, aiMsg.historyErr)
+
+	entries, err := store.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "The answer completes the crafted entry")
+	require.Equal(t, "rough idea", entries[0].Input)
+	require.Equal(t, "crafted", entries[0].CraftedPrompt)
+	require.Equal(t, "answer", entries[0].Answer)
+	require.Equal(t, testModel, entries[0].Execute.Model)
+}
+
+func TestUpdate_Craft_UnsavedEntryIsNotKept(t *testing.T) {
+	const testModel = "test-model"
+
+	mockSession := &testutil.MockChatSession{
+		SendMessageFunc: func(context.Context, ...genai.Part) (*genai.GenerateContentResponse, error) {
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{Content: genai.NewContentFromText("crafted", genai.RoleModel)}},
+			}, nil
+		},
+	}
+
+	// A file in place of the history directory makes every save fail.
+	dir := filepath.Join(t.TempDir(), "history")
+	require.NoError(t, os.WriteFile(dir, nil, 0o600))
+
+	store := history.NewFileStore(filepath.Join(dir, "history.jsonl"))
+	m := New(context.Background(), newMockCreator(t, testModel, mockSession), Options{
+		Version:  "v1",
+		Recorder: history.NewRecorder(store, history.SourceTUI, gemini.GenerationOptions{}),
+	}).(*model)
+	m.state = viewReady
+	m.selectedModel = testModel
+	m.editor.SetValue("rough idea")
+
+	m, aiMsg := runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
+	require.Error(t, aiMsg.historyErr)
+	require.Empty(t, aiMsg.entryID)
+
+	updatedModel, _ := m.Update(aiMsg)
+	m = updatedModel.(*model)
+
+	require.Equal(t, "crafted", m.craftedPrompt, "The prompt is shown even when it cannot be saved")
+	require.False(t, m.canRate(), "An unsaved prompt cannot be rated")
+}
+
+func TestUpdate_CtrlT_TogglesThoughts(t *testing.T) {
+	m := New(context.Background(), &mockChatCreator{}, Options{Version: "v1"}).(*model)
+	m.state = viewReady
+	m.selectedModel = "test-model"
+
+	updatedModel, _ := m.Update(aiResponseMsg{
+		crafted:  true,
+		response: "The crafted prompt.",
+		thoughts: "Reasoning about the audience.",
+		usage:    prompt.Usage{PromptTokens: 1, OutputTokens: 2, ThinkingTokens: 3},
+	})
+	m = updatedModel.(*model)
+
+	require.NotContains(t, m.viewport.View(), "Reasoning about the audience.")
+	require.Contains(t, m.statusBarView(), "thinking 3")
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	m = updatedModel.(*model)
+
+	require.True(t, m.showThoughts)
+	require.Contains(t, m.viewport.View(), "Reasoning about the audience.")
+	require.Equal(t, "The crafted prompt.", m.rawViewportContent, "Copy should not include the thoughts")
+}
+
+func TestNewRequest_AddsRelevantContextFiles(t *testing.T) {
+	m := New(context.Background(), &mockChatCreator{}, Options{
+		Version: "v1",
+		Context: []projectctx.File{
+			{Path: "docs/intro.md", Content: "welcome"},
+			{Path: "internal/cache/cache.go", Content: "package cache"},
+		},
+		ContextBudget: 4,
+	}).(*model)
+
+	req := m.newRequest("explain the cache package", true)
+
+	require.Len(t, req.attachments, 1, "Only the most relevant file fits the budget")
+	require.Equal(t, "internal/cache/cache.go", req.attachments[0].Name)
 }
 
 func TestNewStyles(t *testing.T) {
@@ -262,8 +604,806 @@ func TestNewStyles(t *testing.T) {
 	require.NotNil(t, styles.Input)
 	require.NotNil(t, styles.StatusBar)
 	require.NotNil(t, styles.StatusText)
-	require.NotNil(t, styles.ResubmitHelp)
+	require.NotNil(t, styles.HelpKey)
 	require.NotNil(t, styles.SelectedListItem)
 	require.NotNil(t, styles.ListItem)
 	require.NotNil(t, styles.Spinner)
+	require.NotNil(t, styles.UserRole)
+	require.NotNil(t, styles.ModelRole)
+	require.NotNil(t, styles.Tab)
+	require.NotNil(t, styles.ActiveTab)
+}
+
+func newHistoryTestModel(t *testing.T, entries ...history.Entry) *model {
+	t.Helper()
+
+	store := history.NewFileStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	for _, e := range entries {
+		require.NoError(t, store.Save(context.Background(), e))
+	}
+
+	m := New(context.Background(), &mockChatCreator{}, Options{
+		Version:  "v1",
+		Recorder: history.NewRecorder(store, history.SourceTUI, gemini.GenerationOptions{}),
+	}).(*model)
+	m.state = viewReady
+	m.selectedModel = "test-model"
+
+	// Open the browser and deliver the loaded entries.
+	updatedModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	m = updatedModel.(*model)
+	require.Equal(t, viewHistory, m.state)
+
+	updatedModel, _ = m.Update(cmd())
+
+	return updatedModel.(*model)
+}
+
+func TestHistory_LoadCraftedPromptForEditing(t *testing.T) {
+	m := newHistoryTestModel(t,
+		history.Entry{ID: "old", CreatedAt: time.Unix(100, 0), Input: "old idea", CraftedPrompt: "old crafted"},
+		history.Entry{ID: "new", CreatedAt: time.Unix(200, 0), Input: "new idea", CraftedPrompt: "new crafted"},
+	)
+	require.Len(t, m.historyList.Items(), 2)
+	require.Contains(t, m.historyPreview.View(), "new crafted", "The newest entry is previewed first")
+
+	updatedModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = updatedModel.(*model)
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	m = updatedModel.(*model)
+
+	require.Equal(t, viewReady, m.state)
+	require.Equal(t, "old crafted", m.craftedPrompt)
+	require.Equal(t, "old crafted", m.editor.Value())
+	require.Equal(t, "old", m.entryID)
+}
+
+func TestHistory_EscGoesBackInsteadOfQuitting(t *testing.T) {
+	m := newHistoryTestModel(t)
+
+	updatedModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updatedModel.(*model)
+
+	require.False(t, m.quitting)
+	require.Equal(t, viewReady, m.state)
+}
+
+func TestHistory_ReexecuteSendsStoredPrompt(t *testing.T) {
+	m := newHistoryTestModel(t, history.Entry{
+		ID: "a", CreatedAt: time.Unix(100, 0), CraftedPrompt: "crafted", ExecutedPrompt: "edited", Answer: "answer",
+	})
+
+	updatedModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	m = updatedModel.(*model)
+
+	require.NotNil(t, cmd)
+	require.Equal(t, viewBusy, m.state)
+	require.Equal(t, "edited", m.craftedPrompt)
+}
+
+func newLibraryTestModel(t *testing.T) (*model, *library.Library) {
+	t.Helper()
+
+	lib := library.New(filepath.Join(t.TempDir(), "library"))
+
+	m := New(context.Background(), &mockChatCreator{}, Options{Version: "v1", Library: lib}).(*model)
+	m.state = viewReady
+	m.selectedModel = "test-model"
+
+	return m, lib
+}
+
+func TestLibrary_SaveCraftedPrompt(t *testing.T) {
+	m, lib := newLibraryTestModel(t)
+	m.craftedPrompt = "crafted prompt"
+
+	updatedModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	m = updatedModel.(*model)
+	require.Equal(t, viewSaving, m.state)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("Release Notes #docs")})
+	m = updatedModel.(*model)
+	updatedModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updatedModel.(*model)
+	require.NotNil(t, cmd)
+
+	updatedModel, _ = m.Update(cmd())
+	m = updatedModel.(*model)
+	require.Equal(t, viewReady, m.state)
+	require.Equal(t, "crafted prompt", m.craftedPrompt, "Saving keeps the crafted prompt")
+
+	p, err := lib.Get(context.Background(), "release-notes")
+	require.NoError(t, err)
+	require.Equal(t, "crafted prompt", p.Text())
+	require.Equal(t, []string{"docs"}, p.Tags)
+}
+
+func TestHistory_SizedOnResizeNotInView(t *testing.T) {
+	m := newHistoryTestModel(t,
+		history.Entry{ID: "new", CreatedAt: time.Unix(200, 0), Input: "new idea", CraftedPrompt: "new crafted"},
+	)
+
+	updatedModel, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	m = updatedModel.(*model)
+
+	width, height := m.historyPreview.Width, m.historyPreview.Height
+	require.Less(t, width, 120)
+	require.Less(t, height, 40)
+	require.Equal(t, height, m.historyList.Height())
+
+	_ = m.View()
+	require.Equal(t, width, m.historyPreview.Width, "View only renders")
+	require.Equal(t, height, m.historyPreview.Height)
+}
+
+func TestLibrary_BrowseDiffAndLoad(t *testing.T) {
+	m, lib := newLibraryTestModel(t)
+
+	_, err := lib.Save(context.Background(), "notes", "first line\n", library.SaveOptions{})
+	require.NoError(t, err)
+	_, err = lib.Save(context.Background(), "notes", "second line\n", library.SaveOptions{})
+	require.NoError(t, err)
+
+	updatedModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	m = updatedModel.(*model)
+	require.Equal(t, viewLibrary, m.state)
+
+	updatedModel, _ = m.Update(cmd())
+	m = updatedModel.(*model)
+	require.Len(t, m.libraryList.Items(), 1)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	m = updatedModel.(*model)
+	require.Contains(t, m.libraryPreview.View(), "+second line")
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updatedModel.(*model)
+	require.Equal(t, viewReady, m.state)
+	require.Equal(t, "second line\n", m.craftedPrompt)
+}
+
+func TestLibrary_RunFillsVariables(t *testing.T) {
+	const testModel = "test-model"
+
+	mockSession := &testutil.MockChatSession{
+		SendMessageFunc: func(_ context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+			require.Equal(t, "Write notes for v2.", parts[0].Text, "Should send the rendered prompt")
+
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{Content: genai.NewContentFromText("answer", genai.RoleModel)}},
+			}, nil
+		},
+	}
+
+	lib := library.New(filepath.Join(t.TempDir(), "library"))
+	_, err := lib.Save(context.Background(), "notes", "Write notes for {{ version }}.", library.SaveOptions{})
+	require.NoError(t, err)
+
+	m := New(context.Background(), newMockCreator(t, testModel, mockSession), Options{Version: "v1", Library: lib}).(*model)
+	m.state = viewReady
+	m.selectedModel = testModel
+
+	updatedModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	m = updatedModel.(*model)
+	updatedModel, _ = m.Update(cmd())
+	m = updatedModel.(*model)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	m = updatedModel.(*model)
+	require.Equal(t, viewFilling, m.state)
+	require.Equal(t, []string{"{{version}}"}, m.fillTokens, "The variable is asked for once, as the library names it")
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v2")})
+	m = updatedModel.(*model)
+
+	_, aiMsg := runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	require.Equal(t, "answer", aiMsg.response)
+}
+
+func TestParseSaveInput(t *testing.T) {
+	name, tags := parseSaveInput("  release notes #docs #team #")
+
+	require.Equal(t, "release notes", name)
+	require.Equal(t, []string{"docs", "team"}, tags)
+}
+
+func TestExport_WritesSessionTranscript(t *testing.T) {
+	store := history.NewFileStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	require.NoError(t, store.Save(context.Background(), history.Entry{
+		ID: "a", CreatedAt: time.Unix(100, 0), Input: "rough idea", CraftedPrompt: "crafted prompt",
+	}))
+
+	m := New(context.Background(), &mockChatCreator{}, Options{
+		Version:  "v1",
+		Recorder: history.NewRecorder(store, history.SourceTUI, gemini.GenerationOptions{}),
+	}).(*model)
+	m.state = viewReady
+
+	updatedModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlE})
+	m = updatedModel.(*model)
+	require.Equal(t, viewReady, m.state, "Nothing to export before a response")
+	require.Equal(t, statusMessage("Nothing to export yet."), cmd())
+
+	updatedModel, _ = m.Update(aiResponseMsg{crafted: true, response: "crafted prompt", entryID: "a"})
+	m = updatedModel.(*model)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlE})
+	m = updatedModel.(*model)
+	require.Equal(t, viewExporting, m.state)
+	require.Contains(t, m.exportInput.Value(), ".md")
+
+	path := filepath.Join(t.TempDir(), "session.html")
+	m.exportInput.SetValue(path)
+
+	updatedModel, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updatedModel.(*model)
+	updatedModel, _ = m.Update(cmd())
+	m = updatedModel.(*model)
+	require.Equal(t, viewReady, m.state)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "<title>rough idea</title>")
+}
+
+func TestRating_RatesAnswerWithNote(t *testing.T) {
+	store := history.NewFileStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	require.NoError(t, store.Save(context.Background(), history.Entry{ID: "a", CraftedPrompt: "crafted", Answer: "answer"}))
+
+	m := New(context.Background(), &mockChatCreator{}, Options{
+		Version:  "v1",
+		Recorder: history.NewRecorder(store, history.SourceTUI, gemini.GenerationOptions{}),
+	}).(*model)
+	m.state = viewResult
+	m.entryID = "a"
+	m.width = 120
+	require.Contains(t, m.statusBarView(), "+ rate up • - rate down")
+
+	updatedModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'-'}})
+	m = updatedModel.(*model)
+	require.Equal(t, viewRating, m.state)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("too vague")})
+	m = updatedModel.(*model)
+	updatedModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updatedModel.(*model)
+	updatedModel, _ = m.Update(cmd())
+	m = updatedModel.(*model)
+	require.Equal(t, viewResult, m.state)
+
+	e, err := store.Get(context.Background(), "a")
+	require.NoError(t, err)
+	require.Equal(t, &history.Feedback{Rating: history.RatingDown, Note: "too vague", At: e.AnswerFeedback.At}, e.AnswerFeedback)
+	require.Nil(t, e.CraftFeedback)
+}
+
+func TestRating_EscCancels(t *testing.T) {
+	m := newHistoryTestModel(t)
+	m.showCraftedPrompt("crafted")
+	m.entryID = "a"
+
+	updatedModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'+'}})
+	m = updatedModel.(*model)
+	require.Equal(t, viewRating, m.state)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updatedModel.(*model)
+	require.Equal(t, viewReady, m.state)
+	require.False(t, m.quitting)
+}
+
+func TestEditor_EnterAddsNewlineAndCtrlJSubmits(t *testing.T) {
+	m := New(context.Background(), &mockChatCreator{}, Options{Version: "v1", InputCharLimit: 50}).(*model)
+	m.state = viewReady
+	m.selectedModel = "test-model"
+
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("first line")},
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyRunes, Runes: []rune("- item\n- pasted item"), Paste: true},
+	} {
+		updatedModel, _ := m.Update(msg)
+		m = updatedModel.(*model)
+	}
+
+	require.Equal(t, viewReady, m.state, "Enter does not submit")
+	require.Equal(t, "first line\n- item\n- pasted item", m.editor.Value())
+	require.Contains(t, m.statusBarView(), "31/50 chars · ~8 tokens")
+
+	updatedModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlJ})
+	m = updatedModel.(*model)
+	require.Equal(t, viewBusy, m.state)
+	require.NotNil(t, cmd)
+}
+
+func TestExternalEditorCommand(t *testing.T) {
+	t.Setenv("VISUAL", "code --wait")
+	t.Setenv("EDITOR", "vim")
+
+	require.Equal(t, []string{"code", "--wait", "prompt.md"}, externalEditorCommand("prompt.md").Args)
+
+	t.Setenv("VISUAL", "")
+	require.Equal(t, []string{"vim", "prompt.md"}, externalEditorCommand("prompt.md").Args)
+
+	t.Setenv("EDITOR", "")
+	require.Equal(t, []string{"vi", "prompt.md"}, externalEditorCommand("prompt.md").Args)
+
+	t.Setenv("VISUAL", "  ")
+	require.Equal(t, []string{"vi", "prompt.md"}, externalEditorCommand("prompt.md").Args,
+		"Whitespace-only variables should fall back to vi")
+}
+
+func TestExternalEdit_RunEditsTemporaryFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The fake editor is a shell script")
+	}
+
+	script := filepath.Join(t.TempDir(), "editor")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho ', then edited' >> \"$1\"\n"), 0o700))
+	t.Setenv("VISUAL", script)
+
+	edit := &externalEdit{text: "crafted"}
+	require.NoError(t, edit.Run())
+	require.Equal(t, "crafted, then edited\n", edit.text)
+}
+
+func TestExternalEditor_LoadsEditedText(t *testing.T) {
+	m := New(context.Background(), &mockChatCreator{}, Options{Version: "v1"}).(*model)
+	m.state = viewReady
+	m.craftedPrompt = "crafted"
+
+	updatedModel, _ := m.Update(externalEditedMsg{text: "crafted, then edited\n"})
+	m = updatedModel.(*model)
+	require.Equal(t, "crafted, then edited", m.editor.Value())
+
+	_, cmd := m.Update(externalEditedMsg{err: os.ErrNotExist})
+	require.Equal(t, statusMessage("Editor failed: file does not exist"), cmd())
+}
+
+func TestPlaceholders_FillBeforeExecuting(t *testing.T) {
+	const testModel = "test-model"
+
+	mockSession := &testutil.MockChatSession{
+		SendMessageFunc: func(_ context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+			require.Equal(t, "Email Acme about the launch.", parts[0].Text, "Should send the filled prompt")
+
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{Content: genai.NewContentFromText("answer", genai.RoleModel)}},
+			}, nil
+		},
+	}
+
+	memory := placeholder.NewMemory(filepath.Join(t.TempDir(), "placeholders.json"))
+	require.NoError(t, memory.Remember(map[string]string{"[Company]": "Acme"}))
+
+	m := New(context.Background(), newMockCreator(t, testModel, mockSession), Options{
+		Version:      "v1",
+		Placeholders: memory,
+	}).(*model)
+	m.selectedModel = testModel
+	m.showCraftedPrompt("Email [Company] about {topic}.")
+
+	updatedModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	m = updatedModel.(*model)
+	require.Equal(t, viewFilling, m.state)
+
+	for _, msg := range runCmds(cmd) {
+		updatedModel, _ = m.Update(msg)
+		m = updatedModel.(*model)
+	}
+
+	require.Equal(t, "Acme", m.fillInput.Value(), "The value used before is suggested")
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updatedModel.(*model)
+	require.Contains(t, m.fillInput.Prompt, "{topic} (2/2)")
+
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	require.Equal(t, statusMessage("Fill in {topic}, or press esc to edit the prompt."), cmd())
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("the launch")})
+	m = updatedModel.(*model)
+
+	m, aiMsg := runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	require.Equal(t, "answer", aiMsg.response)
+
+	values, err := memory.Load()
+	require.NoError(t, err)
+	require.Equal(t, "the launch", values.Last("{topic}"))
+}
+
+func TestPlaceholders_EscReturnsToPrompt(t *testing.T) {
+	m := New(context.Background(), &mockChatCreator{}, Options{Version: "v1"}).(*model)
+	m.showCraftedPrompt("Write to [Name].")
+
+	updatedModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	m = updatedModel.(*model)
+	require.Equal(t, viewFilling, m.state)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updatedModel.(*model)
+	require.False(t, m.quitting)
+	require.Equal(t, viewReady, m.state)
+	require.Equal(t, "Write to [Name].", m.editor.Value())
+}
+
+// typeCommand replaces the editor contents with text and presses enter.
+func typeCommand(t *testing.T, m *model, text string) (*model, tea.Cmd) {
+	t.Helper()
+
+	m.editor.SetValue(text)
+	updatedModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	return updatedModel.(*model), cmd
+}
+
+func TestSlashCommands(t *testing.T) {
+	m := New(context.Background(), &mockChatCreator{}, Options{Version: "v1", Model: "gemini-2.5-flash"}).(*model)
+
+	m, _ = typeCommand(t, m, "/bogus")
+	require.True(t, m.inlineErr)
+	require.Contains(t, m.footerView(), "Unknown command /bogus")
+	require.Equal(t, "/bogus", m.editor.Value(), "A failed command stays for fixing")
+
+	m, _ = typeCommand(t, m, "/temp 3")
+	require.Contains(t, m.inlineText, "Usage: /temp")
+
+	m, cmd := typeCommand(t, m, "/temp 0.7")
+	require.Empty(t, m.inlineText)
+	require.Empty(t, m.editor.Value())
+	require.Equal(t, statusMessage("Temperature set to 0.7."), cmd())
+	require.InDelta(t, 0.7, m.temperature, 1e-6)
+
+	m, _ = typeCommand(t, m, "/model flash")
+	require.Contains(t, m.inlineText, "be more specific")
+
+	m, _ = typeCommand(t, m, "/model pro")
+	require.Equal(t, "gemini-2.5-pro", m.selectedModel)
+
+	path := filepath.Join(t.TempDir(), "notes.md")
+	require.NoError(t, os.WriteFile(path, []byte("# Notes"), 0o600))
+
+	m, cmd = typeCommand(t, m, "/attach "+path)
+	updatedModel, _ := m.Update(cmd())
+	m = updatedModel.(*model)
+	require.Equal(t, []string{"notes.md"}, attachment.Names(m.attachments))
+
+	m, _ = typeCommand(t, m, "/help")
+	require.False(t, m.inlineErr)
+	require.Contains(t, m.footerView(), "/export")
+}
+
+func TestSlashCommands_TabCompletes(t *testing.T) {
+	m := New(context.Background(), &mockChatCreator{}, Options{Version: "v1", Model: "gemini-2.5-flash"}).(*model)
+
+	m.editor.SetValue("/hi")
+	updatedModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updatedModel.(*model)
+	require.Equal(t, "/history ", m.editor.Value())
+
+	m.editor.SetValue("/model gemini-2.5-f")
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updatedModel.(*model)
+	require.Equal(t, "/model gemini-2.5-flash", m.editor.Value(), "Completes the shared start")
+	require.Contains(t, m.inlineText, "gemini-2.5-flash-lite")
+}
+
+func TestSubmitPrompt_DoubledSlashSendsPrompt(t *testing.T) {
+	mockSession := &testutil.MockChatSession{
+		SendMessageFunc: func(_ context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+			require.Contains(t, parts[0].Text, "/etc/hosts explained")
+			require.NotContains(t, parts[0].Text, "//etc")
+
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{Content: genai.NewContentFromText("crafted", genai.RoleModel)}},
+			}, nil
+		},
+	}
+
+	m := New(context.Background(), newMockCreator(t, "test-model", mockSession), Options{Version: "v1", Model: "test-model"}).(*model)
+	m.editor.SetValue("//etc/hosts explained")
+
+	_, aiMsg := runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
+	require.Equal(t, "crafted", aiMsg.response)
+}
+
+func TestPalette_FuzzySearchesActionsThatApply(t *testing.T) {
+	m := New(context.Background(), &mockChatCreator{}, Options{Version: "v1", Model: "test-model"}).(*model)
+	m.state = viewResult
+	m.rawViewportContent = "**answer**"
+
+	updatedModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	m = updatedModel.(*model)
+	require.Equal(t, viewPalette, m.state)
+	require.Contains(t, m.paletteView(), "Ask a follow-up question")
+	require.NotContains(t, m.paletteView(), "Execute the crafted prompt", "Only actions that apply are listed")
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("rawvw")})
+	m = updatedModel.(*model)
+	require.Equal(t, "Toggle raw view", m.paletteMatches[0].title)
+	require.Contains(t, m.paletteView(), "  v")
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updatedModel.(*model)
+	require.Equal(t, viewResult, m.state)
+	require.True(t, m.showRaw)
+	require.Contains(t, m.viewport.View(), "**answer**")
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	m = updatedModel.(*model)
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updatedModel.(*model)
+	require.Equal(t, viewResult, m.state, "esc closes the palette instead of quitting")
+	require.False(t, m.quitting)
+}
+
+func TestKeys_RebindFromConfigAndToggleFullHelp(t *testing.T) {
+	keys := map[string][]string{"quit": {"ctrl+q"}, "copy": {"y"}}
+	require.NoError(t, ValidateKeys(keys))
+	require.ErrorIs(t, ValidateKeys(map[string][]string{"launch": {"l"}}), errUnknownKeyAction)
+	require.ErrorIs(t, ValidateKeys(map[string][]string{"quit": {}}), errNoKeys)
+
+	m := New(context.Background(), &mockChatCreator{}, Options{Version: "v1", Model: "test-model", Keys: keys}).(*model)
+	m.state = viewResult
+	m.rawViewportContent = "answer"
+
+	updatedModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updatedModel.(*model)
+	require.False(t, m.quitting, "esc no longer quits once quit is rebound")
+	require.Nil(t, cmd)
+	require.Contains(t, m.statusBarView(), "y copy")
+	require.Contains(t, m.statusBarView(), "ctrl+q quit")
+	require.NotContains(t, m.statusBarView(), "ctrl+c quit", "Only the short help shows")
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'?'}})
+	m = updatedModel.(*model)
+	require.True(t, m.help.ShowAll)
+	require.Contains(t, m.statusBarView(), "ctrl+c quit")
+
+	m.resetToReady()
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'?'}})
+	m = updatedModel.(*model)
+	require.Equal(t, "?", m.editor.Value(), "? is typed while editing a prompt")
+
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyCtrlQ})
+	require.NotNil(t, cmd)
+	require.True(t, m.quitting)
+}
+
+func TestKeys_RebindOverlayKeys(t *testing.T) {
+	keys := map[string][]string{"back": {"ctrl+b"}, "down": {"ctrl+j"}}
+	require.NoError(t, ValidateKeys(keys))
+
+	m := New(context.Background(), &mockChatCreator{}, Options{Version: "v1", Model: "test-model", Keys: keys}).(*model)
+	m.state = viewReady
+
+	updatedModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	m = updatedModel.(*model)
+	require.Equal(t, viewPalette, m.state)
+	require.Contains(t, m.statusBarView(), "ctrl+b close")
+	require.Contains(t, m.statusBarView(), "ctrl+j down")
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updatedModel.(*model)
+	require.Equal(t, viewPalette, m.state, "esc no longer closes the palette once back is rebound")
+	require.False(t, m.quitting)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlJ})
+	m = updatedModel.(*model)
+	require.Equal(t, 1, m.paletteCursor)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlB})
+	m = updatedModel.(*model)
+	require.Equal(t, viewReady, m.state)
+}
+
+func TestCancel_StopsRequestAndKeepsInput(t *testing.T) {
+	mockSession := &testutil.MockChatSession{
+		SendMessageFunc: func(ctx context.Context, _ ...genai.Part) (*genai.GenerateContentResponse, error) {
+			<-ctx.Done()
+
+			return nil, ctx.Err()
+		},
+	}
+
+	m := New(context.Background(), newMockCreator(t, "test-model", mockSession), Options{Version: "v1", Model: "test-model"}).(*model)
+	m.editor.SetValue("a slow prompt")
+
+	updatedModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlJ})
+	m = updatedModel.(*model)
+	require.Equal(t, viewBusy, m.state)
+	require.Contains(t, m.statusBarView(), "ctrl+x cancel")
+
+	msgs := make(chan []tea.Msg)
+	go func() { msgs <- runCmds(cmd) }()
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlX})
+	m = updatedModel.(*model)
+	require.Equal(t, viewReady, m.state)
+	require.False(t, m.quitting, "Canceling does not quit")
+	require.Equal(t, "a slow prompt", m.editor.Value())
+
+	got := <-msgs
+	require.Contains(t, got, tea.Msg(canceledMsg{}), "The canceled request reports nothing else")
+
+	updatedModel, _ = m.Update(canceledMsg{})
+	m = updatedModel.(*model)
+	require.Equal(t, viewReady, m.state)
+}
+
+func TestCancel_ReturnsToTheViewTheRequestStartedFrom(t *testing.T) {
+	replies := []string{"Email [Company].", "answer"}
+	mockSession := &testutil.MockChatSession{
+		SendMessageFunc: func(context.Context, ...genai.Part) (*genai.GenerateContentResponse, error) {
+			reply := replies[0]
+			replies = replies[1:]
+
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{Content: genai.NewContentFromText(reply, genai.RoleModel)}},
+			}, nil
+		},
+	}
+	creator := &mockChatCreator{
+		createFunc: func(context.Context, string, *genai.GenerateContentConfig, []*genai.Content) (gemini.ChatSession, error) {
+			return mockSession, nil
+		},
+	}
+
+	m := New(context.Background(), creator, Options{Version: "v1", Model: "test-model"}).(*model)
+	m.editor.SetValue("rough idea")
+
+	m, aiMsg := runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
+	updatedModel, _ := m.Update(aiMsg)
+	m = updatedModel.(*model)
+
+	// Executing the crafted prompt asks for its placeholder first.
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
+	m = updatedModel.(*model)
+	require.Equal(t, viewFilling, m.state)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("Acme")})
+	m = updatedModel.(*model)
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updatedModel.(*model)
+	require.Equal(t, viewBusy, m.state)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlX})
+	m = updatedModel.(*model)
+	require.Equal(t, viewFilling, m.state, "Canceling keeps the placeholder values")
+	require.Equal(t, "Acme", m.fillInput.Value())
+	require.Equal(t, "Email [Company].", m.craftedPrompt)
+
+	m, aiMsg = runUpdateAndFindAIResponse(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	updatedModel, _ = m.Update(aiMsg)
+	m = updatedModel.(*model)
+	require.Equal(t, viewResult, m.state)
+
+	// Executing again with another model starts from the answer.
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	m = updatedModel.(*model)
+	require.Equal(t, viewPickingModel, m.state)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updatedModel.(*model)
+	require.Equal(t, viewBusy, m.state)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlX})
+	m = updatedModel.(*model)
+	require.Equal(t, viewResult, m.state, "Canceling a rerun keeps the answer")
+	require.Equal(t, "Email Acme.", m.executedPrompt)
+	require.Equal(t, "answer", m.rawViewportContent)
+}
+
+func TestTabs_RequestKeepsRunningInItsTab(t *testing.T) {
+	release := make(chan struct{})
+	mockSession := &testutil.MockChatSession{
+		SendMessageFunc: func(_ context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+			answer := "crafted fast prompt"
+			if strings.HasSuffix(parts[0].Text, "slow prompt") {
+				<-release
+
+				answer = "crafted slow prompt"
+			}
+
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{Content: genai.NewContentFromText(answer, genai.RoleModel)}},
+			}, nil
+		},
+	}
+
+	tm := NewTabs(context.Background(), newMockCreator(t, "test-model", mockSession), Options{Version: "v1", Model: "test-model"})
+	ts := tm.(*tabs)
+	ts.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	first := ts.current()
+	first.session.editor.SetValue("slow prompt")
+	_, slowCmd := ts.Update(tea.KeyMsg{Type: tea.KeyCtrlJ})
+	require.Equal(t, viewBusy, first.session.state)
+
+	slowMsgs := make(chan []tea.Msg)
+	go func() { slowMsgs <- runCmds(slowCmd) }()
+
+	ts.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}, Alt: true})
+	require.Len(t, ts.tabs, 2)
+
+	second := ts.current()
+	require.Equal(t, viewReady, second.session.state, "A new tab starts with the model of the first")
+	require.Equal(t, 100, second.session.width)
+
+	second.session.editor.SetValue("fast prompt")
+	_, fastCmd := ts.Update(tea.KeyMsg{Type: tea.KeyCtrlJ})
+
+	for _, msg := range runCmds(fastCmd) {
+		ts.Update(msg)
+	}
+
+	require.Equal(t, "crafted fast prompt", second.session.craftedPrompt)
+	require.Equal(t, viewBusy, first.session.state, "The first tab is still waiting")
+	require.Contains(t, ts.View(), "1 slow prompt ⋯")
+
+	close(release)
+
+	for _, msg := range <-slowMsgs {
+		ts.Update(msg)
+	}
+
+	require.Equal(t, "crafted slow prompt", first.session.craftedPrompt)
+	require.Equal(t, "crafted fast prompt", second.session.craftedPrompt, "The answer went to its own tab")
+	require.True(t, first.unseen)
+	require.Contains(t, ts.tabBarView(), "1 slow prompt •")
+
+	ts.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'1'}, Alt: true})
+	require.Same(t, first, ts.current())
+	require.False(t, first.unseen)
+
+	ts.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'w'}, Alt: true})
+	require.Len(t, ts.tabs, 1)
+	require.Same(t, second, ts.current())
+}
+
+func TestTagCmd_TagsSequencesAndExecCallbacks(t *testing.T) {
+	msgCmd := func(msg tea.Msg) tea.Cmd { return func() tea.Msg { return msg } }
+
+	seq := reflect.ValueOf(tagCmd(2, tea.Sequence(msgCmd(statusMessage("a")), msgCmd(tea.QuitMsg{})))())
+	require.Equal(t, reflect.Slice, seq.Kind(), "The sequence is still run by the runtime")
+	require.Equal(t, 2, seq.Len())
+	require.Equal(t, tabMsg{id: 2, msg: statusMessage("a")}, seq.Index(0).Interface().(tea.Cmd)())
+	require.Equal(t, tea.QuitMsg{}, seq.Index(1).Interface().(tea.Cmd)())
+
+	ts := NewTabs(context.Background(), &mockChatCreator{}, Options{Version: "v1", Model: "test-model"}).(*tabs)
+	ts.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}, Alt: true})
+
+	second := ts.current()
+	require.Equal(t, second.id, second.session.tabID)
+	require.Equal(t, tabMsg{id: second.id, msg: externalEditedMsg{text: "edited"}},
+		tagExecMsg(second.session.tabID, externalEditedMsg{text: "edited"}))
+	require.Equal(t, externalEditedMsg{text: "edited"}, tagExecMsg(0, externalEditedMsg{text: "edited"}),
+		"Sessions outside tabs get the message as is")
+
+	ts.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'1'}, Alt: true})
+	updatedModel, _ := ts.Update(tagExecMsg(second.id, externalEditedMsg{text: "edited"}))
+	ts = updatedModel.(*tabs)
+	require.Equal(t, "edited", second.session.editor.Value(), "The edit goes to the tab that opened the editor")
+	require.Empty(t, ts.current().session.editor.Value())
+}
+
+func TestTabs_RemappedKeys(t *testing.T) {
+	ts := NewTabs(context.Background(), &mockChatCreator{}, Options{
+		Version: "v1",
+		Model:   "test-model",
+		Keys:    map[string][]string{"go_to_tab": {"f1", "f2"}, "quit": {"q"}},
+	}).(*tabs)
+	first := ts.current()
+
+	ts.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'w'}, Alt: true})
+	require.Equal(t, "This is the only tab; q quits.", first.session.statusMessage)
+
+	ts.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}, Alt: true})
+	second := ts.current()
+
+	ts.Update(tea.KeyMsg{Type: tea.KeyF1})
+	require.Same(t, first, ts.current())
+
+	ts.Update(tea.KeyMsg{Type: tea.KeyF2})
+	require.Same(t, second, ts.current())
 }
@@ -0,0 +1,114 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+
+	"prompt-maker/internal/attachment"
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/prompt"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v5"
+	"google.golang.org/genai"
+)
+
+const (
+	// chatField carries the ID of the conversation a follow-up continues.
+	chatField = "chat"
+	// maxChats caps the conversations kept; the oldest are dropped first.
+	maxChats = 256
+)
+
+// chats keeps the conversations following executed prompts on the server,
+// keyed by an ID the follow-up form posts. It is safe for concurrent use.
+type chats struct {
+	mu    sync.Mutex
+	turns map[string][]prompt.Turn
+	// order lists the IDs oldest first, for dropping them.
+	order []string
+}
+
+func newChats() *chats {
+	return &chats{turns: make(map[string][]prompt.Turn)}
+}
+
+// start keeps a conversation opened by the executed prompt and its answer
+// and returns its ID.
+func (c *chats) start(executed, answer string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := history.NewID()
+	c.turns[id] = []prompt.Turn{
+		{Role: genai.RoleUser, Text: executed},
+		{Role: genai.RoleModel, Text: answer},
+	}
+	c.order = append(c.order, id)
+
+	if len(c.order) > maxChats {
+		delete(c.turns, c.order[0])
+		c.order = slices.Delete(c.order, 0, 1)
+	}
+
+	return id
+}
+
+// transcript returns the turns of conversation id so far.
+func (c *chats) transcript(id string) ([]prompt.Turn, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	turns, ok := c.turns[id]
+
+	return slices.Clone(turns), ok
+}
+
+// add appends a question and its answer to conversation id and returns the
+// number of turns it has, or zero when it was dropped meanwhile.
+func (c *chats) add(id, question, answer string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	turns, ok := c.turns[id]
+	if !ok {
+		return 0
+	}
+
+	turns = append(turns,
+		prompt.Turn{Role: genai.RoleUser, Text: question},
+		prompt.Turn{Role: genai.RoleModel, Text: answer},
+	)
+	c.turns[id] = turns
+
+	return len(turns)
+}
+
+// handleChat answers a follow-up question in the posted conversation.
+// Follow-ups are not recorded to history.
+func (s *Server) handleChat(c *echo.Context) error {
+	id := c.FormValue(chatField)
+
+	transcript, ok := s.chats.transcript(id)
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "Execute a prompt before asking a follow-up question.")
+	}
+
+	question := c.FormValue("prompt")
+
+	followUp := func(ctx context.Context, model, input string, attachments ...attachment.Attachment) (prompt.Result, error) {
+		return s.generator.FollowUp(ctx, model, transcript, input, attachments...)
+	}
+
+	return s.handleGenerate(c, question, followUp, nil,
+		"The AI failed to answer the follow-up question. Please try again.",
+		func(resp responseView, modelName string) templ.Component {
+			resp.ChatID = id
+			resp.ChatTurns = s.chats.add(id, question, resp.Raw)
+
+			return chatTurnComponent(question, resp, modelName)
+		},
+	)
+}
@@ -0,0 +1,59 @@
+package web
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"prompt-maker/internal/export"
+	"prompt-maker/internal/history"
+
+	"github.com/labstack/echo/v5"
+)
+
+// exportFormats returns the transcript formats offered for download, in the
+// order they are linked.
+func exportFormats() []export.Format {
+	return []export.Format{export.Markdown, export.HTML, export.JSON}
+}
+
+// exportPath downloads the transcript of the entry id in format.
+func exportPath(id string, format export.Format) string {
+	return "/export/" + url.PathEscape(id) + "?format=" + format.Ext()
+}
+
+// handleExport downloads a history entry as a transcript in the "format"
+// query parameter, Markdown by default. HTML transcripts use the "theme"
+// query parameter.
+func (s *Server) handleExport(c *echo.Context) error {
+	if s.history == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "History is disabled.")
+	}
+
+	format, err := export.ParseFormat(cmp.Or(c.QueryParam("format"), export.Markdown.Ext()))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Use md, html or json as the format.")
+	}
+
+	theme := cmp.Or(c.QueryParam("theme"), export.DefaultTheme)
+	if !export.KnownTheme(theme) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown theme.")
+	}
+
+	entry, err := s.history.Get(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, history.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "No such history entry.")
+	}
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "History could not be read.").Wrap(err)
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, format.ContentType())
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="prompt-maker-%s.%s"`, entry.ID, format.Ext()))
+
+	return export.Write(c.Response(), []history.Entry{entry}, export.Options{Format: format, Theme: theme})
+}
@@ -2,59 +2,58 @@ package web
 
 import (
 	"context"
-	"fmt"
 
-	"prompt-maker/internal/config"
+	"prompt-maker/internal/attachment"
+	"prompt-maker/internal/gemini"
 	"prompt-maker/internal/prompt"
-
-	"google.golang.org/genai"
 )
 
-// PromptGenerator methods now accept the modelName for each request.
+// PromptGenerator methods accept the modelName for each request and report
+// which model actually answered.
 type PromptGenerator interface {
-	Generate(ctx context.Context, modelName, userInput string) (string, error)
-	Execute(ctx context.Context, modelName, userInput string) (string, error)
+	Generate(ctx context.Context, modelName, userInput string, attachments ...attachment.Attachment) (prompt.Result, error)
+	Execute(ctx context.Context, modelName, userInput string, attachments ...attachment.Attachment) (prompt.Result, error)
+	FollowUp(
+		ctx context.Context, modelName string, transcript []prompt.Turn, question string, attachments ...attachment.Attachment,
+	) (prompt.Result, error)
 	GetModelNames() []string
 }
 
-// The struct no longer needs to store the modelName.
+// geminiPromptGenerator delegates to a prompt.Runner so that web requests
+// share the TUI's fallback behavior.
 type geminiPromptGenerator struct {
-	client *genai.Client
+	runner *prompt.Runner
 }
 
-// NewGeminiPromptGenerator returns a PromptGenerator backed by the Gemini API.
-func NewGeminiPromptGenerator(client *genai.Client) PromptGenerator {
+// NewGeminiPromptGenerator returns a PromptGenerator backed by the Gemini API
+// that retries on the fallback models, in order, when the chosen one is unavailable.
+func NewGeminiPromptGenerator(
+	creator gemini.ChatCreator, fallbacks []string, genOpts gemini.GenerationOptions,
+) PromptGenerator {
 	return &geminiPromptGenerator{
-		client: client,
+		runner: prompt.NewRunner(creator, fallbacks, genOpts),
 	}
 }
 
-// Generate now uses the passed-in modelName.
-func (g *geminiPromptGenerator) Generate(ctx context.Context, modelName, userInput string) (string, error) {
-	genConfig := &genai.GenerateContentConfig{
-		Temperature: genai.Ptr(float32(config.DefaultModelTemperature)),
-	}
-
-	session, err := g.client.Chats.Create(ctx, modelName, genConfig, nil)
-	if err != nil {
-		return "", fmt.Errorf("creating chat session for prompt generation: %w", err)
-	}
-
-	return prompt.Generate(ctx, session, userInput)
+// Generate crafts a prompt with the passed-in modelName.
+func (g *geminiPromptGenerator) Generate(
+	ctx context.Context, modelName, userInput string, attachments ...attachment.Attachment,
+) (prompt.Result, error) {
+	return g.runner.Generate(ctx, modelName, userInput, attachments...)
 }
 
-// Execute now uses the passed-in modelName.
-func (g *geminiPromptGenerator) Execute(ctx context.Context, modelName, userInput string) (string, error) {
-	genConfig := &genai.GenerateContentConfig{
-		Temperature: genai.Ptr(float32(config.DefaultModelTemperature)),
-	}
-
-	session, err := g.client.Chats.Create(ctx, modelName, genConfig, nil)
-	if err != nil {
-		return "", fmt.Errorf("creating chat session for prompt execution: %w", err)
-	}
+// Execute runs a prompt with the passed-in modelName.
+func (g *geminiPromptGenerator) Execute(
+	ctx context.Context, modelName, userInput string, attachments ...attachment.Attachment,
+) (prompt.Result, error) {
+	return g.runner.Execute(ctx, modelName, userInput, attachments...)
+}
 
-	return prompt.Execute(ctx, session, userInput)
+// FollowUp answers a question about the transcript with the passed-in modelName.
+func (g *geminiPromptGenerator) FollowUp(
+	ctx context.Context, modelName string, transcript []prompt.Turn, question string, attachments ...attachment.Attachment,
+) (prompt.Result, error) {
+	return g.runner.FollowUp(ctx, modelName, transcript, question, attachments...)
 }
 
 func (*geminiPromptGenerator) GetModelNames() []string {
@@ -0,0 +1,137 @@
+package web
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/prompt"
+
+	"github.com/labstack/echo/v5"
+)
+
+const (
+	// historyPageSize is the number of entries per page of the sidebar.
+	historyPageSize = 20
+	// historyChangedEvent is triggered on the page when a response is
+	// recorded, so the sidebar reloads.
+	historyChangedEvent = "history-changed"
+	// historyTimeLayout formats entry times in the sidebar and permalinks.
+	historyTimeLayout = "Jan 2, 2006 15:04"
+)
+
+// historyPageView is one page of the history sidebar.
+type historyPageView struct {
+	Entries []history.Entry
+	Query   string
+	Page    int
+	HasMore bool
+}
+
+// permalinkPath is the read-only page of the entry id.
+func permalinkPath(id string) string {
+	return "/p/" + url.PathEscape(id)
+}
+
+// historyPagePath is the sidebar URL for page of the entries matching query.
+func historyPagePath(query string, page int) string {
+	values := url.Values{"page": {strconv.Itoa(page)}}
+	if query != "" {
+		values.Set("q", query)
+	}
+
+	return "/history?" + values.Encode()
+}
+
+// entryMeta summarizes when and with which model an entry was recorded.
+func entryMeta(e history.Entry) string {
+	parts := []string{e.CreatedAt.Local().Format(historyTimeLayout)}
+
+	if model := e.Model(); model != "" {
+		parts = append(parts, model)
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// handleHistory renders a page of the history entries matching the "q"
+// query parameter, newest first.
+func (s *Server) handleHistory(c *echo.Context) error {
+	if s.history == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "History is disabled.")
+	}
+
+	page, err := strconv.Atoi(cmp.Or(c.QueryParam("page"), "1"))
+	if err != nil || page < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid page.")
+	}
+
+	entries, err := s.history.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "History could not be read.").Wrap(err)
+	}
+
+	query := strings.TrimSpace(c.QueryParam("q"))
+	entries = history.Filter{Query: query}.Apply(entries)
+
+	start := min((page-1)*historyPageSize, len(entries))
+	end := min(start+historyPageSize, len(entries))
+
+	return render(c, historyListComponent(historyPageView{
+		Entries: entries[start:end],
+		Query:   query,
+		Page:    page,
+		HasMore: end < len(entries),
+	}))
+}
+
+// handlePermalink renders a stored craft and execution read-only.
+func (s *Server) handlePermalink(c *echo.Context) error {
+	if s.history == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "History is disabled.")
+	}
+
+	entry, err := s.history.Get(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, history.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "No such history entry.")
+	}
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "History could not be read.").Wrap(err)
+	}
+
+	var crafted, answer *responseView
+
+	if text := cmp.Or(entry.CraftedPrompt, entry.ExecutedPrompt); text != "" {
+		crafted = s.storedResponseView(text, entry.Craft, entry.CraftFeedback)
+	}
+
+	if entry.Answer != "" {
+		answer = s.storedResponseView(entry.Answer, entry.Execute, entry.AnswerFeedback)
+	}
+
+	return render(c, permalinkPage(s.version, DefaultTheme, entry, crafted, answer))
+}
+
+// storedResponseView renders a recorded response read-only.
+func (s *Server) storedResponseView(text string, call *history.Call, feedback *history.Feedback) *responseView {
+	result := prompt.Result{Text: text}
+	if call != nil {
+		result.Usage = call.Usage
+	}
+
+	view := s.newResponseView(result)
+	view.ReadOnly = true
+	view.Feedback = feedback
+
+	return &view
+}
+
+// markHistoryChanged tells the page to reload the history sidebar.
+func markHistoryChanged(c *echo.Context) {
+	c.Response().Header().Set("HX-Trigger", historyChangedEvent)
+}
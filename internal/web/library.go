@@ -0,0 +1,150 @@
+package web
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"prompt-maker/internal/config"
+	"prompt-maker/internal/library"
+	"prompt-maker/internal/prompt"
+
+	"github.com/labstack/echo/v5"
+)
+
+// libraryPageView is the library page: every prompt matching a filter.
+type libraryPageView struct {
+	Prompts []library.Prompt
+	Tag     string
+	Query   string
+}
+
+// libraryPromptView is the page of one prompt at one revision, with the diff
+// from the revision before it.
+type libraryPromptView struct {
+	Prompt   library.Prompt
+	Revision library.Revision
+	Text     responseView
+	Diff     string
+}
+
+// libraryPath is the page of the prompt called name.
+func libraryPath(name string) string {
+	return "/library/" + url.PathEscape(name)
+}
+
+// libraryRevisionPath is the page of version of the prompt called name.
+func libraryRevisionPath(name string, version int) string {
+	return libraryPath(name) + "?v=" + strconv.Itoa(version)
+}
+
+// libraryTagPath lists the prompts tagged with tag.
+func libraryTagPath(tag string) string {
+	return "/library?" + url.Values{"tag": {tag}}.Encode()
+}
+
+// handleLibrary renders the prompts matching the "tag" and "q" query
+// parameters.
+func (s *Server) handleLibrary(c *echo.Context) error {
+	if s.library == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "The library is disabled.")
+	}
+
+	prompts, err := s.library.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "The library could not be read.").Wrap(err)
+	}
+
+	view := libraryPageView{Tag: c.QueryParam("tag"), Query: strings.TrimSpace(c.QueryParam("q"))}
+	view.Prompts = library.Filter{Tag: view.Tag, Query: view.Query}.Apply(prompts)
+
+	return render(c, libraryPage(s.version, DefaultTheme, view))
+}
+
+// handleLibraryPrompt renders one prompt at the revision in the "v" query
+// parameter, or its newest one.
+func (s *Server) handleLibraryPrompt(c *echo.Context) error {
+	p, err := s.getLibraryPrompt(c)
+	if err != nil {
+		return err
+	}
+
+	rev := p.Latest()
+
+	if v := c.QueryParam("v"); v != "" {
+		var version int
+		if version, err = strconv.Atoi(v); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid version.")
+		}
+
+		if rev, err = p.Revision(version); err != nil {
+			return echo.NewHTTPError(http.StatusNotFound, "No such revision.").Wrap(err)
+		}
+	}
+
+	view := libraryPromptView{Prompt: p, Revision: rev, Text: s.newResponseView(prompt.Result{Text: rev.Text})}
+	view.Text.ReadOnly = true
+
+	if prev, prevErr := p.Revision(rev.Version - 1); prevErr == nil {
+		view.Diff = library.Diff(p.Name, prev, rev)
+	}
+
+	return render(c, libraryPromptPage(s.version, DefaultTheme, view))
+}
+
+// handleLibrarySave saves the posted prompt as a new revision. Saves from a
+// crafted prompt answer with a status fragment; edits from the prompt page
+// are redirected back to it.
+func (s *Server) handleLibrarySave(c *echo.Context) error {
+	if s.library == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "The library is disabled.")
+	}
+
+	name := cmp.Or(c.Param("name"), c.FormValue("name"))
+	opts := library.SaveOptions{
+		Description: c.FormValue("description"),
+		Note:        c.FormValue("note"),
+		EntryID:     c.FormValue(entryField),
+	}
+
+	// Empty tags keep the prompt's tags.
+	opts.Tags = config.ParseList(c.FormValue("tags"))
+
+	p, err := s.library.Save(c.Request().Context(), name, c.FormValue("prompt"), opts)
+
+	switch {
+	case errors.Is(err, library.ErrInvalidName):
+		return echo.NewHTTPError(http.StatusBadRequest, "Use letters, digits, '.', '_' and '-' in the name.")
+	case errors.Is(err, library.ErrEmptyText):
+		return echo.NewHTTPError(http.StatusBadRequest, "The prompt cannot be empty.")
+	case err != nil:
+		return echo.NewHTTPError(http.StatusInternalServerError, "The prompt could not be saved.").Wrap(err)
+	}
+
+	if c.Param("name") != "" {
+		return c.Redirect(http.StatusSeeOther, libraryPath(p.Name))
+	}
+
+	return render(c, librarySavedComponent(p))
+}
+
+// getLibraryPrompt reads the prompt named in the path.
+func (s *Server) getLibraryPrompt(c *echo.Context) (library.Prompt, error) {
+	if s.library == nil {
+		return library.Prompt{}, echo.NewHTTPError(http.StatusNotFound, "The library is disabled.")
+	}
+
+	p, err := s.library.Get(c.Request().Context(), c.Param("name"))
+
+	switch {
+	case errors.Is(err, library.ErrNotFound), errors.Is(err, library.ErrInvalidName):
+		return library.Prompt{}, echo.NewHTTPError(http.StatusNotFound, "No such prompt in the library.")
+	case err != nil:
+		return library.Prompt{}, echo.NewHTTPError(http.StatusInternalServerError, "The library could not be read.").Wrap(err)
+	}
+
+	return p, nil
+}
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"cmp"
 	"errors"
 	"log/slog"
 	"net/http"
@@ -21,9 +22,11 @@ func ErrorMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 
 		he, ok := errors.AsType[*echo.HTTPError](err)
 		if !ok {
+			// Echo's own errors, such as the body limit's, only carry a status.
+			code := cmp.Or(echo.StatusCode(err), http.StatusInternalServerError)
 			he = &echo.HTTPError{
-				Code:    http.StatusInternalServerError,
-				Message: "Internal Server Error",
+				Code:    code,
+				Message: http.StatusText(code),
 			}
 		}
 
@@ -0,0 +1,101 @@
+package web
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"prompt-maker/internal/placeholder"
+
+	"github.com/labstack/echo/v5"
+)
+
+const (
+	// placeholderField and valueField pair each placeholder of a crafted
+	// prompt with the value filled in for it.
+	placeholderField = "placeholder"
+	valueField       = "value"
+)
+
+// placeholderInput is a form field asking for the value of a placeholder.
+type placeholderInput struct {
+	Token string
+	// Last is the value used last time, and Recent all the values used
+	// before, most recent first.
+	Last   string
+	Recent []string
+}
+
+// placeholderInputs returns a form field for each placeholder in text,
+// suggesting the values remembered for it.
+func (s *Server) placeholderInputs(c *echo.Context, text string) []placeholderInput {
+	tokens := placeholder.Find(text)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var remembered placeholder.Values
+
+	if s.placeholders != nil {
+		var err error
+		if remembered, err = s.placeholders.Load(); err != nil {
+			slog.WarnContext(c.Request().Context(), "failed to read placeholder values", "error", err)
+		}
+	}
+
+	inputs := make([]placeholderInput, len(tokens))
+	for i, token := range tokens {
+		inputs[i] = placeholderInput{Token: token, Last: remembered.Last(token), Recent: remembered.Recent(token)}
+	}
+
+	return inputs
+}
+
+// fillPlaceholders puts the posted placeholder values into text and
+// remembers them. The placeholders are found again in text, since it may
+// have been edited since it was crafted: posted values for placeholders no
+// longer in it are ignored, and every one still in it needs a value.
+func (s *Server) fillPlaceholders(c *echo.Context, text string) (string, error) {
+	form, err := c.FormValues()
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "The form could not be read.").Wrap(err)
+	}
+
+	tokens, values := form[placeholderField], form[valueField]
+	if len(tokens) == 0 {
+		return text, nil
+	}
+
+	if len(tokens) != len(values) {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Every placeholder needs a value.")
+	}
+
+	filled := make(map[string]string, len(tokens))
+	present := placeholder.Find(text)
+
+	for i, token := range tokens {
+		if !slices.Contains(present, token) {
+			continue
+		}
+
+		if values[i] == "" {
+			return "", echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Fill in %s before executing the prompt.", token))
+		}
+
+		filled[token] = values[i]
+	}
+
+	if len(filled) == 0 {
+		return text, nil
+	}
+
+	if s.placeholders != nil {
+		if err := s.placeholders.Remember(filled); err != nil {
+			// The prompt can still be executed when its values cannot be saved.
+			slog.WarnContext(c.Request().Context(), "failed to remember placeholder values", "error", err)
+		}
+	}
+
+	return placeholder.Fill(text, filled), nil
+}
@@ -0,0 +1,54 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"prompt-maker/internal/history"
+
+	"github.com/labstack/echo/v5"
+)
+
+// handleRate stores the posted thumbs up or down, and optional note, on the
+// crafted prompt or the answer of a history entry, answering with the
+// recorded rating.
+func (s *Server) handleRate(c *echo.Context) error {
+	if s.history == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "History is disabled.")
+	}
+
+	target, err := history.ParseTarget(c.FormValue("target"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Rate the prompt or the answer.")
+	}
+
+	rating, err := history.ParseRating(c.FormValue("rating"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Rate up or down.")
+	}
+
+	f := history.Feedback{Rating: rating, Note: strings.TrimSpace(c.FormValue("note")), At: time.Now()}
+	_, err = history.Rate(c.Request().Context(), s.history, c.FormValue(entryField), target, f)
+
+	switch {
+	case errors.Is(err, history.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "No such history entry.")
+	case errors.Is(err, history.ErrNoAnswer):
+		return echo.NewHTTPError(http.StatusBadRequest, "There is no answer to rate yet.")
+	case err != nil:
+		return echo.NewHTTPError(http.StatusInternalServerError, "The rating could not be saved.").Wrap(err)
+	}
+
+	return render(c, ratedComponent(f))
+}
+
+// ratingLabel is the thumb shown for a rating.
+func ratingLabel(r history.Rating) string {
+	if r == history.RatingDown {
+		return "👎"
+	}
+
+	return "👍"
+}
@@ -1,33 +1,67 @@
 package web
 
 import (
-	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"strings"
+
+	"prompt-maker/internal/attachment"
 	"prompt-maker/internal/config"
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/library"
+	"prompt-maker/internal/markdown"
+	"prompt-maker/internal/placeholder"
+	"prompt-maker/internal/prompt"
 
 	"github.com/a-h/templ"
 	"github.com/labstack/echo/v5"
 	"github.com/labstack/echo/v5/middleware"
-	"github.com/yuin/goldmark"
-	"github.com/yuin/goldmark/renderer/html"
 
 	echootel "github.com/labstack/echo-opentelemetry"
 )
 
+const (
+	// attachmentsField is the multipart field that carries uploaded files.
+	attachmentsField = "attachments"
+	// maxAttachments caps the number of files per request.
+	maxAttachments = 10
+	// maxUploadBytes caps a whole request carrying attachments: every file
+	// at its limit, plus room for the prompt and the other fields.
+	maxUploadBytes = maxAttachments*attachment.MaxSize + 1<<20
+	// entryField carries the history entry of a crafted prompt to /execute.
+	entryField = "entry"
+)
+
 // Server holds our testable interface and config values.
 type Server struct {
 	e         *echo.Echo
 	generator PromptGenerator
-	version   string
-	md        goldmark.Markdown
+	recorder  *history.Recorder
+	history   history.Store
+	library   *library.Library
+	// placeholders remembers the values filled into crafted prompts.
+	placeholders *placeholder.Memory
+	// chats keeps the conversations of follow-up questions.
+	chats   *chats
+	version string
+	md      *markdown.Renderer
 }
 
 // Config holds the dependencies for the server.
 type Config struct {
 	Generator PromptGenerator
 	Version   string
+	// Recorder, when set, saves every craft and execution to history.
+	Recorder *history.Recorder
+	// Library, when set, enables saving crafted prompts and the library pages.
+	Library *library.Library
+	// Placeholders, when set, remembers the values filled into crafted
+	// prompts and suggests them again.
+	Placeholders *placeholder.Memory
 }
 
 // NewServer creates a configured Echo server with OTEL tracing,
@@ -61,15 +95,19 @@ func NewServer(cfg Config) (*Server, error) {
 	e.Static("/static", "static")
 
 	s := &Server{
-		e:         e,
-		generator: cfg.Generator,
-		version:   cfg.Version,
-		md: goldmark.New(
-			goldmark.WithRendererOptions(
-				html.WithUnsafe(), // Allow raw HTML in markdown
-			),
-		),
+		e:            e,
+		generator:    cfg.Generator,
+		recorder:     cfg.Recorder,
+		library:      cfg.Library,
+		placeholders: cfg.Placeholders,
+		chats:        newChats(),
+		version:      cfg.Version,
+		md:           markdown.New(),
+	}
+	if cfg.Recorder != nil {
+		s.history = cfg.Recorder.Store()
 	}
+
 	s.registerRoutes()
 
 	return s, nil
@@ -77,10 +115,21 @@ func NewServer(cfg Config) (*Server, error) {
 
 func (s *Server) registerRoutes() {
 	s.e.GET("/", s.handleIndex)
-	s.e.POST("/prompt", s.handlePrompt)
-	s.e.POST("/execute", s.handleExecute)
+	uploadLimit := middleware.BodyLimit(maxUploadBytes)
+
+	s.e.POST("/prompt", s.handlePrompt, uploadLimit)
+	s.e.POST("/execute", s.handleExecute, uploadLimit)
+	s.e.POST("/chat", s.handleChat)
 	s.e.POST("/update-footer", s.handleUpdateFooter)
 	s.e.POST("/clear", handleClear)
+	s.e.GET("/history", s.handleHistory)
+	s.e.GET("/p/:id", s.handlePermalink)
+	s.e.GET("/export/:id", s.handleExport)
+	s.e.POST("/rate", s.handleRate)
+	s.e.GET("/library", s.handleLibrary)
+	s.e.POST("/library", s.handleLibrarySave)
+	s.e.GET("/library/:name", s.handleLibraryPrompt)
+	s.e.POST("/library/:name", s.handleLibrarySave)
 }
 
 // Start begins listening on addr and serves HTTP requests.
@@ -92,47 +141,209 @@ func (s *Server) Start(addr string) error {
 
 func (s *Server) handleIndex(c *echo.Context) error {
 	// Pass the model names, themes, and default theme to the index page template.
-	return render(c, indexPage(s.version, config.DefaultModel, DefaultTheme, s.generator.GetModelNames(), getThemes()))
+	return render(c, indexPage(s.version, config.DefaultModel, DefaultTheme, s.generator.GetModelNames(), getThemes(), s.history != nil, s.library != nil))
 }
 
 func (s *Server) handlePrompt(c *echo.Context) error {
-	return s.handleGenerate(c, s.generator.Generate,
-		"The AI failed to generate a response. Please try again.", craftedPromptComponent)
+	record := func(ctx context.Context, _, input string, attachments []string, result prompt.Result) (history.Entry, error) {
+		return s.recorder.Craft(ctx, input, attachments, result)
+	}
+
+	return s.handleGenerate(c, c.FormValue("prompt"), s.generator.Generate, record,
+		"The AI failed to generate a response. Please try again.",
+		func(resp responseView, modelName string) templ.Component {
+			resp.Placeholders = s.placeholderInputs(c, resp.Raw)
+
+			return craftedPromptComponent(resp, modelName)
+		},
+	)
 }
 
+// handleExecute fills the placeholders of the posted prompt and executes it.
 func (s *Server) handleExecute(c *echo.Context) error {
-	return s.handleGenerate(c, s.generator.Execute,
+	input, err := s.fillPlaceholders(c, c.FormValue("prompt"))
+	if err != nil {
+		return err
+	}
+
+	return s.handleGenerate(c, input, s.generator.Execute, s.recorder.Execute,
 		"The AI failed to execute the prompt. Please try again.",
-		func(html, raw, _ string) templ.Component {
-			return finalAnswerComponent(html, raw)
+		func(resp responseView, modelName string) templ.Component {
+			resp.ChatID = s.chats.start(input, resp.Raw)
+
+			return finalAnswerComponent(resp, modelName)
 		},
 	)
 }
 
+// responseView carries a model response, rendered and raw, into the templates.
+type responseView struct {
+	HTML         string
+	Raw          string
+	ThoughtsHTML string
+	Usage        prompt.Usage
+	// EntryID is the history entry recording the response, if any.
+	EntryID string
+	// ReadOnly hides the actions, for permalinks.
+	ReadOnly bool
+	// Feedback is the stored rating of a read-only response, if any.
+	Feedback *history.Feedback
+	// Savable offers to save a crafted prompt to the library.
+	Savable bool
+	// Placeholders asks for the values of the blanks in a crafted prompt.
+	Placeholders []placeholderInput
+	// ChatID is the conversation a follow-up question continues.
+	ChatID string
+	// ChatTurns counts the turns of the conversation so far.
+	ChatTurns int
+}
+
+// newResponseView renders the markdown of result and its thought summaries.
+func (s *Server) newResponseView(result prompt.Result) responseView {
+	view := responseView{
+		HTML:  s.markdownToHTML(result.Text),
+		Raw:   result.Text,
+		Usage: result.Usage,
+	}
+
+	if result.Thoughts != "" {
+		view.ThoughtsHTML = s.markdownToHTML(result.Thoughts)
+	}
+
+	return view
+}
+
+// generateFunc is the shape shared by PromptGenerator.Generate and Execute.
+type generateFunc func(ctx context.Context, model, input string, attachments ...attachment.Attachment) (prompt.Result, error)
+
+// recordFunc saves a result to history. entryID names the entry being
+// continued, if any.
+type recordFunc func(ctx context.Context, entryID, input string, attachments []string, result prompt.Result) (history.Entry, error)
+
 // handleGenerate is the shared core for handlePrompt and handleExecute.
-// It reads "prompt" and "model" form values, calls generateFn, converts the
-// result to HTML, and renders the component returned by buildComponent.
+// It sends input with the "model" form value and any uploaded attachments to
+// generateFn, records the result with recordFn when history is
+// enabled and recordFn is set, converts the result to HTML, and renders the component returned
+// by buildComponent together with an out-of-band footer naming the model
+// that answered.
 func (s *Server) handleGenerate(
 	c *echo.Context,
-	generateFn func(ctx context.Context, model, input string) (string, error),
+	input string,
+	generateFn generateFunc,
+	recordFn recordFunc,
 	errMsg string,
-	buildComponent func(html, raw, model string) templ.Component,
+	buildComponent func(resp responseView, model string) templ.Component,
 ) error {
-	input := c.FormValue("prompt")
-
 	modelName := c.FormValue("model")
 	if input == "" || modelName == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Prompt and model cannot be empty.")
 	}
 
-	result, err := generateFn(c.Request().Context(), modelName, input)
+	attachments, err := readAttachments(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+
+	result, err := generateFn(ctx, modelName, input, attachments...)
+	if ctx.Err() != nil {
+		// The client canceled or went away, which stopped the model call;
+		// there is no one left to answer or record for.
+		slog.InfoContext(ctx, "request canceled by the client", "path", c.Request().URL.Path)
+
+		return nil
+	}
+
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errMsg)
 	}
 
-	resultHTML := s.markdownToHTML(result)
+	view := s.newResponseView(result)
+	view.Savable = s.library != nil
+
+	if s.recorder != nil && recordFn != nil {
+		entry, err := recordFn(ctx, c.FormValue(entryField), input, attachment.Names(attachments), result)
+		if err != nil {
+			// The answer is still worth showing when it cannot be saved.
+			slog.WarnContext(ctx, "failed to record history", "error", err)
+		} else {
+			// Only a saved entry can be linked, rated or exported.
+			view.EntryID = entry.ID
+
+			markHistoryChanged(c)
+		}
+	}
 
-	return render(c, buildComponent(resultHTML, result, modelName))
+	return render(c, templ.Join(
+		buildComponent(view, modelName),
+		footerOOBComponent(s.version, result.Model),
+	))
+}
+
+// readAttachments validates the files uploaded in the "attachments" field
+// of a multipart form. Plain URL-encoded forms carry no attachments.
+func readAttachments(c *echo.Context) ([]attachment.Attachment, error) {
+	if !strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		return nil, nil
+	}
+
+	form, err := c.MultipartForm()
+	if errors.Is(err, echo.ErrStatusRequestEntityTooLarge) {
+		return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("The upload is too large: requests are limited to %d MB.", maxUploadBytes>>20)).Wrap(err)
+	}
+
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "The upload could not be read.").Wrap(err)
+	}
+
+	files := form.File[attachmentsField]
+	if len(files) > maxAttachments {
+		return nil, echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("Too many attachments: at most %d files are allowed.", maxAttachments))
+	}
+
+	attachments := make([]attachment.Attachment, 0, len(files))
+
+	for _, fh := range files {
+		a, err := readUpload(fh)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, attachmentErrorMessage(fh.Filename, err)).Wrap(err)
+		}
+
+		attachments = append(attachments, a)
+	}
+
+	return attachments, nil
+}
+
+func readUpload(fh *multipart.FileHeader) (attachment.Attachment, error) {
+	if fh.Size > attachment.MaxSize {
+		return attachment.Attachment{}, attachment.ErrTooLarge
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return attachment.Attachment{}, fmt.Errorf("opening upload: %w", err)
+	}
+	defer f.Close()
+
+	return attachment.Read(fh.Filename, f)
+}
+
+// attachmentErrorMessage explains why an upload was rejected.
+func attachmentErrorMessage(name string, err error) string {
+	switch {
+	case errors.Is(err, attachment.ErrTooLarge):
+		return fmt.Sprintf("%s is too large: attachments are limited to %d MB.", name, attachment.MaxSize>>20)
+	case errors.Is(err, attachment.ErrUnsupportedType):
+		return name + " is not a supported image (PNG, JPEG, WebP, HEIC) or text file."
+	case errors.Is(err, attachment.ErrEmpty):
+		return name + " is empty."
+	default:
+		return name + " could not be read."
+	}
 }
 
 func (s *Server) handleUpdateFooter(c *echo.Context) error {
@@ -150,12 +361,7 @@ func handleClear(c *echo.Context) error {
 
 // markdownToHTML converts a markdown string to its HTML representation.
 func (s *Server) markdownToHTML(str string) string {
-	var buf bytes.Buffer
-	if err := s.md.Convert([]byte(str), &buf); err != nil {
-		return str // Return raw text on error
-	}
-
-	return buf.String()
+	return s.md.ToHTML(str)
 }
 
 func render(c *echo.Context, component templ.Component) error {
@@ -1,18 +1,32 @@
 package web
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"prompt-maker/internal/attachment"
 	"prompt-maker/internal/config"
+	"prompt-maker/internal/gemini"
+	"prompt-maker/internal/history"
+	"prompt-maker/internal/library"
+	"prompt-maker/internal/placeholder"
+	"prompt-maker/internal/prompt"
 
 	"github.com/labstack/echo/v5"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
 )
 
 // Rename the error variable to follow Go's initialism convention.
@@ -22,15 +36,49 @@ var errMockAPIFailed = errors.New("mock API failed")
 type mockPromptGenerator struct {
 	GenerateFunc      func(ctx context.Context, modelName, userInput string) (string, error)
 	ExecuteFunc       func(ctx context.Context, modelName, userInput string) (string, error)
+	FollowUpFunc      func(ctx context.Context, modelName string, transcript []prompt.Turn, question string) (string, error)
 	GetModelNamesFunc func() []string
+	// AnsweredModel, when set, is reported instead of the requested model.
+	AnsweredModel string
+	Thoughts      string
+	Usage         prompt.Usage
+	// Attachments records the attachments passed to the last call.
+	Attachments []attachment.Attachment
 }
 
-func (m *mockPromptGenerator) Generate(ctx context.Context, modelName, userInput string) (string, error) {
-	return m.GenerateFunc(ctx, modelName, userInput)
+func (m *mockPromptGenerator) Generate(
+	ctx context.Context, modelName, userInput string, attachments ...attachment.Attachment,
+) (prompt.Result, error) {
+	m.Attachments = attachments
+	text, err := m.GenerateFunc(ctx, modelName, userInput)
+
+	return m.result(text, modelName), err
+}
+
+func (m *mockPromptGenerator) Execute(
+	ctx context.Context, modelName, userInput string, attachments ...attachment.Attachment,
+) (prompt.Result, error) {
+	m.Attachments = attachments
+	text, err := m.ExecuteFunc(ctx, modelName, userInput)
+
+	return m.result(text, modelName), err
+}
+
+func (m *mockPromptGenerator) FollowUp(
+	ctx context.Context, modelName string, transcript []prompt.Turn, question string, attachments ...attachment.Attachment,
+) (prompt.Result, error) {
+	m.Attachments = attachments
+	text, err := m.FollowUpFunc(ctx, modelName, transcript, question)
+
+	return m.result(text, modelName), err
 }
 
-func (m *mockPromptGenerator) Execute(ctx context.Context, modelName, userInput string) (string, error) {
-	return m.ExecuteFunc(ctx, modelName, userInput)
+func (m *mockPromptGenerator) result(text, modelName string) prompt.Result {
+	if m.AnsweredModel != "" {
+		modelName = m.AnsweredModel
+	}
+
+	return prompt.Result{Text: text, Model: modelName, Thoughts: m.Thoughts, Usage: m.Usage}
 }
 
 func (m *mockPromptGenerator) GetModelNames() []string {
@@ -114,13 +162,10 @@ func TestHandlePrompt(t *testing.T) {
 		"The hidden div should contain the raw markdown.",
 	)
 
-	// 3. Check that the raw markdown is correctly placed in the hidden input for the resubmit form.
-	require.Contains(
-		t,
-		body,
-		`<input type="hidden" name="prompt" value="`+rawResponse+`">`,
-		"The hidden input should contain the raw markdown for resubmission.",
-	)
+	// 3. Check that the raw markdown is editable in the resubmit form, with a way back to the original.
+	require.Contains(t, body, `name="prompt" rows="8"`)
+	require.Contains(t, body, `>`+rawResponse+`</textarea>`, "The editor should contain the raw markdown for resubmission.")
+	require.Contains(t, body, `onclick="resetCraftedPrompt(this)" data-target-id="raw-crafted-prompt"`)
 }
 
 func TestHandleIndex_WithDaisyUI(t *testing.T) {
@@ -272,3 +317,630 @@ func TestHandleExecute_ApiError(t *testing.T) {
 	server := newTestServer(t, mockGen, "test")
 	assertAPIError(t, server, "/execute", "The AI failed to execute the prompt. Please try again.")
 }
+
+func TestHandlePrompt_FooterShowsAnsweredModel(t *testing.T) {
+	mockGen := &mockPromptGenerator{
+		GenerateFunc: func(_ context.Context, _, _ string) (string, error) {
+			return "crafted", nil
+		},
+		AnsweredModel: "gemini-2.5-flash",
+	}
+	server := newTestServer(t, mockGen, "1.0.0")
+
+	form := strings.NewReader("prompt=test&model=gemini-2.5-pro")
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/prompt", form)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	w := httptest.NewRecorder()
+
+	server.e.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	require.Contains(t, body, `<aside id="footer-content" hx-swap-oob="true">`)
+	require.Contains(t, body, "prompt-maker v1.0.0 / gemini-2.5-flash")
+	require.Contains(t, body, `<input type="hidden" name="model" value="gemini-2.5-pro">`,
+		"The execute form should keep the selected model so the fallback chain applies again.")
+}
+
+func TestHandleExecute_ShowsThoughtsAndUsage(t *testing.T) {
+	mockGen := &mockPromptGenerator{
+		ExecuteFunc: func(_ context.Context, _, _ string) (string, error) {
+			return "answer", nil
+		},
+		Thoughts: "Considering **edge cases**.",
+		Usage:    prompt.Usage{PromptTokens: 12, OutputTokens: 34, ThinkingTokens: 56},
+	}
+	server := newTestServer(t, mockGen, "test")
+
+	form := strings.NewReader("prompt=test&model=gemini-2.5-flash")
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/execute", form)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	w := httptest.NewRecorder()
+
+	server.e.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	require.Contains(t, body, "<details")
+	require.Contains(t, body, "<p>Considering <strong>edge cases</strong>.</p>")
+	require.Contains(t, body, "tokens in 12 · out 34 · thinking 56")
+}
+
+// newMultipartRequest builds a multipart POST to path with the prompt and
+// model fields and one "attachments" part per file.
+func newMultipartRequest(t *testing.T, path string, files map[string][]byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+
+	mw := multipart.NewWriter(&body)
+	require.NoError(t, mw.WriteField("prompt", "describe this"))
+	require.NoError(t, mw.WriteField("model", "gemini-2.5-flash"))
+
+	for name, data := range files {
+		fw, err := mw.CreateFormFile(attachmentsField, name)
+		require.NoError(t, err)
+
+		_, err = fw.Write(data)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, path, &body)
+	req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+
+	return req
+}
+
+func TestHandlePrompt_WithAttachments(t *testing.T) {
+	mockGen := &mockPromptGenerator{
+		GenerateFunc: func(_ context.Context, _, _ string) (string, error) {
+			return "crafted", nil
+		},
+	}
+	server := newTestServer(t, mockGen, "test")
+
+	w := httptest.NewRecorder()
+	server.e.ServeHTTP(w, newMultipartRequest(t, "/prompt", map[string][]byte{"spec.md": []byte("# Spec")}))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, mockGen.Attachments, 1)
+	require.Equal(t, "spec.md", mockGen.Attachments[0].Name)
+	require.Equal(t, "text/markdown", mockGen.Attachments[0].MIMEType)
+}
+
+func TestHandleExecute_RejectsUnsupportedAttachment(t *testing.T) {
+	mockGen := &mockPromptGenerator{
+		ExecuteFunc: func(_ context.Context, _, _ string) (string, error) {
+			require.Fail(t, "The model should not be called with an invalid attachment")
+			return "", nil
+		},
+	}
+	server := newTestServer(t, mockGen, "test")
+
+	w := httptest.NewRecorder()
+	server.e.ServeHTTP(w, newMultipartRequest(t, "/execute", map[string][]byte{"blob.bin": {0x00, 0x01, 0x02}}))
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "blob.bin is not a supported image")
+}
+
+func TestHandlePrompt_RejectsOversizedRequest(t *testing.T) {
+	mockGen := &mockPromptGenerator{
+		GenerateFunc: func(_ context.Context, _, _ string) (string, error) {
+			require.Fail(t, "The model should not be called for an oversized request")
+			return "", nil
+		},
+	}
+	server := newTestServer(t, mockGen, "test")
+
+	for _, path := range []string{"/prompt", "/execute"} {
+		req := newMultipartRequest(t, path, map[string][]byte{"spec.md": []byte("# Spec")})
+		req.ContentLength = maxUploadBytes + 1
+
+		w := httptest.NewRecorder()
+		server.e.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, w.Code, path)
+	}
+}
+
+// postForm sends a URL-encoded form to path and returns the recorder.
+func postForm(server *Server, path string, values url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, path, strings.NewReader(values.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	w := httptest.NewRecorder()
+	server.e.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestHandlePrompt_RecordsHistory(t *testing.T) {
+	mockGen := &mockPromptGenerator{
+		GenerateFunc: func(_ context.Context, _, _ string) (string, error) { return "crafted", nil },
+		ExecuteFunc:  func(_ context.Context, _, _ string) (string, error) { return "answer", nil },
+	}
+	store := history.NewFileStore(filepath.Join(t.TempDir(), "history.jsonl"))
+
+	server, err := NewServer(Config{
+		Generator: mockGen,
+		Version:   "test",
+		Recorder:  history.NewRecorder(store, history.SourceWeb, gemini.GenerationOptions{}),
+	})
+	require.NoError(t, err)
+
+	w := postForm(server, "/prompt", url.Values{"prompt": {"rough idea"}, "model": {"gemini-2.5-flash"}})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	entries, err := store.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Contains(t, w.Body.String(), `name="entry" value="`+entries[0].ID+`"`,
+		"The execute form carries the entry so the answer completes it")
+
+	w = postForm(server, "/execute", url.Values{"prompt": {"crafted"}, "model": {"gemini-2.5-flash"}, "entry": {entries[0].ID}})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	entries, err = store.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, history.SourceWeb, entries[0].Source)
+	require.Equal(t, "rough idea", entries[0].Input)
+	require.Equal(t, "crafted", entries[0].CraftedPrompt)
+	require.Equal(t, "answer", entries[0].Answer)
+}
+
+func TestHandlePrompt_UnsavedEntryIsNotLinked(t *testing.T) {
+	mockGen := &mockPromptGenerator{
+		GenerateFunc: func(_ context.Context, _, _ string) (string, error) { return "crafted", nil },
+	}
+
+	// A file in place of the history directory makes every save fail.
+	dir := filepath.Join(t.TempDir(), "history")
+	require.NoError(t, os.WriteFile(dir, nil, 0o600))
+
+	store := history.NewFileStore(filepath.Join(dir, "history.jsonl"))
+
+	server, err := NewServer(Config{
+		Generator: mockGen,
+		Version:   "test",
+		Recorder:  history.NewRecorder(store, history.SourceWeb, gemini.GenerationOptions{}),
+	})
+	require.NoError(t, err)
+
+	w := postForm(server, "/prompt", url.Values{"prompt": {"rough idea"}, "model": {"gemini-2.5-flash"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "crafted", "The answer is shown even when it cannot be saved")
+	require.NotContains(t, w.Body.String(), `name="entry" value="`)
+	require.NotContains(t, w.Body.String(), "/history/")
+}
+
+func TestHandlePrompt_ClientCancelStopsModelCall(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+
+	mockGen := &mockPromptGenerator{
+		GenerateFunc: func(ctx context.Context, _, _ string) (string, error) {
+			cancel()
+			<-ctx.Done()
+
+			return "", ctx.Err()
+		},
+	}
+	store := history.NewFileStore(filepath.Join(t.TempDir(), "history.jsonl"))
+
+	server, err := NewServer(Config{
+		Generator: mockGen,
+		Version:   "test",
+		Recorder:  history.NewRecorder(store, history.SourceWeb, gemini.GenerationOptions{}),
+	})
+	require.NoError(t, err)
+
+	form := url.Values{"prompt": {"rough idea"}, "model": {"gemini-2.5-flash"}}
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/prompt", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	w := httptest.NewRecorder()
+	server.e.ServeHTTP(w, req)
+
+	require.NotEqual(t, http.StatusInternalServerError, w.Code, "A canceled request is not an error")
+	require.Empty(t, w.Body.String())
+
+	entries, err := store.List(t.Context())
+	require.NoError(t, err)
+	require.Empty(t, entries, "Nothing is recorded for a canceled request")
+
+	index := doGETIndex(newTestServer(t, &mockPromptGenerator{GetModelNamesFunc: func() []string { return nil }}, "test"))
+	require.Contains(t, index.Body.String(), `data-cancels="prompt-form"`)
+}
+
+// newHistoryTestServer creates a Server recording to a fresh history file,
+// seeded with entries.
+func newHistoryTestServer(t *testing.T, entries ...history.Entry) *Server {
+	t.Helper()
+
+	store := history.NewFileStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	for _, e := range entries {
+		require.NoError(t, store.Save(t.Context(), e))
+	}
+
+	mockGen := &mockPromptGenerator{
+		GenerateFunc:      func(_ context.Context, _, _ string) (string, error) { return "crafted", nil },
+		GetModelNamesFunc: func() []string { return []string{"gemini-2.5-flash"} },
+	}
+
+	server, err := NewServer(Config{
+		Generator: mockGen,
+		Version:   "test",
+		Recorder:  history.NewRecorder(store, history.SourceWeb, gemini.GenerationOptions{}),
+	})
+	require.NoError(t, err)
+
+	return server
+}
+
+// doGET performs a GET request to path and returns the recorder.
+func doGET(server *Server, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, http.NoBody)
+	server.e.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestHandleIndex_HistorySidebar(t *testing.T) {
+	require.NotContains(t, doGETIndex(newTestServer(t, &mockPromptGenerator{
+		GetModelNamesFunc: func() []string { return nil },
+	}, "test")).Body.String(), `id="history-list"`, "No sidebar without history")
+
+	body := doGETIndex(newHistoryTestServer(t)).Body.String()
+	require.Contains(t, body, `id="history-list"`)
+	require.Contains(t, body, `hx-get="/history"`)
+}
+
+func TestHandleHistory_PaginatesAndSearches(t *testing.T) {
+	base := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	entries := make([]history.Entry, historyPageSize+5)
+
+	for i := range entries {
+		entries[i] = history.Entry{
+			ID:        fmt.Sprintf("entry%02d", i),
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+			Input:     fmt.Sprintf("idea number %02d", i),
+		}
+	}
+
+	entries[3].Input = "release notes for v2"
+	server := newHistoryTestServer(t, entries...)
+
+	first := doGET(server, "/history").Body.String()
+	require.Contains(t, first, "idea number 24", "Newest first")
+	require.NotContains(t, first, "idea number 04")
+	require.Contains(t, first, `hx-get="/history?page=2"`)
+
+	second := doGET(server, "/history?page=2").Body.String()
+	require.Contains(t, second, "idea number 04")
+	require.Contains(t, second, `href="/p/entry00"`)
+
+	found := doGET(server, "/history?q=RELEASE").Body.String()
+	require.Contains(t, found, "release notes for v2")
+	require.NotContains(t, found, "idea number")
+
+	require.Contains(t, doGET(server, "/history?q=invoice").Body.String(), "No matching entries.")
+	require.Equal(t, http.StatusBadRequest, doGET(server, "/history?page=0").Code)
+}
+
+func TestHandlePermalink(t *testing.T) {
+	server := newHistoryTestServer(t, history.Entry{
+		ID:            "abc123",
+		CreatedAt:     time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC),
+		Input:         "rough idea",
+		CraftedPrompt: "# Crafted",
+		Answer:        "The **answer**",
+		Execute:       &history.Call{Model: "gemini-2.5-pro", Usage: prompt.Usage{OutputTokens: 7}},
+	})
+
+	w := doGET(server, "/p/abc123")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	require.Contains(t, body, "rough idea")
+	require.Contains(t, body, "<h1>Crafted</h1>")
+	require.Contains(t, body, "<strong>answer</strong>")
+	require.Contains(t, body, "gemini-2.5-pro")
+	require.Contains(t, body, "out 7")
+	require.NotContains(t, body, `hx-post="/execute"`, "Permalinks are read-only")
+
+	require.Equal(t, http.StatusNotFound, doGET(server, "/p/missing").Code)
+	require.Equal(t, http.StatusNotFound, doGET(newTestServer(t, &mockPromptGenerator{}, "test"), "/p/abc123").Code)
+}
+
+func TestHandleExport(t *testing.T) {
+	server := newHistoryTestServer(t, history.Entry{
+		ID:            "abc123",
+		CreatedAt:     time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC),
+		Input:         "rough idea",
+		CraftedPrompt: "# Crafted",
+		Answer:        "The **answer**",
+	})
+
+	require.Contains(t, doGET(server, "/p/abc123").Body.String(), `href="/export/abc123?format=html"`)
+
+	w := doGET(server, "/export/abc123")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `attachment; filename="prompt-maker-abc123.md"`, w.Header().Get("Content-Disposition"))
+	require.Contains(t, w.Body.String(), "### Answer\n\nThe **answer**")
+
+	w = doGET(server, "/export/abc123?format=html&theme=gruvbox")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `attachment; filename="prompt-maker-abc123.html"`, w.Header().Get("Content-Disposition"))
+	require.Contains(t, w.Body.String(), `data-theme="gruvbox"`)
+	require.Contains(t, w.Body.String(), "<strong>answer</strong>")
+
+	w = doGET(server, "/export/abc123?format=json")
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.Contains(t, w.Body.String(), `"id": "abc123"`)
+
+	require.Equal(t, http.StatusBadRequest, doGET(server, "/export/abc123?format=pdf").Code)
+	require.Equal(t, http.StatusBadRequest, doGET(server, "/export/abc123?format=html&theme=neon").Code)
+	require.Equal(t, http.StatusNotFound, doGET(server, "/export/missing").Code)
+}
+
+func TestHandleRate(t *testing.T) {
+	server := newHistoryTestServer(t)
+
+	w := postForm(server, "/prompt", url.Values{"prompt": {"rough idea"}, "model": {"gemini-2.5-flash"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `hx-post="/rate"`)
+	require.Contains(t, w.Body.String(), `name="target" value="prompt"`)
+
+	entries, err := server.history.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	id := entries[0].ID
+
+	w = postForm(server, "/rate", url.Values{"entry": {id}, "target": {"prompt"}, "rating": {"up"}, "note": {"crisp"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "Rated 👍")
+	require.Contains(t, w.Body.String(), "crisp")
+
+	body := doGET(server, "/p/"+id).Body.String()
+	require.Contains(t, body, "Rated 👍", "Permalinks show the stored rating")
+	require.NotContains(t, body, `hx-post="/rate"`)
+
+	require.Equal(t, http.StatusBadRequest,
+		postForm(server, "/rate", url.Values{"entry": {id}, "target": {"answer"}, "rating": {"down"}}).Code, "No answer yet")
+	require.Equal(t, http.StatusBadRequest,
+		postForm(server, "/rate", url.Values{"entry": {id}, "target": {"prompt"}, "rating": {"meh"}}).Code)
+	require.Equal(t, http.StatusNotFound,
+		postForm(server, "/rate", url.Values{"entry": {"missing"}, "target": {"prompt"}, "rating": {"up"}}).Code)
+}
+
+func TestHandlePrompt_LinksPermalinkAndRefreshesHistory(t *testing.T) {
+	server := newHistoryTestServer(t)
+
+	w := postForm(server, "/prompt", url.Values{"prompt": {"rough idea"}, "model": {"gemini-2.5-flash"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, historyChangedEvent, w.Header().Get("HX-Trigger"))
+
+	entries, err := server.history.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Contains(t, w.Body.String(), `href="/p/`+entries[0].ID+`"`)
+}
+
+// newLibraryTestServer creates a Server with a library in a fresh directory.
+func newLibraryTestServer(t *testing.T) (*Server, *library.Library) {
+	t.Helper()
+
+	lib := library.New(filepath.Join(t.TempDir(), "library"))
+	mockGen := &mockPromptGenerator{
+		GenerateFunc:      func(_ context.Context, _, _ string) (string, error) { return "crafted", nil },
+		GetModelNamesFunc: func() []string { return []string{"gemini-2.5-flash"} },
+	}
+
+	server, err := NewServer(Config{Generator: mockGen, Version: "test", Library: lib})
+	require.NoError(t, err)
+
+	return server, lib
+}
+
+func TestHandlePrompt_OffersSaveToLibrary(t *testing.T) {
+	server, _ := newLibraryTestServer(t)
+
+	require.Contains(t, doGETIndex(server).Body.String(), `href="/library"`)
+
+	w := postForm(server, "/prompt", url.Values{"prompt": {"rough idea"}, "model": {"gemini-2.5-flash"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `hx-post="/library"`)
+}
+
+func TestHandleLibrarySave_FromCraftedPrompt(t *testing.T) {
+	server, lib := newLibraryTestServer(t)
+
+	w := postForm(server, "/library", url.Values{"name": {"Release Notes"}, "tags": {"docs, team"}, "prompt": {"Write notes."}})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `href="/library/release-notes"`)
+
+	p, err := lib.Get(t.Context(), "release-notes")
+	require.NoError(t, err)
+	require.Equal(t, []string{"docs", "team"}, p.Tags)
+
+	w = postForm(server, "/library", url.Values{"name": {"../x"}, "prompt": {"Write notes."}})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandleLibrary_ListEditAndDiff(t *testing.T) {
+	server, lib := newLibraryTestServer(t)
+
+	_, err := lib.Save(t.Context(), "notes", "Write notes.\n", library.SaveOptions{Tags: []string{"docs"}})
+	require.NoError(t, err)
+	_, err = lib.Save(t.Context(), "email", "Write an email.\n", library.SaveOptions{})
+	require.NoError(t, err)
+
+	body := doGET(server, "/library?tag=docs").Body.String()
+	require.Contains(t, body, `href="/library/notes"`)
+	require.NotContains(t, body, `href="/library/email"`)
+
+	w := postForm(server, "/library/notes", url.Values{"prompt": {"Write notes for {{version}}.\n"}, "note": {"add version"}})
+	require.Equal(t, http.StatusSeeOther, w.Code)
+	require.Equal(t, "/library/notes", w.Header().Get(echo.HeaderLocation))
+
+	w = doGET(server, "/library/notes")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body = w.Body.String()
+	require.Contains(t, body, "Changes from v1")
+	require.Contains(t, body, "+Write notes for {{version}}.")
+	require.Contains(t, body, "add version")
+	require.Contains(t, body, "variables: version")
+
+	require.NotContains(t, doGET(server, "/library/notes?v=1").Body.String(), "Changes from")
+	require.Equal(t, http.StatusNotFound, doGET(server, "/library/notes?v=9").Code)
+	require.Equal(t, http.StatusNotFound, doGET(server, "/library/missing").Code)
+	require.Equal(t, http.StatusNotFound, doGET(newHistoryTestServer(t), "/library").Code)
+}
+
+func TestHandleExecute_FillsPlaceholders(t *testing.T) {
+	mockGen := &mockPromptGenerator{
+		GenerateFunc: func(_ context.Context, _, _ string) (string, error) {
+			return "Email [Company] about {topic}.", nil
+		},
+		ExecuteFunc: func(_ context.Context, _, input string) (string, error) {
+			require.Equal(t, "Email Globex about the launch.", input)
+
+			return "answer", nil
+		},
+	}
+	memory := placeholder.NewMemory(filepath.Join(t.TempDir(), "placeholders.json"))
+	require.NoError(t, memory.Remember(map[string]string{"[Company]": "Acme"}))
+
+	server, err := NewServer(Config{Generator: mockGen, Version: "test", Placeholders: memory})
+	require.NoError(t, err)
+
+	w := postForm(server, "/prompt", url.Values{"prompt": {"rough idea"}, "model": {"gemini-2.5-flash"}})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	require.Contains(t, body, `<input type="hidden" name="placeholder" value="[Company]">`)
+	require.Contains(t, body, `<input type="text" name="value" value="Acme"`, "The value used before is suggested")
+	require.Contains(t, body, `<input type="hidden" name="placeholder" value="{topic}">`)
+
+	form := url.Values{
+		"prompt":      {"Email [Company] about {topic}."},
+		"model":       {"gemini-2.5-flash"},
+		"placeholder": {"[Company]", "{topic}"},
+		"value":       {"Globex", ""},
+	}
+	w = postForm(server, "/execute", form)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "Fill in {topic} before executing the prompt.")
+
+	form["value"] = []string{"Globex", "the launch"}
+	w = postForm(server, "/execute", form)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	values, err := memory.Load()
+	require.NoError(t, err)
+	require.Equal(t, []string{"Globex", "Acme"}, values.Recent("[Company]"))
+}
+
+func TestHandleExecute_IgnoresPlaceholdersEditedOut(t *testing.T) {
+	mockGen := &mockPromptGenerator{
+		ExecuteFunc: func(_ context.Context, _, input string) (string, error) {
+			require.Equal(t, "Email Globex about the launch.", input)
+
+			return "answer", nil
+		},
+	}
+	memory := placeholder.NewMemory(filepath.Join(t.TempDir(), "placeholders.json"))
+
+	server, err := NewServer(Config{Generator: mockGen, Version: "test", Placeholders: memory})
+	require.NoError(t, err)
+
+	// {topic} was written out by hand after the prompt was crafted.
+	w := postForm(server, "/execute", url.Values{
+		"prompt":      {"Email [Company] about the launch."},
+		"model":       {"gemini-2.5-flash"},
+		"placeholder": {"[Company]", "{topic}"},
+		"value":       {"Globex", ""},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	values, err := memory.Load()
+	require.NoError(t, err)
+	require.Empty(t, values.Recent("{topic}"), "Values of placeholders edited out are not remembered")
+}
+
+func TestHandleChat_AnswersFollowUpWithTranscript(t *testing.T) {
+	var transcripts [][]prompt.Turn
+
+	mockGen := &mockPromptGenerator{
+		ExecuteFunc: func(_ context.Context, _, _ string) (string, error) { return "long answer", nil },
+		FollowUpFunc: func(_ context.Context, _ string, transcript []prompt.Turn, question string) (string, error) {
+			transcripts = append(transcripts, transcript)
+
+			return "answer to " + question, nil
+		},
+	}
+	server := newTestServer(t, mockGen, "test")
+
+	w := postForm(server, "/execute", url.Values{"prompt": {"crafted prompt"}, "model": {"gemini-2.5-flash"}})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	require.Contains(t, body, `id="follow-up-form"`)
+	require.NotContains(t, body, `name="turn"`, "The transcript stays on the server")
+
+	match := regexp.MustCompile(`<input type="hidden" name="chat" value="([0-9a-f]+)">`).FindStringSubmatch(body)
+	require.Len(t, match, 2)
+
+	form := url.Values{
+		"chat":   {match[1]},
+		"prompt": {"make it shorter"},
+		"model":  {"gemini-2.5-flash"},
+	}
+	w = postForm(server, "/chat", form)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "answer to make it shorter")
+	require.Contains(t, w.Body.String(), `hx-swap-oob="true"`)
+
+	form["prompt"] = []string{"and friendlier"}
+	w = postForm(server, "/chat", form)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Equal(t, [][]prompt.Turn{
+		{
+			{Role: genai.RoleUser, Text: "crafted prompt"},
+			{Role: genai.RoleModel, Text: "long answer"},
+		},
+		{
+			{Role: genai.RoleUser, Text: "crafted prompt"},
+			{Role: genai.RoleModel, Text: "long answer"},
+			{Role: genai.RoleUser, Text: "make it shorter"},
+			{Role: genai.RoleModel, Text: "answer to make it shorter"},
+		},
+	}, transcripts)
+
+	form["chat"] = []string{"unknown"}
+	w = postForm(server, "/chat", form)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestChats_DropsOldestConversation(t *testing.T) {
+	c := newChats()
+
+	first := c.start("prompt", "answer")
+	for range maxChats {
+		c.start("prompt", "answer")
+	}
+
+	_, ok := c.transcript(first)
+	require.False(t, ok)
+	require.Zero(t, c.add(first, "question", "answer"))
+	require.Len(t, c.order, maxChats)
+}